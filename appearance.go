@@ -0,0 +1,141 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FormAppearance describes the default appearance of a PDF form.
+type FormAppearance struct {
+	// DA is the default appearance string of the AcroForm, e.g. "/Helv 0 Tf 0 g".
+	DA string
+	// Font is the resource name of the font selected by DA.
+	Font string
+	// FontSize is the font size selected by DA. 0 means auto size.
+	FontSize float64
+	// Fonts are the fonts of the AcroForm default resources (/DR).
+	Fonts []FormFont
+}
+
+// FormFont is a font of the form's default resources.
+type FormFont struct {
+	// Name is the resource name fields refer to in their appearance, e.g. "Helv".
+	Name     string
+	BaseFont string
+	Subtype  string
+	Encoding string
+	Embedded bool
+}
+
+// GetFormAppearance reads the default appearance string and the default font
+// resources of the PDF form.
+func GetFormAppearance(pdfFile string) (*FormAppearance, error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	a := doc.formAppearance()
+	if a == nil {
+		return nil, fmt.Errorf("PDF file has no form: '%s'", pdfFile)
+	}
+	return a, nil
+}
+
+// setNeedAppearances sets the NeedAppearances flag of the form in place.
+// Encrypted output is opened with its password and encrypted again.
+func setNeedAppearances(pdfFile string, o *options) error {
+	doc, err := loadPDFFileWithPassword(pdfFile, o.output.password())
+	if err != nil {
+		return err
+	}
+
+	if doc.dict(doc.catalog()["AcroForm"]) == nil {
+		return nil
+	}
+
+	u := doc.update()
+	u.setNeedAppearances()
+	return u.saveWith(pdfFile, o.tempDir, o.output.args()...)
+}
+
+// setNeedAppearances sets the NeedAppearances flag of the form, if any.
+func (u *pdfUpdate) setNeedAppearances() {
+	catalog := u.doc.catalog()
+	acro := u.doc.dict(catalog["AcroForm"])
+	if acro == nil {
+		return
+	}
+
+	acro = copyDict(acro)
+	acro["NeedAppearances"] = true
+	if ref, ok := catalog["AcroForm"].(pdfRef); ok {
+		u.set(ref, acro)
+	} else if root, ok := u.doc.trailer["Root"].(pdfRef); ok {
+		catalog = copyDict(catalog)
+		catalog["AcroForm"] = acro
+		u.set(root, catalog)
+	}
+}
+
+func (d *pdfDocument) formAppearance() *FormAppearance {
+	acro := d.dict(d.catalog()["AcroForm"])
+	if acro == nil {
+		return nil
+	}
+
+	a := &FormAppearance{
+		DA: d.text(acro["DA"]),
+	}
+	a.Font, a.FontSize = parseDA(a.DA)
+
+	for res, v := range d.dict(d.dict(acro["DR"])["Font"]) {
+		font := d.dict(v)
+		f := FormFont{
+			Name:     string(res),
+			BaseFont: string(d.name(font["BaseFont"])),
+			Subtype:  string(d.name(font["Subtype"])),
+			Encoding: string(d.name(font["Encoding"])),
+		}
+		if f.Encoding == "" {
+			f.Encoding = string(d.name(d.dict(font["Encoding"])["BaseEncoding"]))
+		}
+
+		// Composite fonts keep the descriptor in the descendant font.
+		desc := d.dict(font["FontDescriptor"])
+		if descendants := d.array(font["DescendantFonts"]); len(descendants) > 0 {
+			desc = d.dict(d.dict(descendants[0])["FontDescriptor"])
+		}
+		for _, key := range []pdfName{"FontFile", "FontFile2", "FontFile3"} {
+			if _, ok := desc[key]; ok {
+				f.Embedded = true
+			}
+		}
+
+		a.Fonts = append(a.Fonts, f)
+	}
+
+	sort.Slice(a.Fonts, func(i, j int) bool {
+		return a.Fonts[i].Name < a.Fonts[j].Name
+	})
+	return a
+}
@@ -0,0 +1,262 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pdftkHelp is an excerpt of the pdftk help text with the need_appearances
+// output option.
+const pdftkHelp = `pdftk 2.02
+	pdftk <input PDF files | - | PROMPT>
+	[ input_pw <input PDF owner passwords | PROMPT> ]
+	[ <operation> <operation arguments> ]
+	[ output <output filename | - | PROMPT> ]
+	[ encrypt_40bit | encrypt_128bit ]
+	[ need_appearances ]
+	[ flatten ]
+Where:
+	<operation> may be empty, or:
+	[cat | fill_form | dump_data | dump_data_fields]
+`
+
+func TestSetNeedAppearances(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestForm(t, "form.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the AcroForm into an indirect object like most forms have it.
+	u := doc.update()
+	catalog := copyDict(doc.catalog())
+	catalog["AcroForm"] = u.add(catalog["AcroForm"])
+	u.set(doc.trailer["Root"].(pdfRef), catalog)
+	indirect, err := u.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noForm, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		form bool
+	}{
+		{"direct", data, true},
+		{"indirect", indirect, true},
+		{"no form", noForm, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseNativePDF(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := doc.update()
+			u.setNeedAppearances()
+			if !tt.form {
+				if len(u.objects) != 0 {
+					t.Errorf("update of a PDF without form changed %d objects", len(u.objects))
+				}
+				return
+			}
+
+			out, err := u.bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc, err = parseNativePDF(out); err != nil {
+				t.Fatal(err)
+			}
+			acro := doc.dict(doc.catalog()["AcroForm"])
+			if acro["NeedAppearances"] != true {
+				t.Errorf("NeedAppearances = %v, want true", acro["NeedAppearances"])
+			}
+			if len(doc.array(acro["Fields"])) != 4 {
+				t.Errorf("fields = %v, want the 4 fields of the form", acro["Fields"])
+			}
+		})
+	}
+}
+
+func TestFillNeedAppearancesOption(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"editable", []Option{WithEditable()}, true},
+		{"not flattened", []Option{WithFlatten(false), WithNeedAppearances()}, true},
+		{"flattened", []Option{WithNeedAppearances()}, false},
+		{"not requested", []Option{WithFlatten(false)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte(pdftkHelp), output: []byte("%PDF-filled")}
+			useExecutor(t, e)
+
+			opts := append([]Option{WithBackend(PDFTKBackend{})}, tt.opts...)
+			if _, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", opts...); err != nil {
+				t.Fatal(err)
+			}
+			call := e.lastCall()
+			if got := containsString(call, "need_appearances"); got != tt.want {
+				t.Errorf("pdftk call = %q, want need_appearances %v", strings.Join(call, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestFillEditable(t *testing.T) {
+	requirePDFTK(t)
+	template := writeTestForm(t, "form.pdf")
+
+	data, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off",
+		WithBackend(PDFTKBackend{}), WithEditable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acro := doc.dict(doc.catalog()["AcroForm"])
+	if acro["NeedAppearances"] != true {
+		t.Errorf("NeedAppearances = %v, want true", acro["NeedAppearances"])
+	}
+}
+
+func TestFillEditableBackends(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name    string
+		backend Backend
+		opts    []Option
+	}{
+		{"native editable", NativeBackend{}, []Option{WithEditable()}},
+		{"native need appearances", NativeBackend{}, []Option{WithFlatten(false), WithNeedAppearances()}},
+		{"auto editable", AutoBackend{}, []Option{WithEditable()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithBackend(tt.backend)}, tt.opts...)
+			data, err := FillPDFToBytes(Form{"name": "Bob"}, template, t.TempDir(), "Yes", "Off", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := parseNativePDF(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			acro := doc.dict(doc.catalog()["AcroForm"])
+			if acro["NeedAppearances"] != true {
+				t.Errorf("NeedAppearances = %v, want true", acro["NeedAppearances"])
+			}
+			if values := doc.fieldValues(); values["name"] != "Bob" {
+				t.Errorf("name = %q, want the editable field filled", values["name"])
+			}
+		})
+	}
+
+	// Other backends still reject the option.
+	_, err := FillPDFToBytes(Form{"name": "Bob"}, template, t.TempDir(), "Yes", "Off",
+		WithBackend(&fakeBackend{}), WithEditable())
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("fill with a custom backend = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestGetFormAppearance(t *testing.T) {
+	w := &pdfWriter{}
+	pages := w.add(pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{}, "Count": 0})
+	descriptor := w.add(pdfDict{
+		"Type": pdfName("FontDescriptor"), "FontName": pdfName("NotoSans"),
+		"FontFile2": w.add(&pdfStream{dict: pdfDict{}, data: []byte("font")}),
+	})
+	fonts := pdfDict{
+		"Helv": w.add(pdfDict{"Type": pdfName("Font"), "Subtype": pdfName("Type1"), "BaseFont": pdfName("Helvetica"), "Encoding": pdfName("WinAnsiEncoding")}),
+		"ZaDb": pdfDict{"Type": pdfName("Font"), "Subtype": pdfName("Type1"), "BaseFont": pdfName("ZapfDingbats"), "Encoding": pdfDict{"BaseEncoding": pdfName("StandardEncoding")}},
+		"Noto": w.add(pdfDict{
+			"Type": pdfName("Font"), "Subtype": pdfName("Type0"), "BaseFont": pdfName("NotoSans"), "Encoding": pdfName("Identity-H"),
+			"DescendantFonts": pdfArray{pdfDict{"Type": pdfName("Font"), "Subtype": pdfName("CIDFontType2"), "FontDescriptor": descriptor}},
+		}),
+	}
+	root := w.add(pdfDict{
+		"Type": pdfName("Catalog"), "Pages": pages,
+		"AcroForm": pdfDict{"Fields": pdfArray{}, "DA": pdfString("/Helv 9 Tf 0 g"), "DR": pdfDict{"Font": fonts}},
+	})
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := filepath.Join(t.TempDir(), "form.pdf")
+	if err := ioutil.WriteFile(form, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	a, err := GetFormAppearance(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &FormAppearance{
+		DA:       "/Helv 9 Tf 0 g",
+		Font:     "Helv",
+		FontSize: 9,
+		Fonts: []FormFont{
+			{Name: "Helv", BaseFont: "Helvetica", Subtype: "Type1", Encoding: "WinAnsiEncoding"},
+			{Name: "Noto", BaseFont: "NotoSans", Subtype: "Type0", Encoding: "Identity-H", Embedded: true},
+			{Name: "ZaDb", BaseFont: "ZapfDingbats", Subtype: "Type1", Encoding: "StandardEncoding"},
+		},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("GetFormAppearance() = %+v, want %+v", a, want)
+	}
+}
+
+func TestGetFormAppearanceNoForm(t *testing.T) {
+	input := writeTestPDF(t, "pages.pdf", 1)
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	if a, err := GetFormAppearance(input); err == nil || !strings.Contains(err.Error(), "no form") {
+		t.Errorf("GetFormAppearance() of a PDF without form = %+v, want an error", a)
+	}
+}
@@ -0,0 +1,192 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// AttachFiles embeds the attachments into the PDF file and returns a reader
+// to the resulting PDF. The files are attached to the document, if toPage is
+// 0, or else as file annotations to the given page.
+func AttachFiles(pdfFile string, attachments []string, toPage int) (io.Reader, error) {
+	var err error
+
+	if len(attachments) == 0 {
+		return nil, fmt.Errorf("no files to attach")
+	}
+	if toPage < 0 {
+		return nil, fmt.Errorf("invalid page: %d", toPage)
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	// Get the absolute paths.
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	args := []string{pdfFile, "attach_files"}
+	for _, f := range attachments {
+		if f, err = getAbs(f); err != nil {
+			return nil, err
+		}
+		args = append(args, f)
+	}
+	if toPage > 0 {
+		args = append(args, "to_page", strconv.Itoa(toPage))
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the temporary output file path.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	args = append(args, "output", outputFile)
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
+
+// AttachReaders is like AttachFiles, but reads the attachments from the
+// readers. The keys are the file names of the attachments, like
+// "factur-x.xml".
+func AttachReaders(pdfFile string, attachments map[string]io.Reader, toPage int) (io.Reader, error) {
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Sort the names for a stable order of the attachments.
+	names := make([]string, 0, len(attachments))
+	for name := range attachments {
+		if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+			return nil, fmt.Errorf("invalid attachment name: '%s'", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]string, len(names))
+	for i, name := range names {
+		if files[i], err = spoolReader(attachments[name], tmpDir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	return AttachFiles(pdfFile, files, toPage)
+}
+
+// ExtractAttachments writes the files embedded into the PDF file, attached to
+// the document or to pages, to outputDir and returns their paths. Existing
+// files are replaced.
+func ExtractAttachments(pdfFile, outputDir string) ([]string, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	// Get the absolute paths.
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+	if outputDir, err = filepath.Abs(outputDir); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Unpack into the empty temporary directory first, so the unpacked
+	// files are known.
+	unpackDir := filepath.Join(tmpDir, "files")
+	if err := os.Mkdir(unpackDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", pdfFile, "unpack_files", "output", unpackDir+string(filepath.Separator))
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	entries, err := ioutil.ReadDir(unpackDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		dst := filepath.Join(outputDir, e.Name())
+		if err := copyFile(filepath.Join(unpackDir, e.Name()), dst); err != nil {
+			return nil, err
+		}
+		files = append(files, dst)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,230 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttachFilesCommandLine(t *testing.T) {
+	pdf := writeTestPDF(t, "doc.pdf", 2)
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.xml")
+	b := filepath.Join(dir, "b.txt")
+	for _, f := range []string{a, b} {
+		if err := ioutil.WriteFile(f, []byte("content"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		toPage int
+		want   string
+	}{
+		{0, "pdftk doc.pdf attach_files a.xml b.txt output output.pdf"},
+		{2, "pdftk doc.pdf attach_files a.xml b.txt to_page 2 output output.pdf"},
+	}
+	for _, tt := range tests {
+		e := &recordExecutor{output: []byte("%PDF-attached")}
+		useExecutor(t, e)
+
+		r, err := AttachFiles(pdf, []string{a, b}, tt.toPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := ioutil.ReadAll(r); err != nil || string(data) != "%PDF-attached" {
+			t.Errorf("to page %d: output = %q, %v, want the pdftk output", tt.toPage, data, err)
+		}
+		if got := baseNames(e.lastCall()); got != tt.want {
+			t.Errorf("to page %d: pdftk call = %q, want %q", tt.toPage, got, tt.want)
+		}
+	}
+}
+
+func TestAttachFilesInvalid(t *testing.T) {
+	pdf := writeTestPDF(t, "doc.pdf", 1)
+	useExecutor(t, &recordExecutor{})
+
+	tests := []struct {
+		name        string
+		attachments []string
+		toPage      int
+	}{
+		{"no attachments", nil, 0},
+		{"negative page", []string{"a.xml"}, -1},
+	}
+	for _, tt := range tests {
+		if _, err := AttachFiles(pdf, tt.attachments, tt.toPage); err == nil {
+			t.Errorf("%s: AttachFiles succeeded", tt.name)
+		}
+	}
+}
+
+func TestAttachReadersNames(t *testing.T) {
+	pdf := writeTestPDF(t, "doc.pdf", 1)
+
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"factur-x.xml"}, "pdftk doc.pdf attach_files factur-x.xml output output.pdf"},
+		{[]string{"b.txt", "a.xml"}, "pdftk doc.pdf attach_files a.xml b.txt output output.pdf"},
+		{[]string{""}, ""},
+		{[]string{"."}, ""},
+		{[]string{".."}, ""},
+		{[]string{"../a.xml"}, ""},
+		{[]string{"dir/a.xml"}, ""},
+	}
+	for _, tt := range tests {
+		e := &recordExecutor{output: []byte("%PDF-attached")}
+		useExecutor(t, e)
+
+		attachments := make(map[string]io.Reader)
+		for _, name := range tt.names {
+			attachments[name] = strings.NewReader("content of " + name)
+		}
+		_, err := AttachReaders(pdf, attachments, 0)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("AttachReaders with names %q succeeded", tt.names)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("AttachReaders with names %q: %v", tt.names, err)
+			continue
+		}
+		if got := baseNames(e.lastCall()); got != tt.want {
+			t.Errorf("names %q: pdftk call = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestAttachAndExtract(t *testing.T) {
+	requirePDFTK(t)
+	pdf := writeTestPDF(t, "doc.pdf", 2)
+
+	for _, toPage := range []int{0, 2} {
+		r, err := AttachReaders(pdf, map[string]io.Reader{
+			"factur-x.xml": strings.NewReader("<invoice/>"),
+			"notes.txt":    strings.NewReader("notes"),
+		}, toPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attached := filepath.Join(t.TempDir(), "attached.pdf")
+		if err := ioutil.WriteFile(attached, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := ExtractAttachments(attached, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, f := range files {
+			content, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[filepath.Base(f)] = string(content)
+		}
+		if got["factur-x.xml"] != "<invoice/>" || got["notes.txt"] != "notes" || len(got) != 2 {
+			t.Errorf("to page %d: extracted = %q", toPage, got)
+		}
+	}
+}
+
+// unpackExecutor writes the files for pdftk unpack_files into the output
+// directory.
+type unpackExecutor struct {
+	recordExecutor
+	files map[string]string
+}
+
+// Run implements Executor.
+func (e *unpackExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	out := args[len(args)-1]
+	for name, content := range e.files {
+		if err := ioutil.WriteFile(filepath.Join(out, name), []byte(content), 0600); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := os.Mkdir(filepath.Join(out, "dir"), 0755); err != nil {
+		return nil, nil, err
+	}
+	return e.recordExecutor.Run(dir, name, args, stdin)
+}
+
+func TestExtractAttachmentsCommandLine(t *testing.T) {
+	pdf := writeTestPDF(t, "doc.pdf", 1)
+	e := &unpackExecutor{files: map[string]string{"factur-x.xml": "<invoice/>", "notes.txt": "notes"}}
+	useExecutor(t, e)
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ExtractAttachments(pdf, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := baseNames(e.lastCall()), "pdftk doc.pdf unpack_files output files"; got != want {
+		t.Errorf("pdftk call = %q, want %q", got, want)
+	}
+	if len(files) != 2 {
+		t.Fatalf("extracted files = %v, want 2 files", files)
+	}
+	for _, f := range files {
+		if filepath.Dir(f) != dir {
+			t.Errorf("extracted file %s is not in %s", f, dir)
+		}
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := e.files[filepath.Base(f)]; string(content) != want {
+			t.Errorf("content of %s = %q, want %q", filepath.Base(f), content, want)
+		}
+	}
+}
+
+// baseNames joins the command with absolute paths replaced by their base
+// names.
+func baseNames(call []string) string {
+	args := make([]string, len(call))
+	for i, arg := range call {
+		args[i] = arg
+		if filepath.IsAbs(arg) {
+			args[i] = filepath.Base(arg)
+		}
+	}
+	return strings.Join(args, " ")
+}
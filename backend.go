@@ -0,0 +1,285 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNotSupported is returned if the selected Backend does not support an
+// option or a document.
+var ErrNotSupported = errors.New("not supported by the backend")
+
+// Backend fills the PDF forms of Fill, FillContext, FillPDFToBytes and the
+// functions based on them. The merge, stamp and field listing functions use
+// the Backend too, if it implements MergeBackend, StampBackend or
+// FieldsBackend. The Backend of the package is set with SetBackend, the one
+// of a call with WithBackend. Options only pdftk supports, like passwords,
+// fall back to pdftk for merging, stamping and listing fields. The other
+// functions of the package always use pdftk.
+type Backend interface {
+	// FillForm fills the form PDF templateFile with the values and writes
+	// the result to outputFile.
+	FillForm(ctx context.Context, form Form, templateFile, outputFile, checkedString, uncheckedString string, flatten bool) error
+}
+
+// MergeBackend is a Backend also merging PDFs.
+type MergeBackend interface {
+	Backend
+	// Merge concatenates the files and writes the result to outputFile.
+	Merge(ctx context.Context, files []string, outputFile string) error
+}
+
+// StampMode selects how a StampBackend applies the pages of the stamp PDF.
+// The values are the names of the pdftk operations.
+type StampMode string
+
+const (
+	// StampModeStamp puts the first stamp page on top of every page.
+	StampModeStamp StampMode = "stamp"
+	// StampModeMultistamp puts stamp page N on top of page N.
+	StampModeMultistamp StampMode = "multistamp"
+	// StampModeBackground puts the first stamp page behind every page.
+	StampModeBackground StampMode = "background"
+	// StampModeMultibackground puts stamp page N behind page N.
+	StampModeMultibackground StampMode = "multibackground"
+)
+
+// StampBackend is a Backend also stamping PDFs.
+type StampBackend interface {
+	Backend
+	// Stamp applies the pages of stampFile to the pages of pdfFile as
+	// selected by the mode and writes the result to outputFile.
+	Stamp(ctx context.Context, pdfFile, stampFile, outputFile string, mode StampMode) error
+}
+
+// FieldsBackend is a Backend also listing the form fields of PDFs.
+type FieldsBackend interface {
+	Backend
+	// Fields returns the form fields of the PDF in document order.
+	Fields(ctx context.Context, pdfFile string) ([]Field, error)
+}
+
+// PDFTKBackend fills the forms with the pdftk utility. It is the default
+// Backend and the only one supporting all options.
+type PDFTKBackend struct{}
+
+// FillForm implements Backend.
+func (PDFTKBackend) FillForm(ctx context.Context, form Form, templateFile, outputFile, checkedString, uncheckedString string, flatten bool) error {
+	return fillContext(ctx, form, templateFile, outputFile, checkedString, uncheckedString, true, newOptions([]Option{WithFlatten(flatten)}))
+}
+
+// Merge implements MergeBackend.
+func (PDFTKBackend) Merge(ctx context.Context, files []string, outputFile string) error {
+	r, err := pdftkMerge(ctx, nil, newOptions(nil), files)
+	if err != nil {
+		return err
+	}
+	_, err = spoolReader(r, filepath.Dir(outputFile), filepath.Base(outputFile))
+	return err
+}
+
+// Stamp implements StampBackend.
+func (PDFTKBackend) Stamp(ctx context.Context, pdfFile, stampFile, outputFile string, mode StampMode) error {
+	r, err := pdftkStamp(ctx, string(mode), pdfFile, stampFile, newOptions(nil))
+	if err != nil {
+		return err
+	}
+	_, err = spoolReader(r, filepath.Dir(outputFile), filepath.Base(outputFile))
+	return err
+}
+
+// Fields implements FieldsBackend.
+func (PDFTKBackend) Fields(ctx context.Context, pdfFile string) ([]Field, error) {
+	return pdftkFields(ctx, pdfFile, "")
+}
+
+// AutoBackend fills, merges, stamps and lists the fields with pdftk, if it is
+// in the PATH, and with NativeBackend otherwise, so the package also works
+// where pdftk can't be installed. With pdftk all options are supported.
+type AutoBackend struct{}
+
+// FillForm implements Backend.
+func (AutoBackend) FillForm(ctx context.Context, form Form, templateFile, outputFile, checkedString, uncheckedString string, flatten bool) error {
+	if b := resolveBackend(AutoBackend{}); b != nil {
+		return b.FillForm(ctx, form, templateFile, outputFile, checkedString, uncheckedString, flatten)
+	}
+	return PDFTKBackend{}.FillForm(ctx, form, templateFile, outputFile, checkedString, uncheckedString, flatten)
+}
+
+var (
+	backendMutex sync.Mutex
+	backend      Backend = PDFTKBackend{}
+)
+
+// SetBackend replaces the Backend of the package, e.g. with NativeBackend
+// to fill forms without pdftk or AutoBackend to fall back to it. nil restores
+// the default PDFTKBackend.
+func SetBackend(b Backend) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	if b == nil {
+		b = PDFTKBackend{}
+	}
+	backend = b
+}
+
+// getBackend returns the Backend of the package, nil for PDFTKBackend, which
+// is run by the callers directly to support all options.
+func getBackend() Backend {
+	backendMutex.Lock()
+	b := backend
+	backendMutex.Unlock()
+
+	return resolveBackend(b)
+}
+
+// resolveBackend returns the Backend run for b, nil for PDFTKBackend and for
+// AutoBackend with pdftk in the PATH.
+func resolveBackend(b Backend) Backend {
+	switch b.(type) {
+	case PDFTKBackend:
+		return nil
+	case AutoBackend:
+		if lookPath("pdftk") == nil {
+			return nil
+		}
+		return NativeBackend{}
+	}
+	return b
+}
+
+// WithBackend fills, merges, stamps or lists the fields with b instead of the
+// Backend of the package set with SetBackend. PDFTKBackend{} selects pdftk
+// for the call.
+func WithBackend(b Backend) Option {
+	return func(o *options) {
+		o.backend = b
+	}
+}
+
+// getBackend returns the Backend of the call, nil for PDFTKBackend.
+func (o *options) getBackend() Backend {
+	if o.backend == nil {
+		return getBackend()
+	}
+	return resolveBackend(o.backend)
+}
+
+// lookPathBackend checks if the pdftk utility exists, unless another Backend
+// is selected.
+func (o *options) lookPathBackend() error {
+	if o.getBackend() != nil {
+		return nil
+	}
+	return lookPath("pdftk")
+}
+
+// fillWithBackend fills the form with a Backend other than PDFTKBackend.
+// The options relying on pdftk are rejected.
+func fillWithBackend(ctx context.Context, b Backend, form Form, formPDFFile, outputFile, checkedString, uncheckedString string, o *options) error {
+	var err error
+	if err := o.checkBackend(b); err != nil {
+		return err
+	}
+
+	// Get the absolute path.
+	if formPDFFile, err = getAbs(formPDFFile); err != nil {
+		return err
+	}
+
+	if form, err = qualifyFieldNames(form); err != nil {
+		return err
+	}
+	if form, err = o.formatNumbers(form); err != nil {
+		return err
+	}
+
+	return b.FillForm(ctx, form, formPDFFile, outputFile, checkedString, uncheckedString, o.flatten)
+}
+
+// checkBackend returns an error for the options b does not support.
+func (o *options) checkBackend(b Backend) error {
+	// NativeBackend sets the NeedAppearances flag of all forms it does not
+	// flatten.
+	_, native := b.(NativeBackend)
+
+	unsupported := []struct {
+		option string
+		set    bool
+	}{
+		{"WithFlattenFallback", o.flattenSkipped != nil},
+		{"WithLooseFieldNames", o.looseFieldNames},
+		{"WithPageFieldNames", o.pageFieldNames},
+		{"WithUTF8FDF", o.utf8FDF},
+		{"WithXFDF", o.xfdf},
+		{"WithHexFDF", o.hexFDF},
+		{"WithFieldValidation", o.validateFields},
+		{"WithInputPassword", o.password != ""},
+		{"WithOutputOptions", len(o.output.args()) > 0},
+		{"WithNeedAppearances", o.needAppearances && !native},
+		{"WithValidation", o.validate},
+		{"WithDumpFDF", o.dumpFDF != nil},
+		{"WithDropUnusedFields", o.dropUnusedFields},
+		{"WithKeepFields", len(o.keepFields) > 0},
+		{"WithInfo", len(o.info) > 0},
+		{"WithDropXFA", o.dropXFA},
+		{"WithRepair", o.repair},
+	}
+	for _, u := range unsupported {
+		if u.set {
+			return fmt.Errorf("%w: %s", ErrNotSupported, u.option)
+		}
+	}
+	return nil
+}
+
+// fillToDest fills the form with the Backend and copies the output to the
+// destination file.
+func fillToDest(ctx context.Context, b Backend, form Form, formPDFFile, destPDFFile, checkedString, uncheckedString string, overwrite bool, o *options) error {
+	var err error
+	if destPDFFile, err = filepath.Abs(destPDFFile); err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	if err := fillWithBackend(ctx, b, form, formPDFFile, outputFile, checkedString, uncheckedString, o); err != nil {
+		return err
+	}
+
+	return copyToDest(outputFile, destPDFFile, overwrite)
+}
@@ -0,0 +1,163 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBackend records the calls of the merge, stamp and field functions and
+// writes the name of the operation as output.
+type fakeBackend struct {
+	calls []string
+}
+
+// FillForm implements Backend.
+func (b *fakeBackend) FillForm(ctx context.Context, form Form, templateFile, outputFile, checkedString, uncheckedString string, flatten bool) error {
+	b.calls = append(b.calls, "fill")
+	return ioutil.WriteFile(outputFile, []byte("fill"), 0600)
+}
+
+// Merge implements MergeBackend.
+func (b *fakeBackend) Merge(ctx context.Context, files []string, outputFile string) error {
+	b.calls = append(b.calls, "merge "+strings.Join(files, " "))
+	return ioutil.WriteFile(outputFile, []byte("merge"), 0600)
+}
+
+// Stamp implements StampBackend.
+func (b *fakeBackend) Stamp(ctx context.Context, pdfFile, stampFile, outputFile string, mode StampMode) error {
+	b.calls = append(b.calls, string(mode)+" "+pdfFile+" "+stampFile)
+	return ioutil.WriteFile(outputFile, []byte(mode), 0600)
+}
+
+// Fields implements FieldsBackend.
+func (b *fakeBackend) Fields(ctx context.Context, pdfFile string) ([]Field, error) {
+	b.calls = append(b.calls, "fields "+pdfFile)
+	return []Field{{Name: "name", Type: FieldTypeText, Value: "Ann"}}, nil
+}
+
+func TestWithBackendStamp(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 2)
+	s := writeTestPDF(t, "stamp.pdf", 1)
+
+	tests := []struct {
+		name string
+		run  func(opts ...Option) (io.Reader, error)
+		mode StampMode
+	}{
+		{"Stamp", func(opts ...Option) (io.Reader, error) { return Stamp(a, s, opts...) }, StampModeStamp},
+		{"Multistamp", func(opts ...Option) (io.Reader, error) { return Multistamp(a, s, opts...) }, StampModeMultistamp},
+		{"Background", func(opts ...Option) (io.Reader, error) { return Background(a, s, opts...) }, StampModeBackground},
+		{"Multibackground", func(opts ...Option) (io.Reader, error) { return Multibackground(a, s, opts...) }, StampModeMultibackground},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBackend{}
+			r, err := tt.run(WithBackend(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != string(tt.mode) {
+				t.Errorf("output = %q, want %q", out, tt.mode)
+			}
+			want := []string{string(tt.mode) + " " + a + " " + s}
+			if !reflect.DeepEqual(b.calls, want) {
+				t.Errorf("calls = %q, want %q", b.calls, want)
+			}
+		})
+	}
+}
+
+func TestWithBackendMerge(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 1)
+	c := writeTestPDF(t, "c.pdf", 1)
+
+	b := &fakeBackend{}
+	r, err := MergeWithOptions([]string{a, c}, WithBackend(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "merge" {
+		t.Errorf("output = %q, want %q", out, "merge")
+	}
+	want := []string{"merge " + a + " " + c}
+	if !reflect.DeepEqual(b.calls, want) {
+		t.Errorf("calls = %q, want %q", b.calls, want)
+	}
+}
+
+func TestWithBackendFields(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 1)
+
+	b := &fakeBackend{}
+	form, err := GetFieldValues(a, WithBackend(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Form{"name": "Ann"}); !reflect.DeepEqual(form, want) {
+		t.Errorf("form = %v, want %v", form, want)
+	}
+	if want := []string{"fields " + a}; !reflect.DeepEqual(b.calls, want) {
+		t.Errorf("calls = %q, want %q", b.calls, want)
+	}
+}
+
+func TestWithBackendPasswordFallsBackToPDFTK(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 1)
+	s := writeTestPDF(t, "stamp.pdf", 1)
+	e := &recordExecutor{}
+	useExecutor(t, e)
+
+	b := &fakeBackend{}
+	// The executor writes no output file, only the command line matters.
+	Stamp(a, s, WithBackend(b), WithInputPassword("secret"))
+	MergeWithOptions([]string{a, s}, WithBackend(b), WithInputPassword("secret"))
+	GetFields(a, WithBackend(b), WithInputPassword("secret"))
+
+	if len(b.calls) > 0 {
+		t.Errorf("backend calls = %q, want none", b.calls)
+	}
+	var runs int
+	for _, call := range e.calls {
+		if containsString(call, "--help") {
+			continue
+		}
+		runs++
+		if !containsString(call, "input_pw") {
+			t.Errorf("pdftk call without input_pw: %q", call)
+		}
+	}
+	if runs != 3 {
+		t.Errorf("pdftk runs = %d, want 3", runs)
+	}
+}
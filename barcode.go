@@ -0,0 +1,267 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Symbology is the barcode type StampBarcode generates.
+type Symbology string
+
+const (
+	// SymbologyQR is a QR code. It requires the qrencode utility in the PATH,
+	// ErrQREncodeNotFound is returned without it.
+	SymbologyQR Symbology = "qr"
+	// SymbologyCode128 is a Code 128 barcode of printable ASCII characters.
+	SymbologyCode128 Symbology = "code128"
+)
+
+// StampBarcode draws a barcode of the data into the rectangle of the named
+// form field on every page it is shown and writes the result to output. QR
+// codes are centered as large square, Code 128 barcodes fill the rectangle.
+// The barcode is drawn with vector graphics and stamped like Multistamp. The
+// output file is replaced atomically. WithInputPassword opens a password
+// protected input and WithTempDir sets the directory of the temporary files.
+func StampBarcode(input, output, field, data string, symbology Symbology, opts ...Option) error {
+	o := newOptions(opts)
+
+	var (
+		draw func(b *bytes.Buffer, x, y, w, h float64)
+		err  error
+	)
+	switch symbology {
+	case SymbologyQR:
+		var matrix [][]bool
+		if matrix, err = qrMatrix(data); err == nil {
+			draw = func(b *bytes.Buffer, x, y, w, h float64) {
+				drawMatrix(b, matrix, x, y, w, h)
+			}
+		}
+	case SymbologyCode128:
+		var modules []bool
+		if modules, err = code128Modules(data); err == nil {
+			draw = func(b *bytes.Buffer, x, y, w, h float64) {
+				drawBars(b, modules, x, y, w, h)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid barcode symbology: '%s'", symbology)
+	}
+	if err != nil {
+		return err
+	}
+
+	doc, err := loadPDFFileWithPassword(input, o.password)
+	if err != nil {
+		return err
+	}
+
+	// Create one overlay page per page, matching its size.
+	found := false
+	widgets := doc.widgets()
+	refs := doc.pages()
+	pages := make([]pdfPage, len(refs))
+	for i, ref := range refs {
+		box := doc.array(doc.inherited(doc.dict(ref), "MediaBox"))
+		var r [4]float64
+		for j := 0; j < 4 && j < len(box); j++ {
+			r[j], _ = doc.number(box[j])
+		}
+		pages[i] = pdfPage{width: r[2] - r[0], height: r[3] - r[1]}
+
+		var b bytes.Buffer
+		b.WriteString("0 g\n")
+		for _, w := range widgets {
+			if w.name != field || w.page != i+1 {
+				continue
+			}
+			found = true
+			draw(&b, math.Min(w.rect[0], w.rect[2])-r[0], math.Min(w.rect[1], w.rect[3])-r[1], w.width(), w.height())
+		}
+		pages[i].content = b.Bytes()
+	}
+	if !found {
+		return fmt.Errorf("form field does not exist: '%s'", field)
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	overlayFile := filepath.Join(tmpDir, "barcode.pdf")
+	if err := writePDF(overlayFile, pages); err != nil {
+		return err
+	}
+
+	stamped, err := Multistamp(input, overlayFile, opts...)
+	if err != nil {
+		return err
+	}
+	fb, err := ioutil.ReadAll(stamped)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(output, fb)
+}
+
+// qrMatrix encodes the data as QR code with the qrencode utility and returns
+// the modules, true for dark ones.
+func qrMatrix(data string) ([][]bool, error) {
+	// Check if the qrencode utility exists.
+	if err := lookPath("qrencode"); err != nil {
+		return nil, err
+	}
+
+	// The ASCII output prints each module as "##" or two spaces.
+	out, err := runCommandWithOutput("", "qrencode", "-t", "ASCII", "-m", "0", "-o", "-", "--", data)
+	if err != nil {
+		return nil, fmt.Errorf("qrencode error: %v", err)
+	}
+
+	var matrix [][]bool
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		row := make([]bool, (len(line)+1)/2)
+		for i := range row {
+			row[i] = line[2*i] == '#'
+		}
+		matrix = append(matrix, row)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(matrix) == 0 {
+		return nil, fmt.Errorf("qrencode returned no QR code")
+	}
+	return matrix, nil
+}
+
+// drawMatrix draws the QR code as large as possible centered into the box,
+// including a quiet zone of two modules.
+func drawMatrix(b *bytes.Buffer, matrix [][]bool, x, y, w, h float64) {
+	n := len(matrix)
+	size := math.Min(w, h)
+	module := size / float64(n+4)
+	x += (w-size)/2 + 2*module
+	y += (h-size)/2 + 2*module
+
+	for row, modules := range matrix {
+		for col, dark := range modules {
+			if dark {
+				fmt.Fprintf(b, "%.3f %.3f %.3f %.3f re\n", x+float64(col)*module, y+float64(n-1-row)*module, module, module)
+			}
+		}
+	}
+	b.WriteString("f\n")
+}
+
+// drawBars draws the barcode modules over the full box, leaving a quiet zone
+// of ten modules on both sides.
+func drawBars(b *bytes.Buffer, modules []bool, x, y, w, h float64) {
+	module := w / float64(len(modules)+20)
+	x += 10 * module
+
+	for i := 0; i < len(modules); i++ {
+		if !modules[i] {
+			continue
+		}
+		// Merge adjacent dark modules into one bar.
+		j := i
+		for j+1 < len(modules) && modules[j+1] {
+			j++
+		}
+		fmt.Fprintf(b, "%.3f %.3f %.3f %.3f re\n", x+float64(i)*module, y, float64(j-i+1)*module, h)
+		i = j
+	}
+	b.WriteString("f\n")
+}
+
+// code128Patterns are the bar and space widths of the Code 128 symbols.
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232",
+}
+
+const (
+	code128StartB = 104
+	code128Stop   = "2331112"
+)
+
+// code128Modules encodes the data with code set B and returns the modules
+// of the barcode, true for bars.
+func code128Modules(data string) ([]bool, error) {
+	if data == "" {
+		return nil, fmt.Errorf("no barcode data")
+	}
+
+	symbols := []int{code128StartB}
+	checksum := code128StartB
+	for i, r := range data {
+		if r < 32 || r > 126 {
+			return nil, fmt.Errorf("invalid Code 128 character: %q", r)
+		}
+		symbols = append(symbols, int(r-32))
+		checksum += (i + 1) * int(r-32)
+	}
+	symbols = append(symbols, checksum%103)
+
+	var modules []bool
+	appendPattern := func(pattern string) {
+		for i, c := range pattern {
+			for n := 0; n < int(c-'0'); n++ {
+				modules = append(modules, i%2 == 0)
+			}
+		}
+	}
+	for _, s := range symbols {
+		appendPattern(code128Patterns[s])
+	}
+	appendPattern(code128Stop)
+
+	return modules, nil
+}
@@ -0,0 +1,140 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// widths returns the bar and space widths of the modules, e.g. "211214" for
+// the start symbol of code set B.
+func widths(modules []bool) string {
+	var b strings.Builder
+	for i := 0; i < len(modules); {
+		j := i
+		for j < len(modules) && modules[j] == modules[i] {
+			j++
+		}
+		b.WriteByte(byte('0' + j - i))
+		i = j
+	}
+	return b.String()
+}
+
+func TestCode128Modules(t *testing.T) {
+	const (
+		startB = "211214"
+		stop   = "2331112"
+	)
+	tests := []struct {
+		data string
+		want string
+	}{
+		// Start B, "A" (33), checksum (104+33)%103 = 34 and stop.
+		{"A", startB + "111323" + "131123" + stop},
+		// Start B, " " (0), "~" (94), checksum (104+0+2*94)%103 = 86 and stop.
+		{" ~", startB + "212222" + "131141" + "411212" + stop},
+		// Checksum (104+1*48+2*42+3*42+4*17+5*18+6*19+7*35)%103 = 55.
+		{"PJJ123C", startB + "313121" + "112133" + "112133" + "123221" + "223211" + "221132" + "131321" + "311321" + stop},
+	}
+	for _, tt := range tests {
+		modules, err := code128Modules(tt.data)
+		if err != nil {
+			t.Errorf("code128Modules(%q): %v", tt.data, err)
+			continue
+		}
+		if got := widths(modules); got != tt.want {
+			t.Errorf("code128Modules(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+		// Every symbol has 11 modules, the stop symbol 13.
+		if n := 11*(len(tt.data)+2) + 13; len(modules) != n {
+			t.Errorf("code128Modules(%q) has %d modules, want %d", tt.data, len(modules), n)
+		}
+		if !modules[0] || !modules[len(modules)-1] {
+			t.Errorf("code128Modules(%q) does not start and end with a bar", tt.data)
+		}
+	}
+
+	for _, data := range []string{"", "tab\t", "Grüße", "\x7f"} {
+		if _, err := code128Modules(data); err == nil {
+			t.Errorf("code128Modules(%q) succeeded", data)
+		}
+	}
+}
+
+func TestDrawBars(t *testing.T) {
+	var b bytes.Buffer
+	drawBars(&b, []bool{true, true, false, true}, 0, 10, 24, 5)
+	want := "10.000 10.000 2.000 5.000 re\n13.000 10.000 1.000 5.000 re\nf\n"
+	if b.String() != want {
+		t.Errorf("drawBars() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestQRMatrix(t *testing.T) {
+	useExecutor(t, &recordExecutor{stdout: []byte("##  ##\r\n  ##  \n\n##  ##\n")})
+
+	matrix, err := qrMatrix("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]bool{{true, false, true}, {false, true, false}, {true, false, true}}
+	if !reflect.DeepEqual(matrix, want) {
+		t.Errorf("qrMatrix() = %v, want %v", matrix, want)
+	}
+}
+
+func TestQRMatrixNotFound(t *testing.T) {
+	if _, err := exec.LookPath("qrencode"); err == nil {
+		t.Skip("qrencode found in PATH")
+	}
+	_, err := qrMatrix("data")
+	if !errors.Is(err, ErrQREncodeNotFound) {
+		t.Errorf("qrMatrix() error = %v, want ErrQREncodeNotFound", err)
+	}
+}
+
+func TestStampBarcode(t *testing.T) {
+	requirePDFTK(t)
+	template := writeTestForm(t, "form.pdf")
+	output := filepath.Join(t.TempDir(), "barcode.pdf")
+
+	if err := StampBarcode(template, output, "name", "INV-2024-001", SymbologyCode128, WithTempDir(t.TempDir())); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("output is no PDF: %q", data[:10])
+	}
+
+	if err := StampBarcode(template, output, "missing", "x", SymbologyCode128); err == nil {
+		t.Error("StampBarcode into a missing field succeeded")
+	}
+}
@@ -0,0 +1,320 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BatchResult is the result of a form of FillBatch. Either PDF or Err is set.
+type BatchResult struct {
+	PDF []byte
+	Err error
+}
+
+// BatchOption configures FillBatch.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	workers    int
+	fillOpts   []Option
+	mergeFile  string
+	zipWriter  io.Writer
+	zipPattern string
+	boolCols   []string
+}
+
+// WithWorkers sets the number of concurrent fills. If workers is <= 0, the
+// limit set with SetMaxConcurrency or else the number of CPUs is used.
+func WithWorkers(workers int) BatchOption {
+	return func(o *batchOptions) {
+		o.workers = workers
+	}
+}
+
+// WithFillOptions sets the options applied to every fill of the batch, e.g.
+// WithFlatten or WithCheckedString.
+func WithFillOptions(opts ...Option) BatchOption {
+	return func(o *batchOptions) {
+		o.fillOpts = append(o.fillOpts, opts...)
+	}
+}
+
+// WithMergeOutput concatenates the filled PDFs in the order of the forms
+// into the PDF file, e.g. to print a mail merge at once.
+func WithMergeOutput(pdfFile string) BatchOption {
+	return func(o *batchOptions) {
+		o.mergeFile = pdfFile
+	}
+}
+
+// WithZipOutput writes the filled PDFs into a zip archive to w. The file
+// names are created from the pattern with the index of the form, like
+// "document_%05d.pdf", which is the default for an empty pattern.
+func WithZipOutput(w io.Writer, pattern string) BatchOption {
+	return func(o *batchOptions) {
+		o.zipWriter = w
+		o.zipPattern = pattern
+	}
+}
+
+// WithBoolColumns marks the columns of FillBatchCSV holding checkbox values.
+// Their cells are converted to bool: "1", "true", "yes", "y", "x" and "on"
+// check the box, "0", "false", "no", "n", "off" and empty cells uncheck it,
+// ignoring case.
+func WithBoolColumns(columns ...string) BatchOption {
+	return func(o *batchOptions) {
+		o.boolCols = append(o.boolCols, columns...)
+	}
+}
+
+// FillBatch fills the template once per form, e.g. for a mail merge, and
+// returns the results in the order of the forms. The fills run concurrently,
+// see WithWorkers, and share a single temporary directory, which is removed
+// again when the batch is done. Checkboxes use the strings set with
+// WithCheckedString and WithUncheckedString of WithFillOptions.
+//
+// A failing form does not affect the others, its error is part of its
+// result and it is left out of the merged PDF and the zip archive. The
+// returned error is only set if the template or pdftk is missing or the
+// merged PDF or the zip archive could not be written.
+func FillBatch(templatePath string, forms []Form, opts ...BatchOption) ([]BatchResult, error) {
+	return fillBatch(templatePath, forms, make([]BatchResult, len(forms)), newBatchOptions(opts))
+}
+
+func newBatchOptions(opts []BatchOption) *batchOptions {
+	bo := &batchOptions{}
+	for _, opt := range opts {
+		opt(bo)
+	}
+	return bo
+}
+
+// fillBatch fills the forms into results. Forms with a result error set
+// already are skipped.
+func fillBatch(templatePath string, forms []Form, results []BatchResult, bo *batchOptions) ([]BatchResult, error) {
+	o := newOptions(bo.fillOpts)
+	f, err := NewFiller(templatePath, o.checkedString, o.uncheckedString, bo.fillOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	workers := bo.workers
+	if workers <= 0 {
+		workers = MaxConcurrency()
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if results[i].Err != nil {
+					continue
+				}
+				pdf, err := f.Fill(forms[i], WithTempDir(tmpDir))
+				results[i] = BatchResult{PDF: pdf, Err: err}
+			}
+		}()
+	}
+
+	for i := range forms {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if bo.mergeFile != "" {
+		if err := mergeBatchResults(results, bo.mergeFile); err != nil {
+			return results, err
+		}
+	}
+	if bo.zipWriter != nil {
+		if err := zipBatchResults(results, bo.zipWriter, bo.zipPattern); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// mergeBatchResults concatenates the successfully filled PDFs into pdfFile.
+func mergeBatchResults(results []BatchResult, pdfFile string) error {
+	var pdfs [][]byte
+	for _, r := range results {
+		if r.Err == nil {
+			pdfs = append(pdfs, r.PDF)
+		}
+	}
+	if len(pdfs) == 0 {
+		return fmt.Errorf("no filled PDF to merge into '%s'", pdfFile)
+	}
+
+	merged, err := MergeBytes(pdfs...)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(pdfFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, merged); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// zipBatchResults writes the successfully filled PDFs into a zip archive.
+func zipBatchResults(results []BatchResult, w io.Writer, pattern string) error {
+	if pattern == "" {
+		pattern = "document_%05d.pdf"
+	}
+
+	zw := zip.NewWriter(w)
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		fw, err := zw.Create(fmt.Sprintf(pattern, i))
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(r.PDF); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// FillBatchCSV fills the template once per row of the CSV data like
+// FillBatch, e.g. with a spreadsheet export. The first row is the header.
+// The mapping maps the header names to the field names, columns without
+// mapping are skipped. With a nil mapping the header names are used as
+// field names. The cells of the columns set with WithBoolColumns are
+// checkbox values, all other cells are passed as strings. A row with an
+// invalid checkbox value is not filled and its result has the error set.
+func FillBatchCSV(templatePath string, r io.Reader, mapping map[string]string, opts ...BatchOption) ([]BatchResult, error) {
+	bo := newBatchOptions(opts)
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %v", err)
+	}
+	// Spreadsheet applications often prepend a byte order mark.
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	// Resolve the field name of each column, an empty name skips it.
+	fields := make([]string, len(header))
+	found := make(map[string]bool, len(header))
+	for i, column := range header {
+		found[column] = true
+		if mapping == nil {
+			fields[i] = column
+		} else {
+			fields[i] = mapping[column]
+		}
+	}
+	for column := range mapping {
+		if !found[column] {
+			return nil, fmt.Errorf("CSV column not found: '%s'", column)
+		}
+	}
+	boolCols := make(map[string]bool, len(bo.boolCols))
+	for _, column := range bo.boolCols {
+		if !found[column] {
+			return nil, fmt.Errorf("CSV column not found: '%s'", column)
+		}
+		boolCols[column] = true
+	}
+
+	var (
+		forms   []Form
+		results []BatchResult
+	)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read the CSV data: %v", err)
+		}
+
+		form := make(Form, len(record))
+		var rowErr error
+		for i, value := range record {
+			if fields[i] == "" {
+				continue
+			}
+			if !boolCols[header[i]] {
+				form[fields[i]] = value
+				continue
+			}
+			checked, err := parseCSVBool(value)
+			if err != nil && rowErr == nil {
+				rowErr = fmt.Errorf("row %d, column '%s': %v", len(forms)+1, header[i], err)
+			}
+			form[fields[i]] = checked
+		}
+		forms = append(forms, form)
+		results = append(results, BatchResult{Err: rowErr})
+	}
+
+	return fillBatch(templatePath, forms, results, bo)
+}
+
+// parseCSVBool parses the checkbox value of a CSV cell.
+func parseCSVBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "y", "x", "on":
+		return true, nil
+	case "", "0", "false", "no", "n", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid checkbox value: '%s'", s)
+}
@@ -0,0 +1,254 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// batchValues returns the value of the field name of each filled PDF, or
+// the error of its fill.
+func batchValues(t testing.TB, results []BatchResult) []string {
+	t.Helper()
+	values := make([]string, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			values[i] = "error"
+			continue
+		}
+		doc, err := parseNativePDF(r.PDF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[i] = doc.fieldValues()["name"]
+	}
+	return values
+}
+
+func TestFillBatch(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	forms := make([]Form, 20)
+	want := make([]string, len(forms))
+	for i := range forms {
+		forms[i] = Form{"name": fmt.Sprintf("Name %d", i)}
+		want[i] = fmt.Sprintf("Name %d", i)
+	}
+	// NativeBackend can't encode the characters, which fails the form
+	// without affecting the others.
+	forms[7] = Form{"name": "日本"}
+	want[7] = "error"
+
+	fillOpts := WithFillOptions(WithBackend(NativeBackend{}), WithFlatten(false))
+	for _, workers := range []int{0, 1, 4} {
+		results, err := FillBatch(template, forms, fillOpts, WithWorkers(workers))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := batchValues(t, results); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: values = %q, want %q", workers, got, want)
+		}
+	}
+}
+
+func TestFillBatchMissingTemplate(t *testing.T) {
+	_, err := FillBatch(filepath.Join(t.TempDir(), "missing.pdf"), []Form{{}}, WithFillOptions(WithBackend(NativeBackend{})))
+	if err == nil {
+		t.Error("FillBatch with a missing template succeeded")
+	}
+}
+
+func TestFillBatchZipOutput(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	forms := []Form{{"name": "Ann"}, {"name": "日本"}, {"name": "Cid"}}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"", []string{"document_00000.pdf", "document_00002.pdf"}},
+		{"invoice-%d.pdf", []string{"invoice-0.pdf", "invoice-2.pdf"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		results, err := FillBatch(template, forms,
+			WithFillOptions(WithBackend(NativeBackend{}), WithFlatten(false)),
+			WithZipOutput(&buf, tt.pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for i, f := range zr.File {
+			names = append(names, f.Name)
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := results[2*i]; !bytes.Equal(data, result.PDF) {
+				t.Errorf("pattern %q: %s differs from the result of form %d", tt.pattern, f.Name, 2*i)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("pattern %q: zip entries = %v, want %v", tt.pattern, names, tt.want)
+		}
+	}
+}
+
+func TestFillBatchMergeOutput(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	e := &recordExecutor{output: []byte("%PDF-merged")}
+	useExecutor(t, e)
+
+	output := filepath.Join(t.TempDir(), "merged.pdf")
+	forms := []Form{{"name": "Ann"}, {"name": "日本"}, {"name": "Cid"}}
+	_, err := FillBatch(template, forms,
+		WithFillOptions(WithBackend(NativeBackend{})),
+		WithMergeOutput(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(output); err != nil || string(data) != "%PDF-merged" {
+		t.Errorf("merged output = %q, %v, want the pdftk output", data, err)
+	}
+	if call := e.lastCall(); len(call) != 6 || call[3] != "cat" {
+		t.Errorf("pdftk call = %v, want the two filled PDFs concatenated", call)
+	}
+
+	// Nothing to merge if all fills fail.
+	_, err = FillBatch(template, []Form{{"name": "日本"}},
+		WithFillOptions(WithBackend(NativeBackend{})),
+		WithMergeOutput(output))
+	if err == nil {
+		t.Error("FillBatch merged no filled PDF")
+	}
+}
+
+func TestFillBatchCSV(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	data := "\ufeffCustomer,Accepted,City,Notes\n" +
+		"Ann,yes,Berlin,first\n" +
+		"Bob,maybe,Hamburg,second\n" +
+		"Cid,,Munich,third\n"
+	mapping := map[string]string{"Customer": "name", "Accepted": "agree", "City": "address.city"}
+
+	results, err := FillBatchCSV(template, strings.NewReader(data), mapping,
+		WithFillOptions(WithBackend(NativeBackend{}), WithFlatten(false),
+			WithCheckedString("Yes"), WithUncheckedString("Off")),
+		WithBoolColumns("Accepted"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one per row", len(results))
+	}
+	if err := results[1].Err; err == nil || !strings.Contains(err.Error(), "row 2, column 'Accepted'") {
+		t.Errorf("error of the invalid row = %v, want the row and column", err)
+	}
+
+	want := []map[string]string{
+		{"name": "Ann", "agree": "Yes", "address.city": "Berlin"},
+		nil,
+		{"name": "Cid", "agree": "Off", "address.city": "Munich"},
+	}
+	for i, r := range results {
+		if want[i] == nil {
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("row %d: %v", i+1, r.Err)
+			continue
+		}
+		doc, err := parseNativePDF(r.PDF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := doc.fieldValues()
+		for name, value := range want[i] {
+			if values[name] != value {
+				t.Errorf("row %d: %s = %q, want %q", i+1, name, values[name], value)
+			}
+		}
+	}
+}
+
+func TestFillBatchCSVHeaderNames(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	results, err := FillBatchCSV(template, strings.NewReader("name\nAnn\n"), nil,
+		WithFillOptions(WithBackend(NativeBackend{}), WithFlatten(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batchValues(t, results); !reflect.DeepEqual(got, []string{"Ann"}) {
+		t.Errorf("values = %q, want the header names as field names", got)
+	}
+}
+
+func TestFillBatchCSVInvalid(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	tests := []struct {
+		name    string
+		data    string
+		mapping map[string]string
+		opts    []BatchOption
+	}{
+		{"empty", "", nil, nil},
+		{"mapped column", "name\nAnn\n", map[string]string{"Customer": "name"}, nil},
+		{"bool column", "name\nAnn\n", nil, []BatchOption{WithBoolColumns("agree")}},
+		{"row length", "name,agree\nAnn\n", nil, nil},
+	}
+	for _, tt := range tests {
+		opts := append([]BatchOption{WithFillOptions(WithBackend(NativeBackend{}))}, tt.opts...)
+		if _, err := FillBatchCSV(template, strings.NewReader(tt.data), tt.mapping, opts...); err == nil {
+			t.Errorf("FillBatchCSV(%s) succeeded", tt.name)
+		}
+	}
+}
+
+func TestParseCSVBool(t *testing.T) {
+	for _, s := range []string{"1", "true", "Yes", "y", "X", " on "} {
+		if checked, err := parseCSVBool(s); !checked || err != nil {
+			t.Errorf("parseCSVBool(%q) = %v, %v, want true", s, checked, err)
+		}
+	}
+	for _, s := range []string{"", "0", "FALSE", "no", "n", "off"} {
+		if checked, err := parseCSVBool(s); checked || err != nil {
+			t.Errorf("parseCSVBool(%q) = %v, %v, want false", s, checked, err)
+		}
+	}
+	if _, err := parseCSVBool("maybe"); err == nil {
+		t.Error("parseCSVBool(maybe) succeeded")
+	}
+}
@@ -0,0 +1,130 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+const (
+	// DefaultBlankThreshold is the ink coverage below which DetectBlankPages
+	// considers a page blank. It tolerates the specks of scanned pages.
+	DefaultBlankThreshold = 0.001
+
+	// blankRenderDPI is enough to see text while keeping the rendering fast.
+	blankRenderDPI = 50
+	// inkLevel is the gray level below which a pixel counts as ink, so the
+	// light background noise of scans is ignored.
+	inkLevel = 0xE0
+)
+
+// InkCoverage renders the pages of the PDF and returns for each page the
+// ratio of pixels covered with ink, from 0 (white) to 1. It requires the
+// pdftoppm utility of poppler-utils.
+func InkCoverage(pdfFile string) ([]float64, error) {
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	pages, err := renderPages(pdfFile, tmpDir, blankRenderDPI, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := make([]float64, len(pages))
+	for i, path := range pages {
+		img, err := readPNG(path)
+		if err != nil {
+			return nil, err
+		}
+		coverage[i] = inkCoverage(img)
+	}
+	return coverage, nil
+}
+
+// DetectBlankPages returns the numbers of the pages (starting at 1) with an
+// ink coverage below DefaultBlankThreshold. See InkCoverage.
+func DetectBlankPages(pdfFile string) ([]int, error) {
+	return detectBlankPages(pdfFile, DefaultBlankThreshold)
+}
+
+// RemoveBlankPages writes the input PDF without the pages having an ink
+// coverage below threshold to output. A threshold <= 0 selects
+// DefaultBlankThreshold. See InkCoverage.
+func RemoveBlankPages(input, output string, threshold float64) error {
+	if threshold <= 0 {
+		threshold = DefaultBlankThreshold
+	}
+	if threshold >= 1 {
+		return fmt.Errorf("invalid ink coverage threshold: %v", threshold)
+	}
+
+	blank, err := detectBlankPages(input, threshold)
+	if err != nil {
+		return err
+	}
+	if len(blank) == 0 {
+		return runPdftkFile(input, output)
+	}
+	return RemovePages(input, blank, output)
+}
+
+func detectBlankPages(pdfFile string, threshold float64) ([]int, error) {
+	coverage, err := InkCoverage(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var blank []int
+	for i, c := range coverage {
+		if c < threshold {
+			blank = append(blank, i+1)
+		}
+	}
+	return blank, nil
+}
+
+// inkCoverage returns the ratio of pixels darker than inkLevel.
+func inkCoverage(img image.Image) float64 {
+	r := img.Bounds()
+	if r.Empty() {
+		return 0
+	}
+
+	ink := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < inkLevel {
+				ink++
+			}
+		}
+	}
+	return float64(ink) / float64(r.Dx()*r.Dy())
+}
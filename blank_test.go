@@ -0,0 +1,153 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPage returns a white 100x100 page image with the top share of the rows
+// filled with the color.
+func testPage(share float64, c color.Color) image.Image {
+	img := image.NewGray(image.Rect(0, 0, 100, 100))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 100, int(share*100)), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestInkCoverage(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want float64
+	}{
+		{"white", testPage(0, color.Black), 0},
+		{"half black", testPage(0.5, color.Black), 0.5},
+		{"black", testPage(1, color.Black), 1},
+		{"light noise", testPage(1, color.Gray{Y: inkLevel}), 0},
+		{"dark gray", testPage(0.25, color.Gray{Y: inkLevel - 1}), 0.25},
+		{"empty", image.NewGray(image.Rectangle{}), 0},
+	}
+	for _, tt := range tests {
+		if got := inkCoverage(tt.img); got != tt.want {
+			t.Errorf("inkCoverage(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// renderExecutor renders the pages for pdftoppm -png and runs pdftk like
+// recordExecutor.
+type renderExecutor struct {
+	recordExecutor
+	pages []image.Image
+}
+
+// Run implements Executor.
+func (e *renderExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	if name != "pdftoppm" {
+		return e.recordExecutor.Run(dir, name, args, stdin)
+	}
+	prefix := args[len(args)-1]
+	for i, img := range e.pages {
+		f, err := os.Create(fmt.Sprintf("%s-%d.png", prefix, i+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+func TestDetectBlankPages(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+	pages := []image.Image{
+		testPage(0.2, color.Black),
+		testPage(0, color.Black),
+		testPage(0.0001, color.Black),
+		testPage(1, color.Gray{Y: 0xF0}),
+		testPage(0.05, color.Black),
+	}
+	useExecutor(t, &renderExecutor{pages: pages})
+
+	blank, err := DetectBlankPages(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3, 4}; !reflect.DeepEqual(blank, want) {
+		t.Errorf("blank pages = %v, want %v", blank, want)
+	}
+}
+
+func TestRemoveBlankPages(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+	output := filepath.Join(t.TempDir(), "output.pdf")
+	pages := []image.Image{
+		testPage(0.2, color.Black),
+		testPage(0, color.Black),
+		testPage(0.05, color.Black),
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      string
+		wantErr   bool
+	}{
+		{name: "default", threshold: 0, want: "cat 1 3"},
+		{name: "higher", threshold: 0.1, want: "cat 1"},
+		{name: "invalid", threshold: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &renderExecutor{pages: pages}
+			e.stdout = []byte("NumberOfPages: 3\n")
+			e.output = []byte("%PDF-1.4")
+			useExecutor(t, e)
+
+			err := RemoveBlankPages(input, output, tt.threshold)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("RemoveBlankPages succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if call := strings.Join(e.lastCall(), " "); !strings.Contains(call, " "+tt.want+" output ") {
+				t.Errorf("pdftk call = %q, want %q", call, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Capabilities lists the operations and output options the installed pdftk
+// binary implements, as documented by its help text.
+type Capabilities struct {
+	Operations    map[string]bool
+	OutputOptions map[string]bool
+}
+
+// knownOperations are looked up individually in the help text, if the
+// operation list of the synopsis can't be found.
+var knownOperations = []string{
+	"cat", "shuffle", "burst", "rotate", "generate_fdf", "fill_form",
+	"background", "multibackground", "stamp", "multistamp",
+	"dump_data", "dump_data_utf8", "dump_data_fields", "dump_data_fields_utf8",
+	"dump_data_annots", "update_info", "update_info_utf8",
+	"attach_files", "unpack_files",
+}
+
+var (
+	capabilitiesMutex  sync.Mutex
+	cachedCapabilities *Capabilities
+
+	helpWordRegex        = regexp.MustCompile(`[a-z][a-z0-9_]+`)
+	helpPlaceholderRegex = regexp.MustCompile(`<[^>]*>`)
+)
+
+// PDFTKCapabilities parses the help text of the installed pdftk binary.
+// The result is cached after the first successful call.
+func PDFTKCapabilities() (*Capabilities, error) {
+	capabilitiesMutex.Lock()
+	defer capabilitiesMutex.Unlock()
+
+	if cachedCapabilities != nil {
+		return cachedCapabilities, nil
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	out, err := runCommandWithOutput("", "pdftk", "--help")
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	c := parseCapabilities(string(out))
+	if len(c.Operations) == 0 {
+		return nil, fmt.Errorf("failed to parse the pdftk help text")
+	}
+
+	cachedCapabilities = c
+	return c, nil
+}
+
+// SupportsOperation returns whenever the installed pdftk binary implements
+// the operation, e.g. "dump_data_fields_utf8".
+func SupportsOperation(operation string) (bool, error) {
+	c, err := PDFTKCapabilities()
+	if err != nil {
+		return false, err
+	}
+	return c.Operations[operation], nil
+}
+
+// SupportsOutputOption returns whenever the installed pdftk binary implements
+// the output option, e.g. "need_appearances".
+func SupportsOutputOption(option string) (bool, error) {
+	c, err := PDFTKCapabilities()
+	if err != nil {
+		return false, err
+	}
+	return c.OutputOptions[option], nil
+}
+
+var (
+	versionMutex  sync.Mutex
+	cachedVersion string
+
+	versionRegex = regexp.MustCompile(`(?m)^pdftk\s+(port to java\s+)?([0-9][0-9.]*)`)
+)
+
+// PDFTKVersion returns the version of the installed pdftk binary, e.g.
+// "2.02" for pdftk and PDFtk Server or "java 3.3.3" for pdftk-java. The
+// result is cached after the first successful call.
+func PDFTKVersion() (string, error) {
+	versionMutex.Lock()
+	defer versionMutex.Unlock()
+
+	if cachedVersion != "" {
+		return cachedVersion, nil
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return "", err
+	}
+
+	out, err := runCommandWithOutput("", "pdftk", "--version")
+	if err != nil {
+		return "", fmt.Errorf("pdftk error: %w", err)
+	}
+
+	version, ok := parseVersion(string(out))
+	if !ok {
+		return "", fmt.Errorf("failed to parse the pdftk version")
+	}
+
+	cachedVersion = version
+	return version, nil
+}
+
+// PDFTKVersionAtLeast returns whenever the installed pdftk binary has at
+// least the version min, e.g. "2.02" or "java 3.2". The versions of pdftk and
+// pdftk-java are not comparable, so a different lineage never satisfies min.
+func PDFTKVersionAtLeast(min string) (bool, error) {
+	version, err := PDFTKVersion()
+	if err != nil {
+		return false, err
+	}
+
+	java := strings.HasPrefix(version, "java ")
+	if java != strings.HasPrefix(min, "java ") {
+		return false, nil
+	}
+	have := strings.Split(strings.TrimPrefix(version, "java "), ".")
+	want := strings.Split(strings.TrimPrefix(min, "java "), ".")
+	for i := 0; i < len(have) || i < len(want); i++ {
+		var h, w int
+		if i < len(have) {
+			h, _ = strconv.Atoi(have[i])
+		}
+		if i < len(want) {
+			w, _ = strconv.Atoi(want[i])
+		}
+		if h != w {
+			return h > w, nil
+		}
+	}
+	return true, nil
+}
+
+// parseVersion parses the first line of pdftk --version.
+func parseVersion(out string) (string, bool) {
+	m := versionRegex.FindStringSubmatch(out)
+	if m == nil {
+		return "", false
+	}
+	if m[1] != "" {
+		return "java " + m[2], true
+	}
+	return m[2], true
+}
+
+// utf8Operation returns the UTF-8 variant of the pdftk operation, like
+// "dump_data_utf8", unless the installed pdftk is known to lack it. The
+// values of the plain operations are ASCII with XML character references.
+func utf8Operation(operation string) string {
+	if ok, err := SupportsOperation(operation + "_utf8"); err == nil && !ok {
+		return operation
+	}
+	return operation + "_utf8"
+}
+
+// parseCapabilities parses the synopsis of the pdftk help text. Both pdftk and
+// pdftk-java list the operations after "<operation> may be empty, or:" and
+// the output options between the "output" and the "Where:" line, but differ
+// in their indentation and wrapping.
+func parseCapabilities(help string) *Capabilities {
+	c := &Capabilities{
+		Operations:    make(map[string]bool),
+		OutputOptions: make(map[string]bool),
+	}
+
+	if i := strings.Index(help, "may be empty, or:"); i >= 0 {
+		list := help[i+len("may be empty, or:"):]
+		if start := strings.Index(list, "["); start >= 0 {
+			if end := strings.Index(list[start:], "]"); end >= 0 {
+				for _, op := range strings.Split(list[start+1:start+end], "|") {
+					if op = strings.TrimSpace(op); op != "" {
+						c.Operations[op] = true
+					}
+				}
+			}
+		}
+	}
+
+	// Fall back to searching the known operations as words.
+	if len(c.Operations) == 0 {
+		for _, op := range knownOperations {
+			if regexp.MustCompile(`\b` + op + `\b`).MatchString(help) {
+				c.Operations[op] = true
+			}
+		}
+	}
+
+	if start := strings.Index(help, "[ output "); start >= 0 {
+		synopsis := help[start:]
+		if end := strings.Index(synopsis, "Where:"); end >= 0 {
+			synopsis = synopsis[:end]
+		}
+		if nl := strings.Index(synopsis, "\n"); nl >= 0 {
+			synopsis = helpPlaceholderRegex.ReplaceAllString(synopsis[nl:], "")
+			for _, opt := range helpWordRegex.FindAllString(synopsis, -1) {
+				c.OutputOptions[opt] = true
+			}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,179 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// pdftkServerHelp is the synopsis of the pdftk 2.02 help text.
+const pdftkServerHelp = `pdftk 2.02 a Handy Tool for Manipulating PDF Documents
+Copyright (c) 2003-13 Steward and Lee, LLC - Please Visit: www.pdftk.com
+
+  SYNOPSIS
+       pdftk <input PDF files | - | PROMPT>
+	    [ input_pw <input PDF owner passwords | PROMPT> ]
+	    [ <operation> <operation arguments> ]
+	    [ output <output filename | - | PROMPT> ]
+	    [ encrypt_40bit | encrypt_128bit ]
+	    [ allow <permissions> ]
+	    [ owner_pw <owner password | PROMPT> ]
+	    [ user_pw <user password | PROMPT> ]
+	    [ flatten ] [ need_appearances ]
+	    [ compress | uncompress ]
+	    [ keep_first_id | keep_final_id ] [ drop_xfa ] [ drop_xmp ]
+	    [ verbose ] [ dont_ask | do_ask ]
+       Where:
+	    <operation> may be empty, or:
+	    [ cat | shuffle | burst | rotate |
+	      generate_fdf | fill_form |
+	      background | multibackground |
+	      stamp | multistamp |
+	      dump_data | dump_data_utf8 |
+	      dump_data_fields | dump_data_fields_utf8 |
+	      dump_data_annots |
+	      update_info | update_info_utf8 |
+	      attach_files | unpack_files ]
+
+       For Complete Help: pdftk --help
+`
+
+// pdftkJavaHelp is the synopsis of the pdftk-java 3.3.3 help text, which is
+// indented with spaces and knows more output options.
+const pdftkJavaHelp = `pdftk port to java 3.3.3 a Handy Tool for Manipulating PDF Documents
+Copyright (c) 2017-2018 Marc Vinyals - https://gitlab.com/pdftk-java/pdftk
+
+SYNOPSIS
+       pdftk <input PDF files | - | PROMPT>
+            [ input_pw <input PDF owner passwords | PROMPT> ]
+            [ <operation> <operation arguments> ]
+            [ output <output filename | - | PROMPT> ]
+            [ encrypt_40bit | encrypt_128bit | encrypt_aes128 ]
+            [ allow <permissions> ]
+            [ owner_pw <owner password | PROMPT> ]
+            [ user_pw <user password | PROMPT> ]
+            [ flatten ] [ need_appearances ]
+            [ compress | uncompress ]
+            [ keep_first_id | keep_final_id ] [ drop_xfa ] [ drop_xmp ]
+            [ replacement_font <font name> ]
+            [ verbose ] [ dont_ask | do_ask ]
+       Where:
+            <operation> may be empty, or:
+            [ cat | shuffle | burst | rotate |
+              generate_fdf | fill_form |
+              background | multibackground |
+              stamp | multistamp |
+              dump_data | dump_data_utf8 |
+              dump_data_fields | dump_data_fields_utf8 |
+              dump_data_annots |
+              update_info | update_info_utf8 |
+              attach_files | unpack_files ]
+`
+
+func TestParseCapabilities(t *testing.T) {
+	operations := []string{
+		"attach_files", "background", "burst", "cat", "dump_data", "dump_data_annots",
+		"dump_data_fields", "dump_data_fields_utf8", "dump_data_utf8", "fill_form",
+		"generate_fdf", "multibackground", "multistamp", "rotate", "shuffle", "stamp",
+		"unpack_files", "update_info", "update_info_utf8",
+	}
+	outputOptions := []string{
+		"allow", "compress", "do_ask", "dont_ask", "drop_xfa", "drop_xmp",
+		"encrypt_128bit", "encrypt_40bit", "flatten", "keep_final_id", "keep_first_id",
+		"need_appearances", "owner_pw", "uncompress", "user_pw", "verbose",
+	}
+
+	tests := []struct {
+		name              string
+		help              string
+		wantOperations    []string
+		wantOutputOptions []string
+	}{
+		{"pdftk", pdftkServerHelp, operations, outputOptions},
+		{"pdftk-java", pdftkJavaHelp, operations, append([]string{"encrypt_aes128", "replacement_font"}, outputOptions...)},
+		{"excerpt", pdftkHelp, []string{"cat", "dump_data", "dump_data_fields", "fill_form"}, []string{"encrypt_128bit", "encrypt_40bit", "flatten", "need_appearances"}},
+		{
+			"no operation list",
+			"pdftk 1.41\nUse cat to join and split, burst to split into pages\nand dump_data_fields to list the form fields.\n",
+			[]string{"burst", "cat", "dump_data_fields"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := parseCapabilities(tt.help)
+			if got := sortedKeys(c.Operations); !reflect.DeepEqual(got, sorted(tt.wantOperations)) {
+				t.Errorf("operations = %q, want %q", got, sorted(tt.wantOperations))
+			}
+			if got := sortedKeys(c.OutputOptions); !reflect.DeepEqual(got, sorted(tt.wantOutputOptions)) {
+				t.Errorf("output options = %q, want %q", got, sorted(tt.wantOutputOptions))
+			}
+		})
+	}
+}
+
+func TestPDFTKCapabilitiesCached(t *testing.T) {
+	e := &recordExecutor{stdout: []byte(pdftkJavaHelp)}
+	useExecutor(t, e)
+
+	for i := 0; i < 2; i++ {
+		if ok, err := SupportsOperation("dump_data_fields_utf8"); err != nil || !ok {
+			t.Errorf("SupportsOperation(dump_data_fields_utf8) = %v, %v, want true", ok, err)
+		}
+		if ok, err := SupportsOperation("drop_xfa"); err != nil || ok {
+			t.Errorf("SupportsOperation(drop_xfa) = %v, %v, want false", ok, err)
+		}
+		if ok, err := SupportsOutputOption("encrypt_aes128"); err != nil || !ok {
+			t.Errorf("SupportsOutputOption(encrypt_aes128) = %v, %v, want true", ok, err)
+		}
+	}
+	if len(e.calls) != 1 {
+		t.Errorf("pdftk calls = %q, want a single pdftk --help", e.calls)
+	}
+}
+
+func TestPDFTKCapabilitiesUnparsable(t *testing.T) {
+	useExecutor(t, &recordExecutor{stdout: []byte("usage: something else\n")})
+
+	if _, err := PDFTKCapabilities(); err == nil {
+		t.Error("PDFTKCapabilities() of an unknown help text succeeded")
+	}
+}
+
+// sortedKeys returns the keys of the set in sorted order, nil if it is empty.
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return sorted(keys)
+}
+
+// sorted returns a sorted copy of s, nil if it is empty.
+func sorted(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	s = append([]string(nil), s...)
+	sort.Strings(s)
+	return s
+}
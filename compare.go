@@ -0,0 +1,127 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// CompareOptions configures ComparePDFs.
+type CompareOptions struct {
+	// DPI is the render resolution. Defaults to 72.
+	DPI int
+	// Tolerance is the maximum difference of a color channel (0-255) two
+	// pixels may have to still count as equal.
+	Tolerance uint8
+}
+
+// ComparePDFs renders both PDFs and returns for each page the ratio of
+// differing pixels, from 0 (identical) to 1. Pages missing in one of the
+// documents or having a different size count as completely different.
+//
+// ComparePDFs is meant for visual regression tests against golden files,
+// where metadata and timestamps make byte comparisons useless. It requires
+// the pdftoppm utility of poppler-utils.
+func ComparePDFs(pdfFileA, pdfFileB string, opts CompareOptions) ([]float64, error) {
+	if opts.DPI <= 0 {
+		opts.DPI = 72
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	var pages [2][]string
+	for i, f := range []string{pdfFileA, pdfFileB} {
+		dir := filepath.Join(tmpDir, string(rune('a'+i)))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, err
+		}
+		if pages[i], err = renderPages(f, dir, opts.DPI, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	n := len(pages[0])
+	if len(pages[1]) > n {
+		n = len(pages[1])
+	}
+
+	ratios := make([]float64, n)
+	for i := range ratios {
+		if i >= len(pages[0]) || i >= len(pages[1]) {
+			ratios[i] = 1
+			continue
+		}
+
+		a, err := readPNG(pages[0][i])
+		if err != nil {
+			return nil, err
+		}
+		b, err := readPNG(pages[1][i])
+		if err != nil {
+			return nil, err
+		}
+		ratios[i] = diffRatio(a, b, opts.Tolerance)
+	}
+
+	return ratios, nil
+}
+
+// diffRatio returns the ratio of pixels differing by more than tolerance.
+func diffRatio(a, b image.Image, tolerance uint8) float64 {
+	ra, rb := a.Bounds(), b.Bounds()
+	if ra.Dx() != rb.Dx() || ra.Dy() != rb.Dy() {
+		return 1
+	}
+	if ra.Empty() {
+		return 0
+	}
+
+	exceeds := func(x, y uint32) bool {
+		// Colors are 16 bit per channel.
+		x, y = x>>8, y>>8
+		if x > y {
+			return x-y > uint32(tolerance)
+		}
+		return y-x > uint32(tolerance)
+	}
+
+	diff := 0
+	for y := 0; y < ra.Dy(); y++ {
+		for x := 0; x < ra.Dx(); x++ {
+			r1, g1, b1, a1 := a.At(ra.Min.X+x, ra.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(rb.Min.X+x, rb.Min.Y+y).RGBA()
+			if exceeds(r1, r2) || exceeds(g1, g2) || exceeds(b1, b2) || exceeds(a1, a2) {
+				diff++
+			}
+		}
+	}
+	return float64(diff) / float64(ra.Dx()*ra.Dy())
+}
@@ -0,0 +1,95 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRatio(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      image.Image
+		tolerance uint8
+		want      float64
+	}{
+		{"identical", testPage(0.5, color.Black), testPage(0.5, color.Black), 0, 0},
+		{"quarter", testPage(0.5, color.Black), testPage(0.25, color.Black), 0, 0.25},
+		{"within tolerance", testPage(1, color.Gray{Y: 0xF0}), testPage(0, color.Black), 0x0F, 0},
+		{"over tolerance", testPage(1, color.Gray{Y: 0xF0}), testPage(0, color.Black), 0x0E, 1},
+		{"size", testPage(0, color.Black), image.NewGray(image.Rect(0, 0, 100, 50)), 0xFF, 1},
+		{"empty", image.NewGray(image.Rectangle{}), image.NewGray(image.Rectangle{}), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := diffRatio(tt.a, tt.b, tt.tolerance); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("diffRatio(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// compareExecutor renders the pages of each input file for pdftoppm -png.
+type compareExecutor struct {
+	pages map[string][]image.Image
+	args  [][]string
+}
+
+// Run implements Executor.
+func (e *compareExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.args = append(e.args, args)
+	r := &renderExecutor{pages: e.pages[args[len(args)-2]]}
+	return r.Run(dir, name, args, stdin)
+}
+
+func TestComparePDFs(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 1)
+	b := writeTestPDF(t, "b.pdf", 1)
+	e := &compareExecutor{pages: map[string][]image.Image{
+		a: {testPage(0.5, color.Black), testPage(0, color.Black), testPage(0, color.Black)},
+		b: {testPage(0.5, color.Black), testPage(0.1, color.Black)},
+	}}
+	useExecutor(t, e)
+
+	ratios, err := ComparePDFs(a, b, CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0, 0.1, 1}; !reflect.DeepEqual(ratios, want) {
+		t.Errorf("ratios = %v, want %v", ratios, want)
+	}
+	for _, args := range e.args {
+		if got := args[:3]; !reflect.DeepEqual(got, []string{"-png", "-r", "72"}) {
+			t.Errorf("pdftoppm arguments = %v, want the default of 72 DPI", args)
+		}
+	}
+
+	e.args = nil
+	if _, err := ComparePDFs(a, b, CompareOptions{DPI: 150}); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.args) != 2 || e.args[0][2] != "150" || filepath.Dir(e.args[0][4]) == filepath.Dir(e.args[1][4]) {
+		t.Errorf("pdftoppm arguments = %v, want 150 DPI into separate directories", e.args)
+	}
+}
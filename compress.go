@@ -0,0 +1,143 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Compression is the stream compression used by FillToCompressedWriter.
+type Compression int
+
+const (
+	// Gzip compresses with compress/gzip. The level is one of the
+	// compress/gzip levels, e.g. gzip.BestCompression.
+	Gzip Compression = iota
+	// Zstd compresses with the zstd utility, which has to be installed.
+	// The level ranges from 1 to 19, 0 selects the zstd default.
+	Zstd
+)
+
+// FillToCompressedWriter fills the PDF form like FillPDFToBytes and streams
+// the compressed output to w, without buffering the PDF in memory. The
+// progress set with WithProgress reports the compressed bytes written to w.
+// Note that PDF streams are usually compressed already, so the size gain is
+// modest for most documents; flattened forms with large uncompressed content
+// benefit the most.
+func FillToCompressedWriter(form Form, formPDFFile string, w io.Writer, compression Compression, level int, checkedString, uncheckedString string, opts ...Option) error {
+	var err error
+	o := newOptions(opts)
+
+	// Check if the pdftk utility exists, if it is used.
+	if err := o.lookPathBackend(); err != nil {
+		return err
+	}
+
+	// Get the absolute path.
+	if formPDFFile, err = getAbs(formPDFFile); err != nil {
+		return err
+	}
+
+	cw, err := newCompressWriter(o.progressWriter(w), compression, level)
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		cw.Close()
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	err = fillPDFToWriter(context.Background(), form, formPDFFile, tmpDir, checkedString, uncheckedString, o, cw)
+	if cerr := cw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func newCompressWriter(w io.Writer, compression Compression, level int) (io.WriteCloser, error) {
+	switch compression {
+	case Gzip:
+		return gzip.NewWriterLevel(w, level)
+	case Zstd:
+		return newZstdWriter(w, level)
+	}
+	return nil, fmt.Errorf("invalid compression: %d", compression)
+}
+
+// zstdWriter pipes the written data through the zstd utility.
+type zstdWriter struct {
+	*io.PipeWriter
+	done   chan error
+	stderr bytes.Buffer
+}
+
+func newZstdWriter(w io.Writer, level int) (*zstdWriter, error) {
+	if level < 0 || level > 19 {
+		return nil, fmt.Errorf("invalid zstd compression level: %d", level)
+	}
+
+	// Check if the zstd utility exists.
+	if err := lookPath("zstd"); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-q", "-c"}
+	if level > 0 {
+		args = append(args, "-"+strconv.Itoa(level))
+	}
+
+	// zstd runs concurrently to the producer of the data, so it must not
+	// wait for a process slot.
+	r, pw := io.Pipe()
+	z := &zstdWriter{PipeWriter: pw, done: make(chan error, 1)}
+	go func() {
+		start := time.Now()
+		err := execute(context.Background(), "", r, w, &z.stderr, "zstd", args...)
+		traceCommand("zstd", args, start, err)
+		r.CloseWithError(err)
+		z.done <- err
+	}()
+	return z, nil
+}
+
+// Close flushes the compressed data and waits for zstd to exit.
+func (z *zstdWriter) Close() error {
+	z.PipeWriter.Close()
+	if err := <-z.done; err != nil {
+		return fmt.Errorf("zstd error: %s", strings.TrimSpace(z.stderr.String()))
+	}
+	return nil
+}
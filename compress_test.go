@@ -0,0 +1,128 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFillToCompressedWriterGzip(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	var written int64
+	var out bytes.Buffer
+	err := FillToCompressedWriter(Form{"name": "Ann"}, template, &out, Gzip, gzip.BestCompression, "Yes", "Off",
+		WithBackend(NativeBackend{}),
+		WithFlatten(false),
+		WithProgress(func(n int64) {
+			written = n
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(out.Len()) {
+		t.Errorf("progress = %d, want the %d compressed bytes", written, out.Len())
+	}
+
+	zr, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := doc.fieldValues(); values["name"] != "Ann" {
+		t.Errorf("field values = %v, want name Ann", values)
+	}
+}
+
+// zstdExecutor emulates the zstd utility by prefixing its input.
+type zstdExecutor struct {
+	mutex sync.Mutex
+	args  []string
+}
+
+// Run implements Executor.
+func (e *zstdExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	e.args = append([]string{name}, args...)
+	e.mutex.Unlock()
+
+	data, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte("zstd:"), data...), nil, nil
+}
+
+func TestFillToCompressedWriterZstd(t *testing.T) {
+	e := &zstdExecutor{}
+	useExecutor(t, e)
+
+	var out bytes.Buffer
+	err := FillToCompressedWriter(Form{"name": "Ann"}, writeTestForm(t, "form.pdf"), &out, Zstd, 3, "Yes", "Off", WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("zstd:%PDF-")) {
+		t.Errorf("output = %q, want the PDF piped through zstd", out.Bytes()[:16])
+	}
+	if got, want := strings.Join(e.args, " "), "zstd -q -c -3"; got != want {
+		t.Errorf("zstd args = %q, want %q", got, want)
+	}
+}
+
+func TestFillToCompressedWriterInvalid(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name        string
+		compression Compression
+		level       int
+	}{
+		{"compression", Compression(7), 0},
+		{"gzip level", Gzip, 12},
+		{"zstd level", Zstd, 20},
+		{"negative zstd level", Zstd, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := FillToCompressedWriter(Form{"name": "Ann"}, template, &out, tt.compression, tt.level, "Yes", "Off", WithBackend(NativeBackend{})); err == nil {
+				t.Error("FillToCompressedWriter succeeded")
+			}
+			if out.Len() != 0 {
+				t.Errorf("output = %q, want none", out.Bytes())
+			}
+		})
+	}
+}
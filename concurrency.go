@@ -0,0 +1,76 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	concurrencyMutex sync.Mutex
+	concurrencyLimit int
+	concurrencySem   chan struct{}
+)
+
+// SetMaxConcurrency limits the number of concurrently running pdftk processes
+// for the whole package. Further calls block until a process slot is free.
+// Other utilities like pdftoppm and qrencode are not limited. A value <= 0
+// removes the limit, which is the default.
+func SetMaxConcurrency(n int) {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+
+	if n <= 0 {
+		concurrencyLimit = 0
+		concurrencySem = nil
+		return
+	}
+	concurrencyLimit = n
+	concurrencySem = make(chan struct{}, n)
+}
+
+// MaxConcurrency returns the limit set with SetMaxConcurrency, 0 if unlimited.
+func MaxConcurrency() int {
+	concurrencyMutex.Lock()
+	defer concurrencyMutex.Unlock()
+	return concurrencyLimit
+}
+
+// acquireProcessContext blocks until the command <name> may be started and
+// returns the function releasing the slot again. It gives up waiting once the
+// context is done. Only pdftk processes are limited.
+func acquireProcessContext(ctx context.Context, name string) (func(), error) {
+	concurrencyMutex.Lock()
+	sem := concurrencySem
+	concurrencyMutex.Unlock()
+
+	if sem == nil || name != "pdftk" {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-sem
+	}, nil
+}
@@ -0,0 +1,83 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// blockExecutor blocks pdftk until release is closed and signals each
+// started pdftk process on started.
+type blockExecutor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+// Run implements Executor.
+func (e *blockExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	if name == "pdftk" {
+		e.started <- struct{}{}
+		<-e.release
+	}
+	return nil, nil, nil
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	e := &blockExecutor{started: make(chan struct{}, 2), release: make(chan struct{})}
+	useExecutor(t, e)
+	SetMaxConcurrency(1)
+	defer SetMaxConcurrency(0)
+
+	if n := MaxConcurrency(); n != 1 {
+		t.Errorf("MaxConcurrency() = %d, want 1", n)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- runCommandInPath("", "pdftk", "a.pdf", "dump_data")
+	}()
+	<-e.started
+
+	// A second pdftk process waits for the slot until the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := runCommandWithOutputContext(ctx, "", "pdftk", "b.pdf", "dump_data"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second pdftk error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Other utilities are not limited.
+	if err := runCommandInPath("", "pdftoppm", "a.pdf", "page"); err != nil {
+		t.Errorf("pdftoppm error = %v", err)
+	}
+
+	close(e.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The slot is free again.
+	if err := runCommandInPath("", "pdftk", "c.pdf", "dump_data"); err != nil {
+		t.Errorf("pdftk after release error = %v", err)
+	}
+}
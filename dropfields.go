@@ -0,0 +1,147 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+)
+
+// WithDropUnusedFields removes the form fields without a value in the form
+// from the template before it is filled, so they neither stay interactive nor
+// show their default values after flattening.
+func WithDropUnusedFields() Option {
+	return func(o *options) {
+		o.dropUnusedFields = true
+	}
+}
+
+// WithKeepFields removes all form fields except the given ones from the
+// template before it is filled, like WithDropUnusedFields. Combined with
+// WithDropUnusedFields, the fields with a value are kept as well.
+func WithKeepFields(names ...string) Option {
+	return func(o *options) {
+		o.keepFields = append(o.keepFields, names...)
+	}
+}
+
+// dropFields writes the template without the fields not to keep to
+// templateFile, if there are any, and returns the template to fill.
+func dropFields(keep map[string]bool, formPDFFile, templateFile, password string) (string, error) {
+	doc, err := loadPDFFileWithPassword(formPDFFile, password)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := doc.removeFields(keep)
+	if err != nil || u == nil {
+		return formPDFFile, err
+	}
+
+	if err := u.writeFile(templateFile); err != nil {
+		return "", err
+	}
+	return templateFile, nil
+}
+
+// removeFields removes the fields not to keep with their widgets. The update
+// is nil if all fields are kept.
+func (d *pdfDocument) removeFields(keep map[string]bool) (*pdfUpdate, error) {
+	fields := d.formFields()
+	terminal := make(map[pdfRef]bool, len(fields))
+	dropped := make(map[pdfRef]bool)
+	for name, f := range fields {
+		terminal[f.ref] = true
+		if keep[name] {
+			continue
+		}
+		dropped[f.ref] = true
+		for _, w := range f.widgets {
+			dropped[w] = true
+		}
+	}
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+
+	u := d.update()
+
+	// Remove the fields from the field tree, including their emptied parents.
+	var prune func(kids pdfArray, depth int) pdfArray
+	prune = func(kids pdfArray, depth int) pdfArray {
+		var kept pdfArray
+		for _, kid := range kids {
+			ref, ok := kid.(pdfRef)
+			if ok && dropped[ref] {
+				continue
+			}
+			if ok && !terminal[ref] && depth < 64 {
+				field := d.dict(ref)
+				children := d.array(field["Kids"])
+				remaining := prune(children, depth+1)
+				if len(remaining) == 0 {
+					continue
+				}
+				if len(remaining) != len(children) {
+					field = copyDict(field)
+					field["Kids"] = remaining
+					u.set(ref, field)
+				}
+			}
+			kept = append(kept, kid)
+		}
+		return kept
+	}
+
+	catalog := d.catalog()
+	acro := copyDict(d.dict(catalog["AcroForm"]))
+	acro["Fields"] = prune(d.array(acro["Fields"]), 0)
+	if acroRef, ok := catalog["AcroForm"].(pdfRef); ok {
+		u.set(acroRef, acro)
+	} else if root, ok := d.trailer["Root"].(pdfRef); ok {
+		catalog = copyDict(catalog)
+		catalog["AcroForm"] = acro
+		u.set(root, catalog)
+	} else {
+		return nil, fmt.Errorf("invalid PDF: missing document catalog")
+	}
+
+	// Remove the widgets from the pages.
+	for _, pageRef := range d.pages() {
+		page := d.dict(pageRef)
+		annots := d.array(page["Annots"])
+		var kept pdfArray
+		for _, annot := range annots {
+			if ref, ok := annot.(pdfRef); !ok || !dropped[ref] {
+				kept = append(kept, annot)
+			}
+		}
+		if len(kept) == len(annots) {
+			continue
+		}
+		page = copyDict(page)
+		if len(kept) > 0 {
+			page["Annots"] = kept
+		} else {
+			delete(page, "Annots")
+		}
+		u.set(pageRef, page)
+	}
+	return u, nil
+}
@@ -0,0 +1,127 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// formFieldNames returns the sorted names of the terminal form fields.
+func formFieldNames(doc *pdfDocument) []string {
+	var names []string
+	for name := range doc.formFields() {
+		names = append(names, name)
+	}
+	return sorted(names)
+}
+
+func TestRemoveFields(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestForm(t, "form.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		keep       []string
+		wantFields []string
+		wantAnnots int
+	}{
+		{"nested", []string{"name", "address.city"}, []string{"address.city", "name"}, 2},
+		{"parent", []string{"agree", "color"}, []string{"agree", "color"}, 2},
+		{"none", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseNativePDF(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keep := make(map[string]bool)
+			for _, name := range tt.keep {
+				keep[name] = true
+			}
+			u, err := doc.removeFields(keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := u.bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc, err = parseNativePDF(out); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := formFieldNames(doc); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", got, tt.wantFields)
+			}
+			if got := len(doc.array(doc.catalog()["AcroForm"].(pdfDict)["Fields"])); got != len(tt.keep) {
+				t.Errorf("AcroForm has %d fields, want the emptied parents removed", got)
+			}
+			if got := len(doc.array(doc.dict(doc.pages()[0])["Annots"])); got != tt.wantAnnots {
+				t.Errorf("page has %d annotations, want %d", got, tt.wantAnnots)
+			}
+		})
+	}
+
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep := map[string]bool{"name": true, "agree": true, "address.city": true, "color": true}
+	if u, err := doc.removeFields(keep); u != nil || err != nil {
+		t.Errorf("removeFields(all) = %v, %v, want no update", u, err)
+	}
+}
+
+func TestDropUnusedFields(t *testing.T) {
+	input := writeTestForm(t, "form.pdf")
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+	template := filepath.Join(t.TempDir(), "template.pdf")
+
+	o := newOptions([]Option{WithDropUnusedFields(), WithKeepFields("color")})
+	_, file, err := o.prepareForm(Form{"name": "Bob"}, input, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file != template {
+		t.Fatalf("template = %s, want %s", file, template)
+	}
+
+	out, err := ioutil.ReadFile(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := formFieldNames(doc), []string{"color", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
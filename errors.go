@@ -0,0 +1,154 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrPDFTKNotFound is returned if the pdftk utility is not in the PATH.
+	ErrPDFTKNotFound = errors.New("pdftk utility not found")
+	// ErrQREncodeNotFound is returned if QR codes are generated without the
+	// qrencode utility in the PATH.
+	ErrQREncodeNotFound = errors.New("qrencode utility not found")
+	// ErrDestExists is returned if the destination file exists and may not
+	// be overwritten.
+	ErrDestExists = errors.New("destination file already exists")
+	// ErrPasswordRequired is matched by the PDFTKError returned if an input
+	// PDF is encrypted and no or a wrong password was given.
+	ErrPasswordRequired = errors.New("password required")
+	// ErrFileNotFound is returned if an input file, like the form template,
+	// does not exist.
+	ErrFileNotFound = errors.New("file does not exist")
+	// ErrXFAForm is returned by NativeBackend for XFA forms, whose XFA data
+	// it can't fill, and by the field validation for XFA forms without
+	// AcroForm fields.
+	ErrXFAForm = errors.New("XFA forms are not supported")
+
+	// ErrPdftkNotFound is ErrPDFTKNotFound.
+	ErrPdftkNotFound = ErrPDFTKNotFound
+	// ErrTemplateNotFound is ErrFileNotFound, returned for a missing form
+	// template like for all other input files.
+	ErrTemplateNotFound = ErrFileNotFound
+	// ErrEncryptedTemplate is ErrPasswordRequired, matched for an encrypted
+	// template opened without or with a wrong password.
+	ErrEncryptedTemplate = ErrPasswordRequired
+)
+
+// CommandError is returned if an external command like pdftk exits with an
+// error. Use errors.As to get it from the returned errors.
+type CommandError struct {
+	// Name is the name of the command, like "pdftk".
+	Name string
+	// Args are the arguments of the command with passwords replaced by
+	// "***".
+	Args []string
+	// ExitCode is the exit code of the command, -1 if it is unknown.
+	ExitCode int
+	// Stderr is the error output of the command without passwords.
+	Stderr string
+	// Err is the error of the executor.
+	Err error
+}
+
+// PDFTKError is the CommandError returned for pdftk.
+type PDFTKError = CommandError
+
+// Error describes the command, e.g. "pdftk fill_form", with its exit status
+// and error output.
+func (e *CommandError) Error() string {
+	msg := commandStage(e.Name, e.Args)
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" exited with status %d", e.ExitCode)
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether pdftk failed with the error target, so errors.Is
+// matches ErrPasswordRequired.
+func (e *CommandError) Is(target error) bool {
+	return target == ErrPasswordRequired && e.Name == "pdftk" && strings.Contains(e.Stderr, "PASSWORD REQUIRED")
+}
+
+// FormError is returned if the form doesn't match the fields of the
+// template. Use errors.As to get it from the returned errors.
+type FormError struct {
+	// UnknownFields are the form keys without matching field.
+	UnknownFields []string
+	// MissingFields are the required fields without value in the form or
+	// the template.
+	MissingFields []string
+	// InvalidValues are the values of combo and list boxes, which are none
+	// of their options.
+	InvalidValues []InvalidValue
+}
+
+// InvalidValue is a value of a choice field, which is none of its options.
+type InvalidValue struct {
+	Field   string
+	Value   string
+	Options []string
+}
+
+func (e *FormError) Error() string {
+	var problems []string
+	for _, name := range e.UnknownFields {
+		problems = append(problems, fmt.Sprintf("'%s' matches no field", name))
+	}
+	for _, name := range e.MissingFields {
+		problems = append(problems, fmt.Sprintf("'%s' is required", name))
+	}
+	for _, v := range e.InvalidValues {
+		problems = append(problems, fmt.Sprintf("'%s' is no option of '%s': '%s'", v.Value, v.Field, strings.Join(v.Options, "', '")))
+	}
+	return "invalid form: " + strings.Join(problems, "; ")
+}
+
+// exitCode returns the exit code of the process error, -1 if it is unknown.
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// notFoundError wraps the error of a missing utility.
+func notFoundError(name string, err error) error {
+	switch name {
+	case "pdftk":
+		return fmt.Errorf("%w: %v", ErrPDFTKNotFound, err)
+	case "qrencode":
+		return fmt.Errorf("%w: %v", ErrQREncodeNotFound, err)
+	}
+	return err
+}
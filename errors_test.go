@@ -0,0 +1,99 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exitError is a process error with an exit code.
+type exitError int
+
+func (e exitError) Error() string { return "exit status" }
+func (e exitError) ExitCode() int { return int(e) }
+
+func TestCommandErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *CommandError
+		want string
+	}{
+		{
+			"exit status and stderr",
+			&CommandError{Name: "pdftk", Args: []string{"in.pdf", "fill_form", "data.fdf", "output", "-"}, ExitCode: 1, Stderr: "Error: Unable to find file.", Err: exitError(1)},
+			"pdftk fill_form exited with status 1: Error: Unable to find file.",
+		},
+		{
+			"no stderr",
+			&CommandError{Name: "pdftoppm", Args: []string{"-png", "in.pdf"}, ExitCode: 99, Err: exitError(99)},
+			"pdftoppm exited with status 99",
+		},
+		{
+			"unknown exit code",
+			&CommandError{Name: "pdftk", Args: []string{"in.pdf", "cat", "output", "-"}, ExitCode: -1, Err: errors.New("signal: killed")},
+			"pdftk cat: signal: killed",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%s: Error() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrorAliases(t *testing.T) {
+	encrypted := &CommandError{Name: "pdftk", ExitCode: 1, Stderr: "Error: Failed to open PDF file: \n   in.pdf\n   OWNER PASSWORD REQUIRED, but not given (or incorrect)", Err: exitError(1)}
+	if !errors.Is(encrypted, ErrEncryptedTemplate) || !errors.Is(encrypted, ErrPasswordRequired) {
+		t.Errorf("errors.Is(%v, ErrEncryptedTemplate) = false", encrypted)
+	}
+	if other := (&CommandError{Name: "pdftk", ExitCode: 1, Stderr: "Error", Err: exitError(1)}); errors.Is(other, ErrEncryptedTemplate) {
+		t.Errorf("errors.Is(%v, ErrEncryptedTemplate) = true", other)
+	}
+
+	useExecutor(t, &recordExecutor{})
+	missing := filepath.Join(t.TempDir(), "missing.pdf")
+	err := Fill(Form{"name": "Ann"}, missing, filepath.Join(t.TempDir(), "out.pdf"), "Yes", "Off", true, WithBackend(PDFTKBackend{}))
+	if !errors.Is(err, ErrTemplateNotFound) || !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Fill with a missing template = %v, want ErrTemplateNotFound", err)
+	}
+	if !errors.Is(ErrPDFTKNotFound, ErrPdftkNotFound) {
+		t.Error("ErrPdftkNotFound does not match ErrPDFTKNotFound")
+	}
+}
+
+func TestFillCommandError(t *testing.T) {
+	useExecutor(t, failExecutor{exitError(3)})
+	template := writeTestForm(t, "form.pdf")
+
+	_, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", WithBackend(PDFTKBackend{}))
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("FillPDFToBytes() error = %v, want a *CommandError", err)
+	}
+	if cmdErr.Name != "pdftk" || cmdErr.ExitCode != 3 || !containsString(cmdErr.Args, "fill_form") {
+		t.Errorf("CommandError = %+v, want pdftk fill_form with exit code 3", cmdErr)
+	}
+	if !strings.Contains(err.Error(), "pdftk fill_form exited with status 3") {
+		t.Errorf("error = %q, want the command and exit status", err)
+	}
+}
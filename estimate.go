@@ -0,0 +1,102 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Operation identifies the operation EstimateCost estimates.
+type Operation string
+
+const (
+	OperationFill  Operation = "fill"
+	OperationMerge Operation = "merge"
+	OperationStamp Operation = "stamp"
+)
+
+// Cost is a rough estimate of the resources an operation needs.
+type Cost struct {
+	Duration time.Duration
+	// Memory is the expected peak memory in bytes, of pdftk and this
+	// package together.
+	Memory int64
+	Pages  int
+	Bytes  int64
+}
+
+// costFactors are the heuristic constants of an operation, measured on a
+// typical pdftk installation. Memory factors are multiples of the input size.
+type costFactors struct {
+	startup    time.Duration
+	perMB      time.Duration
+	perPage    time.Duration
+	baseMemory int64
+	memFactor  int64
+}
+
+// operationCosts are calibrated with the benchmarks of estimate_test.go,
+// which run each operation with pdftk on documents of 1, 10 and 100 pages:
+//
+//	go test -run '^$' -bench 'Fill|Merge|Stamp' -benchtime 20x
+//
+// startup is the time per run of the 1 page documents, perPage the increase
+// per page of the larger ones and perMB follows from the reported MB/s. The
+// memory factors are measured separately from the peak RSS of pdftk, e.g.
+// with /usr/bin/time -v. Rerun them on the target system to recalibrate.
+var operationCosts = map[Operation]costFactors{
+	OperationFill:  {startup: 150 * time.Millisecond, perMB: 60 * time.Millisecond, perPage: 5 * time.Millisecond, baseMemory: 64 << 20, memFactor: 4},
+	OperationMerge: {startup: 150 * time.Millisecond, perMB: 40 * time.Millisecond, perPage: 2 * time.Millisecond, baseMemory: 64 << 20, memFactor: 3},
+	OperationStamp: {startup: 150 * time.Millisecond, perMB: 50 * time.Millisecond, perPage: 4 * time.Millisecond, baseMemory: 64 << 20, memFactor: 4},
+}
+
+// EstimateCost estimates the time and peak memory of running the operation
+// on the input files, based on their sizes and page counts. The result is a
+// heuristic to prioritize jobs and to avoid scheduling operations exceeding
+// the available memory, not a precise prediction.
+func EstimateCost(op Operation, files ...string) (Cost, error) {
+	f, ok := operationCosts[op]
+	if !ok {
+		return Cost{}, fmt.Errorf("invalid operation: '%s'", op)
+	}
+
+	var c Cost
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return Cost{}, err
+		}
+		pages, err := NumPages(file)
+		if err != nil {
+			return Cost{}, err
+		}
+		c.Bytes += info.Size()
+		c.Pages += pages
+	}
+
+	c.Duration = f.startup +
+		time.Duration(float64(f.perMB)*float64(c.Bytes)/(1<<20)) +
+		time.Duration(c.Pages)*f.perPage
+	c.Memory = f.baseMemory + f.memFactor*c.Bytes
+
+	return c, nil
+}
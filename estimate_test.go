@@ -0,0 +1,99 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchmarkPages are the page counts of the benchmarks calibrating
+// operationCosts.
+var benchmarkPages = []int{1, 10, 100}
+
+// benchmarkOperation runs the operation for documents of all benchmarkPages
+// and reports the bytes and pages processed per run.
+func benchmarkOperation(b *testing.B, run func(b *testing.B, pdfFile string) error) {
+	requirePDFTK(b)
+	for _, n := range benchmarkPages {
+		b.Run(fmt.Sprintf("pages=%d", n), func(b *testing.B) {
+			pdfFile := writeBenchmarkForm(b, n)
+			info, err := os.Stat(pdfFile)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(info.Size())
+			b.ReportMetric(float64(n), "pages/op")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := run(b, pdfFile); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// writeBenchmarkForm writes the test form followed by n-1 text pages.
+func writeBenchmarkForm(b *testing.B, n int) string {
+	files := []string{writeTestForm(b, "form.pdf")}
+	if n > 1 {
+		files = append(files, writeTestPDF(b, "pages.pdf", n-1))
+	}
+	output := filepath.Join(b.TempDir(), "benchmark.pdf")
+	if err := (NativeBackend{}).Merge(context.Background(), files, output); err != nil {
+		b.Fatal(err)
+	}
+	return output
+}
+
+func BenchmarkFill(b *testing.B) {
+	form := Form{"name": "Benchmark", "agree": true, "address.city": "Berlin"}
+	benchmarkOperation(b, func(b *testing.B, pdfFile string) error {
+		_, err := FillPDFToBytes(form, pdfFile, "", "Yes", "Off", WithBackend(PDFTKBackend{}))
+		return err
+	})
+}
+
+func BenchmarkMerge(b *testing.B) {
+	benchmarkOperation(b, func(b *testing.B, pdfFile string) error {
+		r, err := MergeWithOptions([]string{pdfFile}, WithBackend(PDFTKBackend{}))
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		return err
+	})
+}
+
+func BenchmarkStamp(b *testing.B) {
+	stamp := writeStampPDF(b, "stamp.pdf", 1)
+	benchmarkOperation(b, func(b *testing.B, pdfFile string) error {
+		r, err := Stamp(pdfFile, stamp, WithBackend(PDFTKBackend{}))
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		return err
+	})
+}
@@ -0,0 +1,218 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Executor runs the external commands of the package, like pdftk, pdftoppm
+// and zstd. dir is the working directory, which may be empty. stdin may be
+// nil. A replacement can return canned output in tests or run the commands
+// in a sandbox.
+type Executor interface {
+	Run(dir, name string, args []string, stdin io.Reader) (stdout, stderr []byte, err error)
+}
+
+// ContextExecutor is implemented by executors able to stop a command once the
+// context is done. Commands of other executors run to their end, even if
+// the context of the calling function is canceled.
+type ContextExecutor interface {
+	Executor
+	RunContext(ctx context.Context, dir, name string, args []string, stdin io.Reader) (stdout, stderr []byte, err error)
+}
+
+// ExecExecutor runs the commands with os/exec. It is the default Executor.
+type ExecExecutor struct{}
+
+// Run implements Executor.
+func (e ExecExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	return e.RunContext(context.Background(), dir, name, args, stdin)
+}
+
+// RunContext implements ContextExecutor. The process is killed once the
+// context is done.
+func (ExecExecutor) RunContext(ctx context.Context, dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := ExecExecutor{}.run(ctx, dir, name, args, stdin, &stdout, &stderr)
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func (ExecExecutor) run(ctx context.Context, dir, name string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, commandPath(name), args...)
+	if env := getCommandEnv(); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+var (
+	executorMutex sync.Mutex
+	executor      Executor = ExecExecutor{}
+)
+
+// SetExecutor replaces the Executor of the package. nil restores the default.
+// With a replaced Executor, the commands are not looked up in the PATH
+// before they are run.
+func SetExecutor(e Executor) {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+
+	if e == nil {
+		e = ExecExecutor{}
+	}
+	executor = e
+}
+
+func getExecutor() Executor {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	return executor
+}
+
+var (
+	commandMutex sync.Mutex
+	pdftkPath    string
+	commandEnv   []string
+)
+
+// SetPDFTKPath sets the pdftk executable run by ExecExecutor, e.g.
+// "/opt/pdftk-java/pdftk" or a vendored binary. The empty path restores the
+// default, which is "pdftk" looked up in the PATH. The cached capabilities
+// and version of the previous executable are dropped.
+func SetPDFTKPath(path string) {
+	commandMutex.Lock()
+	pdftkPath = path
+	commandMutex.Unlock()
+
+	capabilitiesMutex.Lock()
+	cachedCapabilities = nil
+	capabilitiesMutex.Unlock()
+
+	versionMutex.Lock()
+	cachedVersion = ""
+	versionMutex.Unlock()
+}
+
+// SetCommandEnv sets additional environment variables like "JAVA_HOME=/opt/jdk"
+// of the commands run by ExecExecutor. They are added to the environment of
+// the process. nil removes them, which is the default.
+func SetCommandEnv(env []string) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+	commandEnv = append([]string(nil), env...)
+}
+
+// commandPath returns the executable run for the command name.
+func commandPath(name string) string {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	if name == "pdftk" && pdftkPath != "" {
+		return pdftkPath
+	}
+	return name
+}
+
+func getCommandEnv() []string {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+	return commandEnv
+}
+
+// CommandHook is called after each command the package ran, e.g. to log the
+// pdftk command lines or to record metrics. Passwords in args are replaced
+// by "***". err is the error returned for the command, if any.
+type CommandHook func(name string, args []string, duration time.Duration, err error)
+
+var (
+	commandHookMutex sync.Mutex
+	commandHook      CommandHook
+)
+
+// SetCommandHook sets the hook called after each command. nil removes it,
+// which is the default.
+func SetCommandHook(h CommandHook) {
+	commandHookMutex.Lock()
+	defer commandHookMutex.Unlock()
+	commandHook = h
+}
+
+// traceCommand calls the CommandHook, if set.
+func traceCommand(name string, args []string, start time.Time, err error) {
+	commandHookMutex.Lock()
+	h := commandHook
+	commandHookMutex.Unlock()
+
+	if h != nil {
+		h(name, redactArgs(args), time.Since(start), err)
+	}
+}
+
+// lookPath checks if the command exists, if it is run by the default Executor.
+// A missing pdftk is reported as ErrPDFTKNotFound.
+func lookPath(name string) error {
+	if _, ok := getExecutor().(ExecExecutor); !ok {
+		return nil
+	}
+	if _, err := exec.LookPath(commandPath(name)); err != nil {
+		return notFoundError(name, err)
+	}
+	return nil
+}
+
+// execute runs the command with the package Executor. The output of the
+// default Executor is streamed to stdout, the one of others copied once the
+// command exited.
+func execute(ctx context.Context, dir string, stdin io.Reader, stdout, stderr io.Writer, name string, args ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var (
+		out, errOut []byte
+		err         error
+	)
+	switch e := getExecutor().(type) {
+	case ExecExecutor:
+		return e.run(ctx, dir, name, args, stdin, stdout, stderr)
+	case ContextExecutor:
+		out, errOut, err = e.RunContext(ctx, dir, name, args, stdin)
+	default:
+		out, errOut, err = e.Run(dir, name, args, stdin)
+	}
+
+	stderr.Write(errOut)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
@@ -0,0 +1,364 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldType is the type of a form field.
+type FieldType string
+
+const (
+	FieldTypeText       FieldType = "text"
+	FieldTypeCheckbox   FieldType = "checkbox"
+	FieldTypeRadio      FieldType = "radio"
+	FieldTypePushButton FieldType = "pushbutton"
+	FieldTypeComboBox   FieldType = "combobox"
+	FieldTypeListBox    FieldType = "listbox"
+	FieldTypeSignature  FieldType = "signature"
+)
+
+// Field describes a form field of a PDF.
+type Field struct {
+	// Name is the fully qualified field name, used as Form key.
+	Name string
+	// AltName is the alternate name shown as tooltip, if any.
+	AltName string
+	Type    FieldType
+	// Flags are the raw /Ff field flags.
+	Flags        int
+	Value        string
+	DefaultValue string
+	// Options are the states of checkboxes and radio buttons and the
+	// choices of combo and list boxes.
+	Options   []string
+	MaxLength int
+}
+
+// GetFields returns the form fields of the PDF in document order. The
+// options WithBackend and WithInputPassword apply.
+func GetFields(pdfFile string, opts ...Option) ([]Field, error) {
+	return getFields(pdfFile, newOptions(opts))
+}
+
+// GetFieldValues returns the current values of the form fields of the PDF,
+// e.g. of a filled PDF, keyed by the field names. Checkboxes are returned as
+// Checkbox with the state name of checked boxes as OnValue, so the result can
+// be passed to Fill again. Other fields, including radio buttons, have the
+// value as string. Push buttons and signatures are skipped. The options apply
+// like for GetFields.
+func GetFieldValues(pdfFile string, opts ...Option) (Form, error) {
+	fields, err := getFields(pdfFile, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	form := make(Form, len(fields))
+	for _, f := range fields {
+		switch f.Type {
+		case FieldTypePushButton, FieldTypeSignature:
+		case FieldTypeCheckbox:
+			checked := f.Value != "" && f.Value != "Off"
+			c := Checkbox{Checked: checked}
+			if checked {
+				c.OnValue = f.Value
+			}
+			form[f.Name] = c
+		default:
+			form[f.Name] = f.Value
+		}
+	}
+	return form, nil
+}
+
+// getFields lists the form fields with the Backend of the call, if it is a
+// FieldsBackend and no input password is given, or else with pdftk.
+func getFields(pdfFile string, o *options) ([]Field, error) {
+	if b, ok := o.getBackend().(FieldsBackend); ok && o.password == "" {
+		absPath, err := getAbs(pdfFile)
+		if err != nil {
+			return nil, err
+		}
+		return b.Fields(context.Background(), absPath)
+	}
+	return pdftkFields(context.Background(), pdfFile, o.password)
+}
+
+// pdftkFields lists the form fields with pdftk.
+func pdftkFields(ctx context.Context, pdfFile, password string) ([]Field, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	operation := utf8Operation("dump_data_fields")
+	args := append(pdftkInput(pdfFile, password), operation)
+	out, err := runCommandWithOutputContext(ctx, "", "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fields := parseFields(out)
+	if operation == "dump_data_fields" {
+		unescapeFields(fields)
+	}
+	return fields, nil
+}
+
+// unescapeFields decodes the XML character references of the plain
+// dump_data_fields output.
+func unescapeFields(fields []Field) {
+	for i := range fields {
+		f := &fields[i]
+		f.Name = html.UnescapeString(f.Name)
+		f.AltName = html.UnescapeString(f.AltName)
+		f.Value = html.UnescapeString(f.Value)
+		f.DefaultValue = html.UnescapeString(f.DefaultValue)
+		for j := range f.Options {
+			f.Options[j] = html.UnescapeString(f.Options[j])
+		}
+	}
+}
+
+// parseFields parses the output of pdftk dump_data_fields_utf8 or
+// dump_data_fields. Each field is
+// a block of "Key: Value" lines, separated by "---".
+func parseFields(out []byte) []Field {
+	var (
+		fields   []Field
+		field    *Field
+		rawType  string
+		finalize = func() {
+			if field == nil {
+				return
+			}
+			field.Type = fieldType(rawType, field.Flags)
+			fields = append(fields, *field)
+			field, rawType = nil, ""
+		}
+	)
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if s.Text() == "---" {
+			finalize()
+			continue
+		}
+		i := strings.Index(s.Text(), ": ")
+		if i < 0 {
+			continue
+		}
+		name, value := s.Text()[:i], s.Text()[i+2:]
+		if field == nil {
+			field = &Field{}
+		}
+
+		switch name {
+		case "FieldType":
+			rawType = value
+		case "FieldName":
+			field.Name = value
+		case "FieldNameAlt":
+			field.AltName = value
+		case "FieldFlags":
+			field.Flags, _ = strconv.Atoi(value)
+		case "FieldValue":
+			field.Value = value
+		case "FieldValueDefault":
+			field.DefaultValue = value
+		case "FieldStateOption":
+			field.Options = append(field.Options, value)
+		case "FieldMaxLength":
+			field.MaxLength, _ = strconv.Atoi(value)
+		}
+	}
+	finalize()
+
+	return fields
+}
+
+// fieldType maps the pdftk field type and flags to a FieldType.
+func fieldType(rawType string, flags int) FieldType {
+	switch rawType {
+	case "Button":
+		if flags&fieldFlagPushButton != 0 {
+			return FieldTypePushButton
+		} else if flags&fieldFlagRadio != 0 {
+			return FieldTypeRadio
+		}
+		return FieldTypeCheckbox
+	case "Choice":
+		if flags&fieldFlagCombo != 0 {
+			return FieldTypeComboBox
+		}
+		return FieldTypeListBox
+	case "Signature":
+		return FieldTypeSignature
+	}
+	return FieldTypeText
+}
+
+// ValidateForm checks the form against the fields of the template like
+// WithFieldValidation, without filling it. It returns a *FormError listing
+// the form keys without matching field, the required fields without value and
+// the values of combo and list boxes, which are none of their options. XFA
+// forms without AcroForm fields return ErrXFAForm.
+func ValidateForm(templatePDFFile string, form Form) error {
+	fields, err := GetFields(templatePDFFile)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		if xfa, err := HasXFA(templatePDFFile); err == nil && xfa {
+			return ErrXFAForm
+		}
+	}
+	return validateForm(form, fields)
+}
+
+// validateForm checks all form keys match a field, required fields have a
+// value and the values of choice fields are one of their options. Editable
+// combo boxes accept any value.
+func validateForm(form Form, fields []Field) error {
+	var formErr FormError
+
+	byName := make(map[string]Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+		if _, ok := form[f.Name]; !ok && f.Flags&fieldFlagRequired != 0 && f.Value == "" {
+			formErr.MissingFields = append(formErr.MissingFields, f.Name)
+		}
+	}
+
+	// Sort the keys for a stable error message.
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f, ok := byName[key]
+		if !ok {
+			formErr.UnknownFields = append(formErr.UnknownFields, key)
+			continue
+		}
+
+		if (f.Type != FieldTypeComboBox && f.Type != FieldTypeListBox) || f.Flags&fieldFlagEdit != 0 {
+			continue
+		}
+		if value := fmt.Sprintf("%v", form[key]); !containsString(f.Options, value) {
+			formErr.InvalidValues = append(formErr.InvalidValues, InvalidValue{Field: key, Value: value, Options: f.Options})
+		}
+	}
+
+	if len(formErr.UnknownFields) > 0 || len(formErr.MissingFields) > 0 || len(formErr.InvalidValues) > 0 {
+		return &formErr
+	}
+	return nil
+}
+
+// dumpFieldNames returns the names of all fields of the PDF form.
+func dumpFieldNames(pdfFile string, o *options) ([]string, error) {
+	fields, err := getFields(pdfFile, o)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// normalizeFieldName returns the lookup key for case and surrounding
+// whitespace insensitive field name matching.
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// resolveFieldNames maps the form keys to the field names of the template,
+// ignoring case and surrounding whitespace. Keys matching no field are kept
+// as they are. A key matching several fields or several keys matching the
+// same field are reported as ambiguous.
+func resolveFieldNames(form Form, names []string) (Form, error) {
+	fields := make(map[string][]string)
+	for _, name := range names {
+		key := normalizeFieldName(name)
+		fields[key] = append(fields[key], name)
+	}
+
+	var problems []string
+	resolved := make(Form, len(form))
+	sources := make(map[string]string, len(form))
+
+	// Sort the keys for a stable error message.
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := key
+		if matches := fields[normalizeFieldName(key)]; len(matches) == 1 {
+			name = matches[0]
+		} else if len(matches) > 1 && !containsString(matches, key) {
+			problems = append(problems, fmt.Sprintf("'%s' matches several fields: '%s'", key, strings.Join(matches, "', '")))
+			continue
+		}
+
+		if other, ok := sources[name]; ok {
+			problems = append(problems, fmt.Sprintf("'%s' and '%s' both match the field '%s'", other, key, name))
+			continue
+		}
+		sources[name] = key
+		resolved[name] = form[key]
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("ambiguous form field names: %s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
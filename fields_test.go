@@ -0,0 +1,200 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	out := []byte(`---
+FieldType: Text
+FieldName: address.city
+FieldNameAlt: City
+FieldFlags: 0
+FieldValue: Köln
+FieldJustification: Left
+FieldMaxLength: 40
+---
+FieldType: Button
+FieldName: agree
+FieldFlags: 0
+FieldValue: Yes
+FieldStateOption: Off
+FieldStateOption: Yes
+---
+FieldType: Button
+FieldName: size
+FieldFlags: 49152
+FieldValue: M
+FieldStateOption: L
+FieldStateOption: M
+---
+FieldType: Choice
+FieldName: color
+FieldFlags: 131072
+FieldValue: red
+FieldValueDefault: blue
+FieldStateOption: blue
+FieldStateOption: red
+---
+FieldType: Button
+FieldName: reset
+FieldFlags: 65536
+---
+FieldType: Signature
+FieldName: sig
+FieldFlags: 0
+`)
+
+	want := []Field{
+		{Name: "address.city", AltName: "City", Type: FieldTypeText, Value: "Köln", MaxLength: 40},
+		{Name: "agree", Type: FieldTypeCheckbox, Value: "Yes", Options: []string{"Off", "Yes"}},
+		{Name: "size", Type: FieldTypeRadio, Flags: 49152, Value: "M", Options: []string{"L", "M"}},
+		{Name: "color", Type: FieldTypeComboBox, Flags: 131072, Value: "red", DefaultValue: "blue", Options: []string{"blue", "red"}},
+		{Name: "reset", Type: FieldTypePushButton, Flags: 65536},
+		{Name: "sig", Type: FieldTypeSignature},
+	}
+	if fields := parseFields(out); !reflect.DeepEqual(fields, want) {
+		t.Errorf("parseFields =\n%+v\nwant\n%+v", fields, want)
+	}
+}
+
+func TestUnescapeFields(t *testing.T) {
+	fields := []Field{{Name: "K&#246;ln", Value: "a &lt; b", Options: []string{"&#228;"}}}
+	unescapeFields(fields)
+	want := []Field{{Name: "Köln", Value: "a < b", Options: []string{"ä"}}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("unescapeFields = %+v, want %+v", fields, want)
+	}
+}
+
+func TestGetFieldValuesRoundTrip(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	want := Form{
+		"name":         "Ann",
+		"agree":        Checkbox{Checked: true, OnValue: "Yes"},
+		"address.city": "Berlin",
+		"color":        "g",
+	}
+
+	form, err := GetFieldValues(template, WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("GetFieldValues = %v, want %v", form, want)
+	}
+
+	// Fill the values read back into the template again.
+	filled := filepath.Join(t.TempDir(), "filled.pdf")
+	form["name"] = "Bob"
+	form["agree"] = false
+	if err := Fill(form, template, filled, "Yes", "Off", false, WithBackend(NativeBackend{}), WithFlatten(false)); err != nil {
+		t.Fatal(err)
+	}
+	want["name"] = "Bob"
+	want["agree"] = Checkbox{}
+
+	form, err = GetFieldValues(filled, WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("GetFieldValues after fill = %v, want %v", form, want)
+	}
+}
+
+func TestResolveFieldNames(t *testing.T) {
+	names := []string{"Name", "address.city", "Color", "color", "agree"}
+
+	tests := []struct {
+		name    string
+		form    Form
+		want    Form
+		wantErr string
+	}{
+		{
+			name: "case and whitespace",
+			form: Form{" NAME ": "Ann", "Address.City\t": "Berlin", "AGREE": true},
+			want: Form{"Name": "Ann", "address.city": "Berlin", "agree": true},
+		},
+		{
+			name: "unknown keys are kept",
+			form: Form{"zip": "10115", "name": "Ann"},
+			want: Form{"zip": "10115", "Name": "Ann"},
+		},
+		{
+			name: "exact match of several fields",
+			form: Form{"color": "red", "Color": "green"},
+			want: Form{"color": "red", "Color": "green"},
+		},
+		{
+			name:    "several fields",
+			form:    Form{"COLOR": "red"},
+			wantErr: "'COLOR' matches several fields: 'Color', 'color'",
+		},
+		{
+			name:    "several keys",
+			form:    Form{"name": "Ann", "NAME": "Bob"},
+			wantErr: "'NAME' and 'name' both match the field 'Name'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, err := resolveFieldNames(tt.form, names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolveFieldNames() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(form, tt.want) {
+				t.Errorf("resolveFieldNames() = %v, want %v", form, tt.want)
+			}
+		})
+	}
+}
+
+func TestFillLooseFieldNames(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	e := &recordExecutor{stdout: []byte(pdftkHelp + "---\nFieldType: Text\nFieldName: name\n---\nFieldType: Choice\nFieldName: color\n")}
+	useExecutor(t, e)
+
+	var data bytes.Buffer
+	opts := []Option{WithBackend(PDFTKBackend{}), WithLooseFieldNames(), WithDumpFDF(&data)}
+	if _, err := FillPDFToBytes(Form{" Name ": "Ann", "COLOR": "red"}, template, t.TempDir(), "Yes", "Off", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if values, want := dataFileValues(t, data.Bytes()), map[string]string{"name": "Ann", "color": "red"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("data file values = %v, want %v", values, want)
+	}
+
+	if _, err := FillPDFToBytes(Form{"name": "Ann", "NAME": "Bob"}, template, t.TempDir(), "Yes", "Off", opts...); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("fill with ambiguous keys error = %v, want ambiguous field names", err)
+	}
+}
@@ -0,0 +1,76 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"sync"
+)
+
+// Filler fills the same form template repeatedly. The template and the pdftk
+// utility are checked once by NewFiller. A Filler may be used concurrently.
+type Filler struct {
+	template        string
+	checkedString   string
+	uncheckedString string
+	opts            []Option
+
+	fieldsOnce sync.Once
+	fields     []Field
+	fieldsErr  error
+}
+
+// NewFiller returns a Filler for the form template. The checkbox strings
+// are used for bool values like in Fill, the options apply to every fill.
+func NewFiller(templatePath, checkedString, uncheckedString string, opts ...Option) (*Filler, error) {
+	var err error
+
+	// Check if the pdftk utility exists, if it is used.
+	if err := newOptions(opts).lookPathBackend(); err != nil {
+		return nil, err
+	}
+
+	// Get the absolute path.
+	if templatePath, err = getAbs(templatePath); err != nil {
+		return nil, err
+	}
+
+	return &Filler{
+		template:        templatePath,
+		checkedString:   checkedString,
+		uncheckedString: uncheckedString,
+		opts:            append([]Option(nil), opts...),
+	}, nil
+}
+
+// Fill fills the template with the form and returns the PDF. Additional
+// options apply to this fill only.
+func (f *Filler) Fill(form Form, opts ...Option) ([]byte, error) {
+	o := newOptions(append(f.opts[:len(f.opts):len(f.opts)], opts...))
+	return fillPDFToBytes(form, f.template, o.tempDir, f.checkedString, f.uncheckedString, o)
+}
+
+// Fields returns the form fields of the template like GetFields. They are
+// read once and cached.
+func (f *Filler) Fields() ([]Field, error) {
+	f.fieldsOnce.Do(func() {
+		f.fields, f.fieldsErr = GetFields(f.template, f.opts...)
+	})
+	return f.fields, f.fieldsErr
+}
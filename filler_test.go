@@ -0,0 +1,108 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestNewFillerMissingTemplate(t *testing.T) {
+	_, err := NewFiller(filepath.Join(t.TempDir(), "missing.pdf"), "Yes", "Off", WithBackend(NativeBackend{}))
+	if err == nil {
+		t.Error("NewFiller with a missing template succeeded")
+	}
+}
+
+func TestFiller(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	f, err := NewFiller(template, "Yes", "Off", WithBackend(NativeBackend{}), WithFlatten(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fill := func(name string, opts ...Option) map[string]string {
+		t.Helper()
+		data, err := f.Fill(Form{"name": name}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := parseNativePDF(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc.fieldValues()
+	}
+
+	if values := fill("Ann"); values["name"] != "Ann" {
+		t.Errorf("name = %q, want Ann", values["name"])
+	}
+	if values := fill("Bob", WithFlatten(true)); len(values) != 0 {
+		t.Errorf("flattened fill has the fields %v", values)
+	}
+	if values := fill("Cid"); values["name"] != "Cid" {
+		t.Errorf("name after a fill with extra options = %q, want the fields kept", values["name"])
+	}
+
+	// Concurrent fills must not share the appended options.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = f.Fill(Form{"name": fmt.Sprint(i)}, WithFlatten(i%2 == 0))
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("concurrent fill %d: %v", i, err)
+		}
+	}
+}
+
+func TestFillerFieldsCached(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	f, err := NewFiller(template, "Yes", "Off", WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := f.Fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 4 {
+		t.Fatalf("Fields() returned %d fields, want 4", len(fields))
+	}
+
+	// The second call must not read the template again.
+	if err := os.Remove(template); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := f.Fields()
+	if err != nil || len(cached) != len(fields) {
+		t.Errorf("cached Fields() = %d fields, %v, want %d", len(cached), err, len(fields))
+	}
+}
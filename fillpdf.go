@@ -20,12 +20,18 @@ package fillpdf
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 	"unicode/utf16"
 )
 
@@ -34,20 +40,74 @@ type FormJson struct {
 }
 
 // Form represents the PDF form.
-// This is a key value map.
+// This is a key value map. The keys are the fully qualified field names like
+// "applicant.name.first". Values may also be nested forms (Form or
+// map[string]interface{}, e.g. from JSON) keyed by the partial field names.
 type Form map[string]interface{}
 
+// Checkbox is a form value for checkboxes and radio buttons with their own
+// export values. Empty values fall back to the checked and unchecked strings
+// of the fill, which plain bool values always use.
+type Checkbox struct {
+	Checked  bool
+	OnValue  string
+	OffValue string
+}
+
+// value returns the export value of the checkbox state.
+func (c Checkbox) value(checkedString, uncheckedString string) string {
+	if c.Checked {
+		if c.OnValue != "" {
+			return c.OnValue
+		}
+		return checkedString
+	}
+	if c.OffValue != "" {
+		return c.OffValue
+	}
+	return uncheckedString
+}
+
 // Fill a PDF form with the specified form values and create a final filled PDF file.
 // One variadic boolean specifies, whenever to overwrite the destination file if it exists.
 // Checkboxes specify one string for checked (checkedString) and one string for
 // unchecked (uncheckedString). The specification can be done on each individual
 // checkbox, but lets assume that all checkboxes in the same document will
 // use the same strings.
-func Fill(form Form, formPDFFile, destPDFFile, checkedString, uncheckedString string, overwrite bool) error {
+func Fill(form Form, formPDFFile, destPDFFile, checkedString, uncheckedString string, overwrite bool, opts ...Option) error {
+	return FillContext(context.Background(), form, formPDFFile, destPDFFile, checkedString, uncheckedString, overwrite, opts...)
+}
+
+// FillContext is like Fill, but kills pdftk once the context is done. The
+// temporary files are removed in any case.
+func FillContext(ctx context.Context, form Form, formPDFFile, destPDFFile, checkedString, uncheckedString string, overwrite bool, opts ...Option) error {
+	o := newOptions(opts)
+	if b := o.getBackend(); b != nil {
+		return fillToDest(ctx, b, form, formPDFFile, destPDFFile, checkedString, uncheckedString, overwrite, o)
+	}
+	return fillContext(ctx, form, formPDFFile, destPDFFile, checkedString, uncheckedString, overwrite, o)
+}
+
+// FillFile is like Fill, but takes all settings as options, so new ones don't
+// change the signature. Checkboxes are "Yes" and "Off" unless set with
+// WithCheckedString and WithUncheckedString, and an existing destination
+// file is only replaced with WithOverwrite.
+func FillFile(form Form, formPDFFile, destPDFFile string, opts ...Option) error {
+	return FillFileContext(context.Background(), form, formPDFFile, destPDFFile, opts...)
+}
+
+// FillFileContext is like FillFile, but kills pdftk once the context is done.
+func FillFileContext(ctx context.Context, form Form, formPDFFile, destPDFFile string, opts ...Option) error {
+	o := newOptions(opts)
+	return FillContext(ctx, form, formPDFFile, destPDFFile, o.checkedString, o.uncheckedString, o.overwrite, opts...)
+}
+
+// fillContext fills the form with pdftk.
+func fillContext(ctx context.Context, form Form, formPDFFile, destPDFFile, checkedString, uncheckedString string, overwrite bool, o *options) error {
 	var err error
 
 	// Check if the pdftk utility exists.
-	if _, err := exec.LookPath("pdftk"); err != nil {
+	if err := lookPath("pdftk"); err != nil {
 		return err
 	}
 
@@ -61,7 +121,7 @@ func Fill(form Form, formPDFFile, destPDFFile, checkedString, uncheckedString st
 	}
 
 	// Create a temporary directory.
-	tmpDir, err := ioutil.TempDir("", "fillpdf-")
+	tmpDir, err := createTempDir(o.tempDir)
 	if err != nil {
 		return err
 	}
@@ -74,82 +134,510 @@ func Fill(form Form, formPDFFile, destPDFFile, checkedString, uncheckedString st
 	// Create the temporary output file path.
 	outputFile := filepath.Clean(tmpDir + "/output.pdf")
 
+	templateFile := filepath.Clean(tmpDir + "/template.pdf")
+	if form, formPDFFile, err = o.prepareForm(form, formPDFFile, templateFile); err != nil {
+		return err
+	}
+
 	// Create the fdf data file.
 	fdfFile := filepath.Clean(tmpDir + "/data.fdf")
-	if err := createFdfFile(form, fdfFile, checkedString, uncheckedString); err != nil {
+	if err := o.createDataFile(form, fdfFile, checkedString, uncheckedString); err != nil {
 		return err
 	}
 
 	// Create the pdftk command line arguments.
-	args := []string{
-		formPDFFile,
+	args := append(pdftkInput(formPDFFile, o.password),
 		"fill_form", fdfFile,
 		"output", outputFile,
-		"flatten",
-	}
+	)
 
 	// Run the pdftk utility.
-	if err := runCommandInPath(tmpDir, "pdftk", args...); err != nil {
-		return fmt.Errorf("pdftk error: %v", err)
+	_, err = o.runFill(args, func(args []string) ([]byte, error) {
+		return nil, runCommandInPathContext(ctx, tmpDir, "pdftk", args...)
+	})
+	if err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+
+	if o.patchNeedAppearances {
+		if err := setNeedAppearances(outputFile, o); err != nil {
+			return err
+		}
+	}
+
+	if o.validate {
+		if err := validatePDF(outputFile, o.output.password()); err != nil {
+			return err
+		}
 	}
 
+	return copyToDest(outputFile, destPDFFile, overwrite)
+}
+
+// copyToDest copies the output file to the destination file, which is only
+// replaced if overwrite is true. The output is copied to a temporary file next
+// to the destination first and renamed, so a failed copy leaves an existing
+// destination file intact.
+func copyToDest(outputFile, destPDFFile string, overwrite bool) (err error) {
 	// Check if the destination file exists.
-	e, err := exists(destPDFFile)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(destPDFFile); err == nil {
+		if !overwrite {
+			return fmt.Errorf("%w: '%s'", ErrDestExists, destPDFFile)
+		}
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(destPDFFile), ".fillpdf-*.pdf")
 	if err != nil {
 		return err
-	} else if e {
-		if !overwrite {
-			return fmt.Errorf("destination PDF file already exists: '%s'", destPDFFile)
+	}
+	tmpFile := tmp.Name()
+	tmp.Close()
+
+	// Remove the temporary file on failure.
+	defer func() {
+		if err != nil {
+			os.Remove(tmpFile)
 		}
+	}()
 
-		if err := os.Remove(destPDFFile); err != nil {
-			return err
+	if err = copyFile(outputFile, tmpFile); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFile, mode); err != nil {
+		return err
+	}
+
+	// On success, move the file to the final destination.
+	return os.Rename(tmpFile, destPDFFile)
+}
+
+// writeFileAtomic replaces the file with the data like copyToDest, so on
+// failure the previous file is left intact. The mode of an existing file is
+// kept, new files are created with 0644.
+func writeFileAtomic(path string, data []byte) (err error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".fillpdf-*.pdf")
+	if err != nil {
+		return err
+	}
+	tmpFile := tmp.Name()
+
+	// Remove the temporary file on failure.
+	defer func() {
+		if err != nil {
+			os.Remove(tmpFile)
 		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFile, mode); err != nil {
+		return err
+	}
+
+	// On success, move the file to the final destination.
+	return os.Rename(tmpFile, path)
+}
+
+// FillPDFToBytes fills the form like Fill and returns the PDF. The temporary
+// files are written to a new directory in tmpDir, which is removed again, so
+// concurrent calls may share the same tmpDir.
+func FillPDFToBytes(form Form, formAbsolutePath, tmpDir, checkedString, uncheckedString string, opts ...Option) ([]byte, error) {
+	return fillPDFToBytes(form, formAbsolutePath, tmpDir, checkedString, uncheckedString, newOptions(opts))
+}
+
+// FillToWriter fills the PDF form like FillPDFToBytes, but streams the output
+// of pdftk to w instead of buffering the PDF in memory, e.g. into a HTTP
+// response. On error, w may have received partial output.
+func FillToWriter(form Form, formPDFFile string, w io.Writer, checkedString, uncheckedString string, opts ...Option) error {
+	return FillToWriterContext(context.Background(), form, formPDFFile, w, checkedString, uncheckedString, opts...)
+}
+
+// FillToWriterContext is like FillToWriter, but kills pdftk once the context
+// is done.
+func FillToWriterContext(ctx context.Context, form Form, formPDFFile string, w io.Writer, checkedString, uncheckedString string, opts ...Option) error {
+	var err error
+	o := newOptions(opts)
+
+	// Check if the pdftk utility exists, if it is used.
+	if err := o.lookPathBackend(); err != nil {
+		return err
 	}
 
-	// On success, copy the output file to the final destination.
-	if err := copyFile(outputFile, destPDFFile); err != nil {
+	// Get the absolute path.
+	if formPDFFile, err = getAbs(formPDFFile); err != nil {
 		return err
 	}
 
-	return nil
+	return fillPDFToWriter(ctx, form, formPDFFile, o.tempDir, checkedString, uncheckedString, o, o.progressWriter(w))
+}
+
+// FillReader is like FillPDFToBytes, but reads the form template from r.
+func FillReader(form Form, template io.Reader, checkedString, uncheckedString string, opts ...Option) ([]byte, error) {
+	var b bytes.Buffer
+	if err := FillReaderToWriter(form, template, &b, checkedString, uncheckedString, opts...); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// FillReaderToWriter reads the form template from r like FillReader and
+// streams the filled PDF to w like FillToWriter, e.g. from an embedded file
+// system or an upload into a HTTP response. pdftk needs a seekable input, so
+// the template is still written to a temporary file.
+func FillReaderToWriter(form Form, template io.Reader, w io.Writer, checkedString, uncheckedString string, opts ...Option) error {
+	o := newOptions(opts)
+
+	// Check if the pdftk utility exists, if it is used.
+	if err := o.lookPathBackend(); err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	formPDFFile, err := spoolReader(template, tmpDir, "form.pdf")
+	if err != nil {
+		return err
+	}
+
+	return fillPDFToWriter(context.Background(), form, formPDFFile, tmpDir, checkedString, uncheckedString, o, o.progressWriter(w))
 }
 
-func FillPDFToBytes(form Form, formAbsolutePath, tmpDir, checkedString, uncheckedString string) ([]byte, error) {
+// FillAndReadValues fills the PDF form without flattening it and returns the
+// interactive PDF together with the field values it contains. The values are
+// read from the produced PDF, so usually no second pdftk run is required.
+// WithFlatten is ignored, the other options apply like for FillPDFToBytes.
+func FillAndReadValues(form Form, formPDFFile, checkedString, uncheckedString string, opts ...Option) ([]byte, map[string]string, error) {
 	var err error
-	id, err := GetID("pdf_")
+	o := newOptions(append(opts[:len(opts):len(opts)], WithFlatten(false)))
+
+	// Check if the pdftk utility exists, if it is used.
+	if err := o.lookPathBackend(); err != nil {
+		return nil, nil, err
+	}
+
+	// Get the absolute path.
+	if formPDFFile, err = getAbs(formPDFFile); err != nil {
+		return nil, nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Create the fdf data file.
-	fdfFile := filepath.Clean(tmpDir + "/" + id + ".fdf")
+	// Remove the temporary directory on defer again.
 	defer func() {
-		os.Remove(fdfFile)
+		os.RemoveAll(tmpDir)
 	}()
 
-	if err := createFdfFile(form, fdfFile, checkedString, uncheckedString); err != nil {
+	pdf, err := fillPDFToBytes(form, formPDFFile, tmpDir, checkedString, uncheckedString, o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := filledFieldValues(pdf, tmpDir, o.output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pdf, values, nil
+}
+
+// filledFieldValues returns the field values of a filled PDF. The PDF is
+// parsed as stored, expanding compressed object streams. Documents the
+// parser can't read directly, e.g. encrypted ones, are normalized with pdftk
+// uncompress first. A PDF without reachable form returns an error instead of
+// empty values.
+func filledFieldValues(pdf []byte, tmpDir string, output OutputOptions) (map[string]string, error) {
+	doc, err := parseNativePDF(pdf)
+	if err != nil || doc.dict(doc.catalog()["AcroForm"]) == nil {
+		if lookPath("pdftk") == nil {
+			pdfFile, err := spoolReader(bytes.NewReader(pdf), tmpDir, "filled.pdf")
+			if err != nil {
+				return nil, err
+			}
+			if doc, err = loadPDFFileWithPassword(pdfFile, output.password()); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if doc.dict(doc.catalog()["AcroForm"]) == nil {
+		return nil, fmt.Errorf("no form found in the filled PDF")
+	}
+	return doc.fieldValues(), nil
+}
+
+// FillResult is the result of FillWithValues.
+type FillResult struct {
+	// PDF is the filled, interactive PDF.
+	PDF []byte
+	// Values are the field values present in PDF.
+	Values map[string]string
+	// Unmatched lists the form keys without a field of the template. Keys
+	// of nested forms are given fully qualified, like "applicant.name".
+	Unmatched []string
+	// Duration is the time the fill took.
+	Duration time.Duration
+}
+
+// FillWithValues fills the PDF form like FillAndReadValues and additionally
+// reports the form keys not matching any field of the template and the time
+// the fill took. With WithLooseFieldNames keys matching a field ignoring case
+// and surrounding whitespace are not reported.
+func FillWithValues(form Form, formPDFFile, checkedString, uncheckedString string, opts ...Option) (*FillResult, error) {
+	o := newOptions(opts)
+
+	start := time.Now()
+	pdf, values, err := FillAndReadValues(form, formPDFFile, checkedString, uncheckedString, opts...)
+	if err != nil {
 		return nil, err
 	}
 
+	r := &FillResult{
+		PDF:      pdf,
+		Values:   values,
+		Duration: time.Since(start),
+	}
+	if r.Unmatched, err = unmatchedFormKeys(form, formPDFFile, o); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// unmatchedFormKeys returns the fully qualified form keys without a field of
+// the template, sorted.
+func unmatchedFormKeys(form Form, formPDFFile string, o *options) ([]string, error) {
+	names, err := dumpFieldNames(formPDFFile, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if form, err = qualifyFieldNames(form); err != nil {
+		return nil, err
+	}
+	if o.looseFieldNames {
+		if form, err = resolveFieldNames(form, names); err != nil {
+			return nil, err
+		}
+	}
+
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	var unmatched []string
+	for key := range form {
+		if !known[key] {
+			unmatched = append(unmatched, key)
+		}
+	}
+	sort.Strings(unmatched)
+	return unmatched, nil
+}
+
+func fillPDFToBytes(form Form, formAbsolutePath, tmpDir, checkedString, uncheckedString string, o *options) ([]byte, error) {
+	var b bytes.Buffer
+	if err := fillPDFToWriter(context.Background(), form, formAbsolutePath, tmpDir, checkedString, uncheckedString, o, &b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// fillPDFToWriter fills the form and streams the output of pdftk to w.
+func fillPDFToWriter(ctx context.Context, form Form, formAbsolutePath, tmpDir, checkedString, uncheckedString string, o *options, w io.Writer) error {
+	// Create a working directory of this call, so concurrent calls sharing
+	// the same tmpDir never touch each other's files.
+	workDir, err := createTempDir(tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory in '%s': %v", tmpDir, err)
+	}
+
+	// Remove the working directory on defer again.
+	defer func() {
+		os.RemoveAll(workDir)
+	}()
+
+	if b := o.getBackend(); b != nil {
+		outputFile := filepath.Clean(workDir + "/output.pdf")
+		if err := fillWithBackend(ctx, b, form, formAbsolutePath, outputFile, checkedString, uncheckedString, o); err != nil {
+			return err
+		}
+		return copyFileToWriter(outputFile, w)
+	}
+
+	// Create the fdf data file.
+	fdfFile := filepath.Clean(workDir + "/data.fdf")
+	templateFile := filepath.Clean(workDir + "/template.pdf")
+
+	if form, formAbsolutePath, err = o.prepareForm(form, formAbsolutePath, templateFile); err != nil {
+		return err
+	}
+
+	if err := o.createDataFile(form, fdfFile, checkedString, uncheckedString); err != nil {
+		return err
+	}
+
 	// Create the pdftk command line arguments.
-	args := []string{
-		formAbsolutePath,
+	args := append(pdftkInput(formAbsolutePath, o.password),
 		"fill_form", fdfFile,
 		"output", "-",
-		"flatten",
+	)
+
+	// Run the pdftk utility. A failed flatten or parse attempt might have
+	// written partial output and the validation and the NeedAppearances flag
+	// need the complete output, so write it to a file first in these cases.
+	if o.validate || (o.flatten && o.flattenSkipped != nil) || o.needAppearances || o.repair {
+		outputFile := filepath.Clean(workDir + "/output.pdf")
+
+		args[len(args)-1] = outputFile
+		_, err = o.runFill(args, func(args []string) ([]byte, error) {
+			return nil, runCommandInPathContext(ctx, workDir, "pdftk", args...)
+		})
+		if err != nil {
+			return fmt.Errorf("pdftk error: %w", err)
+		}
+
+		if o.patchNeedAppearances {
+			if err := setNeedAppearances(outputFile, o); err != nil {
+				return err
+			}
+		}
+
+		if o.validate {
+			if err := validatePDF(outputFile, o.output.password()); err != nil {
+				return err
+			}
+		}
+
+		return copyFileToWriter(outputFile, w)
 	}
 
-	// Run the pdftk utility.
-	bytes, err := runCommandWithOutput(tmpDir, "pdftk", args...)
+	_, err = o.runFill(args, func(args []string) ([]byte, error) {
+		return nil, runCommandToWriterContext(ctx, workDir, w, "pdftk", args...)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("pdftk error: %v", err)
+		return fmt.Errorf("pdftk error: %w", err)
 	}
-	return bytes, err
+	return nil
 }
 
-// createFdfFile with 16 bit encoded utf to enable creation of pdf with special characters
-func createFdfFile(form Form, path, checkedString, uncheckedString string) error {
+// copyFileToWriter copies the content of the file to w.
+func copyFileToWriter(path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// runFill runs the pdftk fill_form command line, adding the flatten flag if
+// requested or else the need_appearances flag, if requested and supported.
+// On a flatten failure the fill is retried without flattening if the flatten
+// fallback is enabled. patchNeedAppearances is set if the output still needs
+// the flag. If pdftk fails to parse the template and the repair is enabled,
+// the template is repaired and filled again.
+func (o *options) runFill(args []string, run func(args []string) ([]byte, error)) ([]byte, error) {
+	out, err := o.runFillOnce(args, run)
+	if err == nil || !o.repair || !isParseError(err) {
+		return out, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, tmpErr := createTempDir(o.tempDir)
+	if tmpErr != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// The template is the first argument, followed by its password, if any.
+	repaired := filepath.Clean(tmpDir + "/repaired.pdf")
+	if repairFile(args[0], repaired, o.password) != nil {
+		return nil, err
+	}
+	return o.runFillOnce(append([]string{repaired}, args[1:]...), run)
+}
+
+// runFillOnce runs the fill of runFill.
+func (o *options) runFillOnce(args []string, run func(args []string) ([]byte, error)) ([]byte, error) {
+	args = append(args[:len(args):len(args)], o.output.args()...)
+	if o.dropXFA {
+		args = append(args, "drop_xfa")
+	}
+	o.patchNeedAppearances = false
+
+	// Let viewers render the values of fields staying interactive.
+	editable := args
+	patch := false
+	if o.needAppearances {
+		if ok, err := SupportsOutputOption("need_appearances"); err == nil && ok {
+			editable = append(args[:len(args):len(args)], "need_appearances")
+		} else {
+			patch = true
+		}
+	}
+
+	if o.flattenSkipped != nil {
+		*o.flattenSkipped = !o.flatten
+	}
+	if !o.flatten {
+		o.patchNeedAppearances = patch
+		return run(editable)
+	}
+
+	out, err := run(append(args[:len(args):len(args)], "flatten"))
+	if err != nil && o.flattenSkipped != nil && strings.Contains(strings.ToLower(err.Error()), "flatten") {
+		*o.flattenSkipped = true
+		o.patchNeedAppearances = patch
+		return run(editable)
+	}
+	return out, err
+}
+
+// createFdfFile with 16 bit encoded utf to enable creation of pdf with special characters.
+// The strings are written as escaped literals or, if hex is set, as hex strings.
+func createFdfFile(form Form, path, checkedString, uncheckedString string, hex bool) error {
 	// Create the file.
 	file, err := os.Create(path)
 	if err != nil {
@@ -170,26 +658,16 @@ func createFdfFile(form Form, path, checkedString, uncheckedString string) error
 	b.WriteString("/Fields [\n")
 
 	// Write the form data.
-	for key, value := range form {
-		var valStr string
-		switch v := value.(type) {
-		case bool:
-			if v {
-				valStr = checkedString
-			} else {
-				valStr = uncheckedString
-			}
-		default:
-			valStr = fmt.Sprintf("%v", value)
-		}
+	for _, key := range sortedFieldNames(form) {
+		valStr := normalizeLineBreaks(formValueString(form[key], checkedString, uncheckedString))
 
 		b.WriteString("<<\n")
-		b.WriteString("/T (")
-		b.Write(EncodeUTF16(key, true))
-		b.WriteString(")\n")
-		b.WriteString("/V (")
-		b.Write(EncodeUTF16(valStr, true))
-		b.WriteString(")\n")
+		b.WriteString("/T ")
+		writeFdfString(b, key, hex)
+		b.WriteString("\n")
+		b.WriteString("/V ")
+		writeFdfString(b, valStr, hex)
+		b.WriteString("\n")
 		b.WriteString(">>\n")
 	}
 
@@ -209,20 +687,196 @@ func createFdfFile(form Form, path, checkedString, uncheckedString string) error
 	return b.Flush()
 }
 
-// Taken from https://gist.github.com/ik5/65de721ca495fa1bf451
-// EncodeUTF16 get a utf8 string and translate it into a slice of bytes of ucs2
+// writeFdfString writes s as UTF-16 encoded FDF string, either as literal
+// like (\xFE\xFF...) or as hex string like <FEFF...>.
+func writeFdfString(b *bufio.Writer, s string, hex bool) {
+	if hex {
+		fmt.Fprintf(b, "<%X>", EncodeUTF16(s, true))
+		return
+	}
+	b.WriteByte('(')
+	b.Write(escapeFdfString(EncodeUTF16(s, true)))
+	b.WriteByte(')')
+}
+
+// escapeFdfString escapes the bytes of a string literal, which would end the
+// literal or be changed by the reader: parentheses, backslashes and line
+// breaks. The bytes are escaped individually, since they also occur inside
+// of UTF-16 code units.
+func escapeFdfString(s []byte) []byte {
+	escaped := make([]byte, 0, len(s))
+	for _, c := range s {
+		switch c {
+		case '(', ')', '\\':
+			escaped = append(escaped, '\\', c)
+		case '\r':
+			escaped = append(escaped, '\\', 'r')
+		case '\n':
+			escaped = append(escaped, '\\', 'n')
+		default:
+			escaped = append(escaped, c)
+		}
+	}
+	return escaped
+}
+
+// lineBreakReplacer turns all line breaks into carriage returns, the line
+// separator of multi-line text field values.
+var lineBreakReplacer = strings.NewReplacer("\r\n", "\r", "\n", "\r")
+
+// normalizeLineBreaks makes \n, \r\n and \r line breaks of a multi-line value
+// appear the same across viewers.
+func normalizeLineBreaks(s string) string {
+	return lineBreakReplacer.Replace(s)
+}
+
+// createXfdfFile writes the form as UTF-8 encoded XFDF, which pdftk reads
+// like an FDF file.
+func createXfdfFile(form Form, path, checkedString, uncheckedString string) error {
+	// Create the file.
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Create a new writer.
+	b := bufio.NewWriter(file)
+
+	// Header
+	b.WriteString(xml.Header)
+	b.WriteString("<xfdf xmlns=\"http://ns.adobe.com/xfdf/\" xml:space=\"preserve\">\n")
+	b.WriteString("<fields>\n")
+
+	// Write the form data.
+	for _, key := range sortedFieldNames(form) {
+		b.WriteString("<field name=\"")
+		xml.EscapeText(b, []byte(key))
+		b.WriteString("\"><value>")
+		xml.EscapeText(b, []byte(normalizeLineBreaks(formValueString(form[key], checkedString, uncheckedString))))
+		b.WriteString("</value></field>\n")
+	}
+
+	// Footer
+	b.WriteString("</fields>\n")
+	b.WriteString("</xfdf>\n")
+
+	// Flush everything.
+	return b.Flush()
+}
+
+// formValueString returns the string written for a form value.
+func formValueString(value interface{}, checkedString, uncheckedString string) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return checkedString
+		}
+		return uncheckedString
+	case Checkbox:
+		return v.value(checkedString, uncheckedString)
+	case *Checkbox:
+		return v.value(checkedString, uncheckedString)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// qualifyFieldNames returns the form with the values of nested forms keyed
+// by their fully qualified field names. A field given twice is an error.
+func qualifyFieldNames(form Form) (Form, error) {
+	nested := false
+	for _, value := range form {
+		switch value.(type) {
+		case Form, map[string]interface{}:
+			nested = true
+		}
+	}
+	if !nested {
+		return form, nil
+	}
+
+	qualified := make(Form, len(form))
+	var add func(prefix string, form map[string]interface{}, depth int) error
+	add = func(prefix string, form map[string]interface{}, depth int) error {
+		if depth > 64 {
+			return fmt.Errorf("form nested too deeply: '%s'", prefix)
+		}
+		for key, value := range form {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			switch v := value.(type) {
+			case Form:
+				if err := add(name, v, depth+1); err != nil {
+					return err
+				}
+			case map[string]interface{}:
+				if err := add(name, v, depth+1); err != nil {
+					return err
+				}
+			default:
+				if _, ok := qualified[name]; ok {
+					return fmt.Errorf("form field given twice: '%s'", name)
+				}
+				qualified[name] = value
+			}
+		}
+		return nil
+	}
+	if err := add("", form, 0); err != nil {
+		return nil, err
+	}
+	return qualified, nil
+}
+
+// sortedFieldNames returns the field names of the form in sorted order, so
+// the data files are written deterministically.
+func sortedFieldNames(form Form) []string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EncodeUTF16 encodes the UTF-8 string as UTF-16BE, preceded by the byte
+// order mark FE FF if addBom is true. Characters outside of the basic
+// multilingual plane are encoded as surrogate pairs. An empty string results
+// in an empty slice or the byte order mark only.
 func EncodeUTF16(s string, addBom bool) []byte {
-	r := []rune(s)
-	iresult := utf16.Encode(r)
-	var bytes []byte
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2*len(units)+2)
 	if addBom {
-		bytes = make([]byte, 2)
-		bytes = []byte{254, 255}
+		b = append(b, 0xFE, 0xFF)
+	}
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+// DecodeUTF16 decodes UTF-16 encoded bytes, e.g. a value encoded with
+// EncodeUTF16, into a UTF-8 string. A byte order mark selects big or little
+// endian and is removed, without one big endian is assumed. Unpaired
+// surrogates are replaced by U+FFFD.
+func DecodeUTF16(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", fmt.Errorf("invalid UTF-16: odd number of bytes: %d", len(b))
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE {
+		order = binary.LittleEndian
+		b = b[2:]
+	} else if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		b = b[2:]
 	}
-	for _, i := range iresult {
-		temp := make([]byte, 2)
-		binary.BigEndian.PutUint16(temp, i)
-		bytes = append(bytes, temp...)
+
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
 	}
-	return bytes
+	return string(utf16.Decode(units)), nil
 }
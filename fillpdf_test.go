@@ -0,0 +1,830 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEscapeFdfString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"(a)", `\(a\)`},
+		{`C:\dir`, `C:\\dir`},
+		{"a\nb", `a\nb`},
+		{"a\rb", `a\rb`},
+		{"\\)", `\\\)`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		escaped := escapeFdfString([]byte(tt.in))
+		if string(escaped) != tt.want {
+			t.Errorf("escapeFdfString(%q) = %q, want %q", tt.in, escaped, tt.want)
+		}
+
+		// The PDF reader restores the original bytes.
+		l := &pdfLexer{data: append(append([]byte("("), escaped...), ')')}
+		v, err := l.parseValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s, _ := v.(pdfString); string(s) != tt.in {
+			t.Errorf("parsed %q = %q, want %q", escaped, s, tt.in)
+		}
+	}
+}
+
+func TestCreateFdfFile(t *testing.T) {
+	form := Form{
+		"name":    "Smith (née Müller)",
+		"path":    `C:\forms\a.pdf`,
+		"address": "Main St. 1\nSpringfield",
+		"agree":   true,
+		"(odd)":   "€ 5",
+	}
+	want := map[string]string{
+		"name":    "Smith (née Müller)",
+		"path":    `C:\forms\a.pdf`,
+		"address": "Main St. 1\rSpringfield",
+		"agree":   "Yes",
+		"(odd)":   "€ 5",
+	}
+
+	for _, hex := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "form.fdf")
+		if err := createFdfFile(form, path, "Yes", "Off", hex); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex && bytes.Contains(data, []byte("(")) {
+			t.Errorf("hex FDF contains a literal string")
+		}
+
+		doc, err := parsePDF(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, f := range doc.array(doc.dict(doc.dict(doc.objects[1])["FDF"])["Fields"]) {
+			field := doc.dict(f)
+			got[doc.text(field["T"])] = doc.text(field["V"])
+		}
+		if len(got) != len(want) {
+			t.Errorf("hex %v: fields = %q, want %q", hex, got, want)
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("hex %v: field '%s' = %q, want %q", hex, name, got[name], value)
+			}
+		}
+	}
+}
+
+func TestNormalizeLineBreaks(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"one line", "one line"},
+		{"a\nb", "a\rb"},
+		{"a\r\nb", "a\rb"},
+		{"a\rb", "a\rb"},
+		{"a\n\nb", "a\r\rb"},
+		{"a\r\n\r\nb", "a\r\rb"},
+		{"a\n\r\nb\rc", "a\r\rb\rc"},
+		{"\ntrailing\r\n", "\rtrailing\r"},
+	}
+	for _, tt := range tests {
+		if got := normalizeLineBreaks(tt.in); got != tt.want {
+			t.Errorf("normalizeLineBreaks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeUTF16(t *testing.T) {
+	tests := []struct {
+		in     string
+		addBom bool
+		want   []byte
+	}{
+		{"", false, []byte{}},
+		{"", true, []byte{0xFE, 0xFF}},
+		{"A", false, []byte{0x00, 0x41}},
+		{"A", true, []byte{0xFE, 0xFF, 0x00, 0x41}},
+		{"ü€", false, []byte{0x00, 0xFC, 0x20, 0xAC}},
+		{"😀", false, []byte{0xD8, 0x3D, 0xDE, 0x00}},
+		{"a😀b", true, []byte{0xFE, 0xFF, 0x00, 0x61, 0xD8, 0x3D, 0xDE, 0x00, 0x00, 0x62}},
+		// Invalid UTF-8 is encoded as U+FFFD.
+		{"\xff", false, []byte{0xFF, 0xFD}},
+	}
+	for _, tt := range tests {
+		got := EncodeUTF16(tt.in, tt.addBom)
+		if got == nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("EncodeUTF16(%q, %v) = % X, want % X", tt.in, tt.addBom, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	tests := []struct {
+		in      []byte
+		want    string
+		wantErr bool
+	}{
+		{nil, "", false},
+		{[]byte{0xFE, 0xFF}, "", false},
+		{[]byte{0xFF, 0xFE}, "", false},
+		{[]byte{0x00, 0x41}, "A", false},
+		{[]byte{0xFE, 0xFF, 0x00, 0x41}, "A", false},
+		{[]byte{0xFF, 0xFE, 0x41, 0x00}, "A", false},
+		{[]byte{0xD8, 0x3D, 0xDE, 0x00}, "😀", false},
+		{[]byte{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE}, "😀", false},
+		// Unpaired surrogates are replaced.
+		{[]byte{0xD8, 0x3D, 0x00, 0x41}, "\uFFFDA", false},
+		{[]byte{0xDE, 0x00}, "\uFFFD", false},
+		{[]byte{0x00}, "", true},
+		{[]byte{0xFE, 0xFF, 0x00, 0x41, 0x00}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := DecodeUTF16(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DecodeUTF16(% X) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DecodeUTF16(% X) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// Values round trip with and without byte order mark.
+	for _, s := range []string{"", "Grüße", "a😀b", "日本語"} {
+		for _, addBom := range []bool{false, true} {
+			if got, err := DecodeUTF16(EncodeUTF16(s, addBom)); err != nil || got != s {
+				t.Errorf("round trip of %q with BOM %v = %q, %v", s, addBom, got, err)
+			}
+		}
+	}
+}
+
+func TestQualifyFieldNames(t *testing.T) {
+	deep := Form{"value": "x"}
+	for i := 0; i < 70; i++ {
+		deep = Form{"level": deep}
+	}
+
+	tests := []struct {
+		name    string
+		form    Form
+		want    Form
+		wantErr bool
+	}{
+		{"flat", Form{"a": "1", "b.c": "2"}, Form{"a": "1", "b.c": "2"}, false},
+		{"nested", Form{"applicant": Form{"name": Form{"first": "Ann"}, "age": 42}},
+			Form{"applicant.name.first": "Ann", "applicant.age": 42}, false},
+		{"map", Form{"applicant": map[string]interface{}{"name": "Ann"}, "payment": "cash"},
+			Form{"applicant.name": "Ann", "payment": "cash"}, false},
+		{"mixed", Form{"applicant": Form{"name": "Ann"}, "applicant.age": 42},
+			Form{"applicant.name": "Ann", "applicant.age": 42}, false},
+		{"empty nested", Form{"applicant": Form{}, "payment": "cash"}, Form{"payment": "cash"}, false},
+		{"twice", Form{"applicant": Form{"name": "Ann"}, "applicant.name": "Bob"}, nil, true},
+		{"too deep", deep, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := qualifyFieldNames(tt.form)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("qualifyFieldNames() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qualifyFieldNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortedFieldNames(t *testing.T) {
+	form := Form{"b": 1, "a.b": 2, "a": 3, "B": 4, "a.a": 5}
+	if got := strings.Join(sortedFieldNames(form), " "); got != "B a a.a a.b b" {
+		t.Errorf("sortedFieldNames() = %q", got)
+	}
+}
+
+// writeRadioForm writes a form with the radio group "payment" of the
+// widgets "card" and "cash" and the nested text field
+// "applicant.name.first".
+func writeRadioForm(t testing.TB, name string) string {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+	page := w.add(nil)
+
+	state := func(s string) pdfRef {
+		return w.add(&pdfStream{dict: pdfDict{"Subtype": pdfName("Form"), "BBox": pdfArray{0, 0, 10, 10}}, data: []byte(s)})
+	}
+	payment := w.add(nil)
+	var radios pdfArray
+	for i, value := range []string{"card", "cash"} {
+		radios = append(radios, w.add(pdfDict{
+			"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page, "Parent": payment,
+			"AS":   pdfName("Off"),
+			"AP":   pdfDict{"N": pdfDict{pdfName(value): state("0 g"), "Off": state("")}},
+			"Rect": pdfArray{72 + 40*i, 700, 82 + 40*i, 710},
+		}))
+	}
+	w.set(payment, pdfDict{
+		"FT": pdfName("Btn"), "T": pdfString("payment"), "Ff": fieldFlagRadio,
+		"V": pdfName("Off"), "Kids": radios,
+	})
+
+	applicant := w.add(nil)
+	applicantName := w.add(nil)
+	first := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page, "Parent": applicantName,
+		"FT": pdfName("Tx"), "T": pdfString("first"), "Rect": pdfArray{72, 660, 272, 680},
+	})
+	w.set(applicantName, pdfDict{"T": pdfString("name"), "Parent": applicant, "Kids": pdfArray{first}})
+	w.set(applicant, pdfDict{"T": pdfString("applicant"), "Kids": pdfArray{applicantName}})
+
+	w.set(page, pdfDict{
+		"Type":      pdfName("Page"),
+		"Parent":    parent,
+		"MediaBox":  pdfArray{0, 0, 595, 842},
+		"Resources": helveticaResources(),
+		"Contents":  w.add(&pdfStream{dict: pdfDict{}, data: []byte("BT /F1 24 Tf 72 760 Td (Form) Tj ET")}),
+		"Annots":    append(radios[:len(radios):len(radios)], first),
+	})
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{page}, "Count": 1})
+	root := w.add(pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": parent,
+		"AcroForm": pdfDict{
+			"Fields": pdfArray{payment, applicant},
+			"DA":     pdfString("/Helv 0 Tf 0 g"),
+		},
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// hierarchicalForm fills the form of writeRadioForm.
+var hierarchicalForm = Form{
+	"applicant": Form{"name": map[string]interface{}{"first": "Ann"}},
+	"payment":   "cash",
+}
+
+func TestCreateFdfFileHierarchical(t *testing.T) {
+	form, err := qualifyFieldNames(hierarchicalForm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "form.fdf")
+	if err := createFdfFile(form, path, "Yes", "Off", false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parsePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A single entry with one value per fully qualified field name.
+	var got []string
+	for _, f := range doc.array(doc.dict(doc.dict(doc.objects[1])["FDF"])["Fields"]) {
+		field := doc.dict(f)
+		got = append(got, doc.text(field["T"])+"="+doc.text(field["V"]))
+		if _, ok := field["Kids"]; ok {
+			t.Errorf("field '%s' has kids", doc.text(field["T"]))
+		}
+	}
+	if want := "applicant.name.first=Ann payment=cash"; strings.Join(got, " ") != want {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+}
+
+func TestFillHierarchical(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend Backend
+	}{
+		{"native", NativeBackend{}},
+		{"pdftk", PDFTKBackend{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.backend.(PDFTKBackend); ok {
+				requirePDFTK(t)
+			}
+			template := writeRadioForm(t, "form.pdf")
+
+			pdf, err := FillPDFToBytes(hierarchicalForm, template, t.TempDir(), "Yes", "Off",
+				WithBackend(tt.backend), WithFlatten(false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			filled := filepath.Join(t.TempDir(), "filled.pdf")
+			if err := ioutil.WriteFile(filled, pdf, 0600); err != nil {
+				t.Fatal(err)
+			}
+			fields, err := GetFields(filled, WithBackend(tt.backend))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			for _, f := range fields {
+				got[f.Name] = f.Value
+			}
+			want := map[string]string{"applicant.name.first": "Ann", "payment": "cash"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("fields = %q, want %q", got, want)
+			}
+
+			// Only the widget of the selected value is on.
+			doc, err := parseNativePDF(pdf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var states []string
+			for _, ref := range doc.array(doc.dict(doc.pages()[0])["Annots"]) {
+				if as, ok := doc.dict(ref)["AS"]; ok {
+					states = append(states, string(doc.name(as)))
+				}
+			}
+			if strings.Join(states, " ") != "Off cash" {
+				t.Errorf("radio states = %q, want [Off cash]", states)
+			}
+		})
+	}
+}
+
+func TestCreateXfdfFile(t *testing.T) {
+	form := Form{
+		"name":         "Smith & <Sons>",
+		"quote":        `"Grüße" 'ü' 😀`,
+		"a&b":          "x",
+		"address":      "Main St. 1\r\nSpringfield",
+		"agree":        true,
+		"disagree":     false,
+		"person.email": "ann@example.com",
+	}
+	want := map[string]string{
+		"name":         "Smith & <Sons>",
+		"quote":        `"Grüße" 'ü' 😀`,
+		"a&b":          "x",
+		"address":      "Main St. 1\rSpringfield",
+		"agree":        "Yes",
+		"disagree":     "Off",
+		"person.email": "ann@example.com",
+	}
+
+	path := filepath.Join(t.TempDir(), "form.xfdf")
+	if err := createXfdfFile(form, path, "Yes", "Off"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Errorf("XFDF does not start with the XML header: %q", data)
+	}
+
+	var xfdf struct {
+		Space  string `xml:"space,attr"`
+		Fields []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"fields>field"`
+	}
+	if err := xml.Unmarshal(data, &xfdf); err != nil {
+		t.Fatal(err)
+	}
+	if xfdf.Space != "preserve" {
+		t.Errorf("xml:space = %q, want preserve", xfdf.Space)
+	}
+
+	var names []string
+	got := make(map[string]string)
+	for _, f := range xfdf.Fields {
+		names = append(names, f.Name)
+		got[f.Name] = f.Value
+	}
+	if strings.Join(names, " ") != strings.Join(sortedFieldNames(form), " ") {
+		t.Errorf("fields = %q, want the sorted field names", names)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("field '%s' = %q, want %q", name, got[name], value)
+		}
+	}
+}
+
+func TestFillDataFormat(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		prefix string
+	}{
+		{"default", nil, "%FDF-1.2"},
+		{"hex", []Option{WithHexFDF()}, "%FDF-1.2"},
+		{"xfdf", []Option{WithXFDF()}, xml.Header},
+		{"xfdf and hex", []Option{WithXFDF(), WithHexFDF()}, xml.Header},
+		// The help text has no *_utf8 operations.
+		{"utf8 unsupported", []Option{WithUTF8FDF()}, "%FDF-1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte(pdftkHelp)}
+			useExecutor(t, e)
+
+			var data bytes.Buffer
+			opts := append([]Option{WithBackend(PDFTKBackend{}), WithDumpFDF(&data)}, tt.opts...)
+			if _, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", opts...); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(data.String(), tt.prefix) {
+				t.Errorf("data file = %q, want prefix %q", data.String(), tt.prefix)
+			}
+			if call := e.lastCall(); !containsString(call, "fill_form") {
+				t.Errorf("pdftk call = %q, want fill_form", call)
+			}
+		})
+	}
+}
+
+// dataFileValues parses the field values of an FDF or XFDF data file.
+func dataFileValues(t testing.TB, data []byte) map[string]string {
+	t.Helper()
+	values := make(map[string]string)
+	if bytes.HasPrefix(data, []byte(xml.Header)) {
+		var xfdf struct {
+			Fields []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value"`
+			} `xml:"fields>field"`
+		}
+		if err := xml.Unmarshal(data, &xfdf); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range xfdf.Fields {
+			values[f.Name] = f.Value
+		}
+		return values
+	}
+
+	doc, err := parsePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range doc.array(doc.dict(doc.dict(doc.objects[1])["FDF"])["Fields"]) {
+		field := doc.dict(f)
+		values[doc.text(field["T"])] = doc.text(field["V"])
+	}
+	return values
+}
+
+func TestFillUTF8FDF(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	form := Form{
+		"name":    "Zoë & <Ann> 😀",
+		"agree":   true,
+		"address": Form{"city": "Zürich\nSchweiz"},
+		"color":   "g",
+	}
+
+	// pdftk builds with UTF-8 support list the *_utf8 operations.
+	utf8Help := strings.Replace(pdftkHelp, "dump_data_fields]", "dump_data_fields | dump_data_fields_utf8]", 1)
+
+	tests := []struct {
+		name   string
+		help   string
+		opts   []Option
+		prefix string
+	}{
+		{"fdf", pdftkHelp, nil, "%FDF-1.2"},
+		{"utf8", utf8Help, []Option{WithUTF8FDF()}, xml.Header},
+		{"utf8 fallback", pdftkHelp, []Option{WithUTF8FDF()}, "%FDF-1.2"},
+		{"xfdf", pdftkHelp, []Option{WithXFDF()}, xml.Header},
+	}
+	var want map[string]string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExecutor(t, &recordExecutor{stdout: []byte(tt.help)})
+
+			var data bytes.Buffer
+			opts := append([]Option{WithBackend(PDFTKBackend{}), WithDumpFDF(&data)}, tt.opts...)
+			if _, err := FillPDFToBytes(form, template, t.TempDir(), "Yes", "Off", opts...); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(data.String(), tt.prefix) {
+				t.Fatalf("data file = %q, want prefix %q", data.String(), tt.prefix)
+			}
+
+			// All data files hold the same values.
+			values := dataFileValues(t, data.Bytes())
+			if want == nil {
+				want = values
+				if want["name"] != "Zoë & <Ann> 😀" || want["address.city"] != "Zürich\rSchweiz" || want["agree"] != "Yes" {
+					t.Fatalf("values = %q", want)
+				}
+			}
+			if !reflect.DeepEqual(values, want) {
+				t.Errorf("values = %q, want %q", values, want)
+			}
+		})
+	}
+}
+
+func TestFillUTF8FDFValues(t *testing.T) {
+	requirePDFTK(t)
+	template := writeTestForm(t, "form.pdf")
+	form := Form{"name": "Zoë Łukasz", "address": Form{"city": "Zürich"}}
+
+	var results []Form
+	for _, opts := range [][]Option{nil, {WithUTF8FDF()}} {
+		opts = append([]Option{WithBackend(PDFTKBackend{}), WithFlatten(false)}, opts...)
+		pdf, err := FillPDFToBytes(form, template, t.TempDir(), "Yes", "Off", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filled := filepath.Join(t.TempDir(), "filled.pdf")
+		if err := ioutil.WriteFile(filled, pdf, 0600); err != nil {
+			t.Fatal(err)
+		}
+		values, err := GetFieldValues(filled, WithBackend(PDFTKBackend{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, values)
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("FDF values = %v, UTF-8 values = %v", results[0], results[1])
+	}
+	if results[1]["name"] != "Zoë Łukasz" {
+		t.Errorf("name = %q, want %q", results[1]["name"], "Zoë Łukasz")
+	}
+}
+
+func TestFillXFDF(t *testing.T) {
+	requirePDFTK(t)
+	template := writeTestForm(t, "form.pdf")
+
+	pdf, err := FillPDFToBytes(Form{"name": "Zoë & 😀"}, template, t.TempDir(), "Yes", "Off",
+		WithBackend(PDFTKBackend{}), WithFlatten(false), WithXFDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled := filepath.Join(t.TempDir(), "filled.pdf")
+	if err := ioutil.WriteFile(filled, pdf, 0600); err != nil {
+		t.Fatal(err)
+	}
+	values, err := GetFieldValues(filled, WithBackend(PDFTKBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["name"] != "Zoë & 😀" {
+		t.Errorf("name = %q, want %q", values["name"], "Zoë & 😀")
+	}
+}
+
+func TestFillOutputOptionsCommandLine(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	output := OutputOptions{OwnerPassword: "owner", UserPassword: "user", AllowPermissions: []string{"Printing"}}
+
+	e := &recordExecutor{stdout: []byte("%PDF-encrypted")}
+	useExecutor(t, e)
+	pdf, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off",
+		WithBackend(PDFTKBackend{}), WithOutputOptions(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pdf) != "%PDF-encrypted" {
+		t.Errorf("pdf = %q, want the pdftk output", pdf)
+	}
+	if call := strings.Join(e.lastCall(), " "); !strings.Contains(call, " output - owner_pw owner user_pw user allow Printing") {
+		t.Errorf("pdftk call = %q, want the output options", call)
+	}
+
+	// Invalid output options fail before running pdftk.
+	e.calls = nil
+	_, err = FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off",
+		WithBackend(PDFTKBackend{}), WithOutputOptions(OutputOptions{AllowPermissions: []string{"Printing"}}))
+	if err == nil {
+		t.Error("fill with permissions but no password succeeded")
+	}
+	for _, call := range e.calls {
+		if containsString(call, "fill_form") {
+			t.Errorf("pdftk ran: %q", call)
+		}
+	}
+}
+
+func TestFillEncrypted(t *testing.T) {
+	requirePDFTK(t)
+	template := writeTestForm(t, "form.pdf")
+	output := filepath.Join(t.TempDir(), "encrypted.pdf")
+
+	err := Fill(Form{"name": "Ann"}, template, output, "Yes", "Off", true,
+		WithBackend(PDFTKBackend{}), WithOutputOptions(OutputOptions{OwnerPassword: "owner", UserPassword: "user"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NumPages(output); err == nil {
+		t.Error("encrypted PDF opened without password")
+	}
+	if n, err := numPages(output, "user"); err != nil || n != 1 {
+		t.Errorf("pages with user password = %d, %v, want 1", n, err)
+	}
+}
+
+func TestFillPDFToBytesConcurrent(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	tmpDir := t.TempDir()
+
+	const n = 20
+	results := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = FillPDFToBytes(Form{"name": fmt.Sprint("Ann ", i)}, template, tmpDir, "Yes", "Off",
+				WithBackend(NativeBackend{}), WithFlatten(false))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("fill %d: %v", i, errs[i])
+		}
+		filled := filepath.Join(t.TempDir(), "filled.pdf")
+		if err := ioutil.WriteFile(filled, results[i], 0600); err != nil {
+			t.Fatal(err)
+		}
+		values, err := GetFieldValues(filled, WithBackend(NativeBackend{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprint("Ann ", i); values["name"] != want {
+			t.Errorf("fill %d: name = %q, want %q", i, values["name"], want)
+		}
+	}
+
+	// The working directories of the calls are removed again.
+	entries, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("left in tmpDir: %s", e.Name())
+	}
+}
+
+// failExecutor fails every command with the error.
+type failExecutor struct {
+	err error
+}
+
+// Run implements Executor.
+func (e failExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	return nil, []byte(e.err.Error()), e.err
+}
+
+func TestCopyToDest(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.pdf")
+	if err := ioutil.WriteFile(output, []byte("%PDF-new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		output    string
+		existing  bool
+		overwrite bool
+		want      string
+		wantErr   bool
+	}{
+		{"new", output, false, false, "%PDF-new", false},
+		{"overwrite", output, true, true, "%PDF-new", false},
+		{"exists", output, true, false, "%PDF-old", true},
+		{"missing output", filepath.Join(dir, "missing.pdf"), true, true, "%PDF-old", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := t.TempDir()
+			dest := filepath.Join(destDir, "dest.pdf")
+			if tt.existing {
+				if err := ioutil.WriteFile(dest, []byte("%PDF-old"), 0640); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := copyToDest(tt.output, dest, tt.overwrite)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("copyToDest() error = %v, want error %v", err, tt.wantErr)
+			}
+			if data, err := ioutil.ReadFile(dest); err != nil || string(data) != tt.want {
+				t.Errorf("dest = %q, %v, want %q", data, err, tt.want)
+			}
+			if tt.existing {
+				if info, err := os.Stat(dest); err != nil || info.Mode().Perm() != 0640 {
+					t.Errorf("dest mode = %v, %v, want 0640", info.Mode().Perm(), err)
+				}
+			}
+
+			// No temporary file is left next to the destination.
+			entries, err := ioutil.ReadDir(destDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("files in the destination directory = %d, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestFillFailurePreservesDest(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name     string
+		executor Executor
+	}{
+		{"pdftk error", failExecutor{errors.New("Error: Failed to open form data file")}},
+		{"no output", &recordExecutor{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExecutor(t, tt.executor)
+
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "dest.pdf")
+			if err := ioutil.WriteFile(dest, []byte("%PDF-old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Fill(Form{"name": "Ann"}, template, dest, "Yes", "Off", true, WithBackend(PDFTKBackend{}))
+			if err == nil {
+				t.Fatal("Fill succeeded")
+			}
+			if data, err := ioutil.ReadFile(dest); err != nil || string(data) != "%PDF-old" {
+				t.Errorf("dest = %q, %v, want the original file", data, err)
+			}
+			if entries, err := ioutil.ReadDir(dir); err != nil || len(entries) != 1 {
+				t.Errorf("files in the destination directory = %d, %v, want 1", len(entries), err)
+			}
+		})
+	}
+}
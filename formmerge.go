@@ -0,0 +1,78 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergePolicy defines how MergeForms resolves fields set in multiple forms
+// with different values.
+type MergePolicy int
+
+const (
+	// MergeLastWins keeps the value of the last form.
+	MergeLastWins MergePolicy = iota
+	// MergeFirstWins keeps the value of the first form.
+	MergeFirstWins
+	// MergeErrorOnConflict fails listing all conflicting fields.
+	MergeErrorOnConflict
+)
+
+// MergeForms combines the forms into a new form. Fields set to equal values
+// in multiple forms are no conflict. The input forms are not modified.
+func MergeForms(policy MergePolicy, forms ...Form) (Form, error) {
+	if policy < MergeLastWins || policy > MergeErrorOnConflict {
+		return nil, fmt.Errorf("invalid merge policy: %d", policy)
+	}
+
+	merged := Form{}
+	var conflicts []string
+	for _, form := range forms {
+		for key, value := range form {
+			prev, ok := merged[key]
+			if !ok {
+				merged[key] = value
+				continue
+			}
+			if reflect.DeepEqual(prev, value) {
+				continue
+			}
+
+			switch policy {
+			case MergeLastWins:
+				merged[key] = value
+			case MergeErrorOnConflict:
+				if !containsString(conflicts, key) {
+					conflicts = append(conflicts, key)
+				}
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("conflicting form values: '%s'", strings.Join(conflicts, "', '"))
+	}
+	return merged, nil
+}
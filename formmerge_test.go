@@ -0,0 +1,97 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeForms(t *testing.T) {
+	a := Form{"name": "Ann", "city": "Berlin", "agree": true}
+	b := Form{"name": "Bob", "zip": "10115", "agree": true}
+	c := Form{"city": "Hamburg"}
+
+	tests := []struct {
+		name    string
+		policy  MergePolicy
+		forms   []Form
+		want    Form
+		wantErr string
+	}{
+		{
+			name:   "last wins",
+			policy: MergeLastWins,
+			forms:  []Form{a, b, c},
+			want:   Form{"name": "Bob", "city": "Hamburg", "zip": "10115", "agree": true},
+		},
+		{
+			name:   "first wins",
+			policy: MergeFirstWins,
+			forms:  []Form{a, b, c},
+			want:   Form{"name": "Ann", "city": "Berlin", "zip": "10115", "agree": true},
+		},
+		{
+			name:    "error on conflict",
+			policy:  MergeErrorOnConflict,
+			forms:   []Form{a, b, c},
+			wantErr: "conflicting form values: 'city', 'name'",
+		},
+		{
+			name:   "equal values are no conflict",
+			policy: MergeErrorOnConflict,
+			forms:  []Form{{"agree": true, "name": "Ann"}, {"agree": true}, {"name": "Ann"}},
+			want:   Form{"agree": true, "name": "Ann"},
+		},
+		{
+			name:   "no forms",
+			policy: MergeLastWins,
+			want:   Form{},
+		},
+		{
+			name:    "invalid policy",
+			policy:  MergePolicy(3),
+			forms:   []Form{a},
+			wantErr: "invalid merge policy: 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeForms(tt.policy, tt.forms...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(merged, tt.want) {
+				t.Errorf("merged = %v, want %v", merged, tt.want)
+			}
+		})
+	}
+
+	// The inputs are not modified.
+	if !reflect.DeepEqual(a, Form{"name": "Ann", "city": "Berlin", "agree": true}) {
+		t.Errorf("input form modified: %v", a)
+	}
+}
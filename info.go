@@ -0,0 +1,436 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumPages returns the number of pages of the PDF file. The error matches
+// ErrPasswordRequired with errors.Is for an encrypted file.
+func NumPages(pdfFile string) (int, error) {
+	return numPages(pdfFile, "")
+}
+
+// numPages is NumPages for a password protected file.
+func numPages(pdfFile, password string) (int, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return 0, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return 0, err
+	}
+
+	// Run the pdftk utility.
+	args := append(pdftkInput(pdfFile, password), "dump_data")
+	out, err := runCommandWithOutput("", "pdftk", args...)
+	if err != nil {
+		return 0, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if v := strings.TrimPrefix(s.Text(), "NumberOfPages:"); v != s.Text() {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return 0, fmt.Errorf("invalid number of pages: '%s': %v", pdfFile, err)
+			}
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("failed to read the number of pages: '%s'", pdfFile)
+}
+
+// ValidatePDF checks whenever the file is a complete PDF document pdftk is
+// able to read, to catch truncated or corrupt output.
+func ValidatePDF(pdfFile string) error {
+	return validatePDF(pdfFile, "")
+}
+
+// validatePDF is ValidatePDF for a password protected file.
+func validatePDF(pdfFile, password string) error {
+	data, err := ioutil.ReadFile(pdfFile)
+	if err != nil {
+		return err
+	}
+
+	// Check the header and the end of file marker first.
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return fmt.Errorf("invalid PDF: missing header: '%s'", pdfFile)
+	}
+	tail := data
+	if len(tail) > 1024 {
+		tail = tail[len(tail)-1024:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("invalid PDF: missing end of file marker: '%s'", pdfFile)
+	}
+
+	numPages, err := numPages(pdfFile, password)
+	if err != nil {
+		return fmt.Errorf("invalid PDF: %v", err)
+	} else if numPages < 1 {
+		return fmt.Errorf("invalid PDF: no pages: '%s'", pdfFile)
+	}
+
+	return nil
+}
+
+// GetMetadata returns the document information dictionary of the PDF file,
+// e.g. the Title, Author, Subject, Keywords and Creator entries.
+func GetMetadata(pdfFile string) (map[string]string, error) {
+	data, err := dumpData(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	return data.info, nil
+}
+
+// Bookmark is an entry of the document outline.
+type Bookmark struct {
+	Title string
+	// Level is the nesting level, starting at 1 for top-level bookmarks.
+	Level int
+	// Page is the target page, starting at 1. It is 0 if the bookmark
+	// does not point to a page.
+	Page int
+}
+
+// GetBookmarks returns the document outline of the PDF file in document order.
+func GetBookmarks(pdfFile string) ([]Bookmark, error) {
+	data, err := dumpData(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	return data.bookmarks, nil
+}
+
+// UpdateInfo sets the document information entries of the PDF file in place,
+// e.g. Title, Author, Subject, Keywords and Creator. Values may contain any
+// UTF-8 characters, line breaks are replaced by spaces. Entries already set
+// in the document are only replaced if overwrite is true. The file is
+// replaced atomically and keeps its mode.
+func UpdateInfo(pdfFile string, info map[string]string, overwrite bool) error {
+	fb, err := UpdateInfoToBytes(pdfFile, info, overwrite)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(pdfFile, fb)
+}
+
+// UpdateInfoToBytes is like UpdateInfo, but returns the updated PDF instead
+// of changing the file.
+func UpdateInfoToBytes(pdfFile string, info map[string]string, overwrite bool) ([]byte, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	if !overwrite {
+		existing, err := GetMetadata(pdfFile)
+		if err != nil {
+			return nil, err
+		}
+		filtered := make(map[string]string, len(info))
+		for key, value := range info {
+			if existing[key] == "" {
+				filtered[key] = value
+			}
+		}
+		info = filtered
+	}
+
+	return updateInfo(pdfFile, "", info, nil)
+}
+
+// SetBookmarks replaces the document outline of the input PDF with the
+// bookmarks and writes the result to output, e.g. one entry per section of a
+// merged packet. The bookmarks are given in document order. The first one has
+// level 1 and the levels increase by at most one from bookmark to bookmark.
+// Every bookmark needs a target page. No bookmarks remove the outline. The
+// output is replaced atomically and keeps its mode, so it may be the input.
+func SetBookmarks(input, output string, bookmarks []Bookmark) error {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return err
+	}
+
+	if input, err = getAbs(input); err != nil {
+		return err
+	}
+
+	level := 0
+	for _, b := range bookmarks {
+		if b.Level < 1 || b.Level > level+1 {
+			return fmt.Errorf("invalid level of bookmark '%s': %d", b.Title, b.Level)
+		} else if b.Page < 1 {
+			return fmt.Errorf("invalid page of bookmark '%s': %d", b.Title, b.Page)
+		}
+		level = b.Level
+	}
+
+	// pdftk keeps the outline if the info file has no bookmarks.
+	var fb []byte
+	if len(bookmarks) == 0 {
+		fb, err = removeOutline(input)
+	} else {
+		fb, err = updateInfo(input, "", nil, bookmarks)
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(output, fb)
+}
+
+// removeOutline returns the PDF without document outline.
+func removeOutline(pdfFile string) ([]byte, error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	root, ok := doc.trailer["Root"].(pdfRef)
+	if !ok {
+		return nil, fmt.Errorf("invalid PDF: missing document catalog")
+	}
+
+	u := doc.update()
+	catalog := copyDict(doc.catalog())
+	delete(catalog, "Outlines")
+	if doc.name(catalog["PageMode"]) == "UseOutlines" {
+		delete(catalog, "PageMode")
+	}
+	u.set(root, catalog)
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	if err := u.save(outputFile, ""); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(outputFile)
+}
+
+// updateInfo sets the info entries and bookmarks of the PDF with pdftk and
+// returns the updated PDF. The PDF file path must be absolute.
+func updateInfo(pdfFile, password string, info map[string]string, bookmarks []Bookmark) ([]byte, error) {
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the info file. Without update_info_utf8, pdftk expects
+	// non-ASCII characters as XML character references.
+	operation := utf8Operation("update_info")
+	var b bytes.Buffer
+	newlines := strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+	keys := make([]string, 0, len(info))
+	for key := range info {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" || strings.ContainsAny(key, "\r\n") {
+			return nil, fmt.Errorf("invalid info key: '%s'", key)
+		}
+		value := newlines.Replace(info[key])
+		if operation == "update_info" {
+			key, value = escapeNonASCII(key), escapeNonASCII(value)
+		}
+		fmt.Fprintf(&b, "InfoBegin\nInfoKey: %s\nInfoValue: %s\n", key, value)
+	}
+	for _, bm := range bookmarks {
+		title := newlines.Replace(bm.Title)
+		if operation == "update_info" {
+			title = escapeNonASCII(title)
+		}
+		fmt.Fprintf(&b, "BookmarkBegin\nBookmarkTitle: %s\nBookmarkLevel: %d\nBookmarkPageNumber: %d\n", title, bm.Level, bm.Page)
+	}
+	infoFile := filepath.Clean(tmpDir + "/info.txt")
+	if err := ioutil.WriteFile(infoFile, b.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+
+	// Create the pdftk command line arguments.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	args := append(pdftkInput(pdfFile, password),
+		operation, infoFile,
+		"output", outputFile,
+	)
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	return ioutil.ReadFile(outputFile)
+}
+
+// pdfData is the parsed output of pdftk dump_data_utf8 or dump_data.
+type pdfData struct {
+	info      map[string]string
+	numPages  int
+	pageSizes [][2]float64
+	bookmarks []Bookmark
+}
+
+// dumpData runs pdftk dump_data_utf8, or dump_data on versions without it,
+// and parses its output.
+func dumpData(pdfFile string) (*pdfData, error) {
+	return dumpDataWithPassword(pdfFile, "")
+}
+
+// dumpDataWithPassword is like dumpData for a password protected file.
+func dumpDataWithPassword(pdfFile, password string) (*pdfData, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	// Run the pdftk utility.
+	operation := utf8Operation("dump_data")
+	out, err := runCommandWithOutput("", "pdftk", append(pdftkInput(pdfFile, password), operation)...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	return parseDumpData(out, operation == "dump_data"), nil
+}
+
+// docData returns the exported document data.
+func (d *pdfData) docData() *DocData {
+	return &DocData{
+		Info:      d.info,
+		NumPages:  d.numPages,
+		PageSizes: d.pageSizes,
+		Bookmarks: d.bookmarks,
+	}
+}
+
+// parseDumpData parses the output of pdftk dump_data_utf8 or dump_data. The
+// values of dump_data are unescaped, since it writes non-ASCII characters as
+// XML character references.
+func parseDumpData(out []byte, unescape bool) *pdfData {
+	data := &pdfData{
+		info: make(map[string]string),
+	}
+
+	var key string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		i := strings.Index(s.Text(), ": ")
+		if i < 0 {
+			continue
+		}
+		name, value := s.Text()[:i], s.Text()[i+2:]
+		if unescape {
+			value = html.UnescapeString(value)
+		}
+
+		switch name {
+		case "InfoKey":
+			key = value
+		case "InfoValue":
+			data.info[key] = value
+		case "BookmarkTitle":
+			data.bookmarks = append(data.bookmarks, Bookmark{Title: value})
+		case "BookmarkLevel", "BookmarkPageNumber":
+			if len(data.bookmarks) == 0 {
+				continue
+			}
+			n, _ := strconv.Atoi(value)
+			if b := &data.bookmarks[len(data.bookmarks)-1]; name == "BookmarkLevel" {
+				b.Level = n
+			} else {
+				b.Page = n
+			}
+		case "NumberOfPages":
+			data.numPages, _ = strconv.Atoi(value)
+		case "PageMediaDimensions":
+			var size [2]float64
+			for j, f := range strings.Fields(strings.Replace(value, ",", "", -1)) {
+				if j < 2 {
+					size[j], _ = strconv.ParseFloat(f, 64)
+				}
+			}
+			data.pageSizes = append(data.pageSizes, size)
+		}
+	}
+
+	return data
+}
+
+// escapeNonASCII replaces the non-ASCII characters of s and '&' with XML
+// character references.
+func escapeNonASCII(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 128 && r != '&' {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "&#%d;", r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDumpData(t *testing.T) {
+	out := []byte(`InfoBegin
+InfoKey: Title
+InfoValue: Fish &amp; Chips
+BookmarkBegin
+BookmarkTitle: Kapitel &#196;
+BookmarkLevel: 1
+BookmarkPageNumber: 2
+NumberOfPages: 3
+PageMediaBegin
+PageMediaDimensions: 595.276 841.89
+`)
+
+	tests := []struct {
+		name      string
+		unescape  bool
+		title     string
+		bookmarks []Bookmark
+	}{
+		{"utf8", false, "Fish &amp; Chips", []Bookmark{{Title: "Kapitel &#196;", Level: 1, Page: 2}}},
+		{"plain", true, "Fish & Chips", []Bookmark{{Title: "Kapitel Ä", Level: 1, Page: 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := parseDumpData(out, tt.unescape)
+			if got := data.info["Title"]; got != tt.title {
+				t.Errorf("title = %q, want %q", got, tt.title)
+			}
+			if !reflect.DeepEqual(data.bookmarks, tt.bookmarks) {
+				t.Errorf("bookmarks = %v, want %v", data.bookmarks, tt.bookmarks)
+			}
+			if data.numPages != 3 {
+				t.Errorf("pages = %d, want 3", data.numPages)
+			}
+			if want := [][2]float64{{595.276, 841.89}}; !reflect.DeepEqual(data.pageSizes, want) {
+				t.Errorf("page sizes = %v, want %v", data.pageSizes, want)
+			}
+		})
+	}
+}
+
+func TestUpdateInfoRoundTrip(t *testing.T) {
+	requirePDFTK(t)
+	pdfFile := writeTestPDF(t, "info.pdf", 1)
+	if err := os.Chmod(pdfFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info := map[string]string{
+		"Title":  "Übersicht der Prüfungsergebnisse",
+		"Author": "Jürgen Müller & Söhne",
+	}
+	if err := UpdateInfo(pdfFile, info, true); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetMetadata(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range info {
+		if got[key] != want {
+			t.Errorf("%s = %q, want %q", key, got[key], want)
+		}
+	}
+
+	fi, err := os.Stat(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.pdf")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("content = %q, want %q", data, "new")
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	// A failing write leaves the file and no temporary files behind.
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "doc.pdf"), []byte("x")); err == nil {
+		t.Error("writeFileAtomic succeeded in a missing directory")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Errorf("files = %v, want only doc.pdf", files)
+	}
+}
+
+func TestSetBookmarks(t *testing.T) {
+	requirePDFTK(t)
+	pdfFile := writeTestPDF(t, "bookmarks.pdf", 3)
+
+	bookmarks := []Bookmark{
+		{Title: "Einführung", Level: 1, Page: 1},
+		{Title: "Details", Level: 2, Page: 2},
+		{Title: "Anhang", Level: 1, Page: 3},
+	}
+	if err := SetBookmarks(pdfFile, pdfFile, bookmarks); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetBookmarks(pdfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, bookmarks) {
+		t.Errorf("bookmarks = %v, want %v", got, bookmarks)
+	}
+
+	// No bookmarks remove the outline.
+	if err := SetBookmarks(pdfFile, pdfFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = GetBookmarks(pdfFile); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("bookmarks = %v, want none", got)
+	}
+}
+
+func TestSetBookmarksInvalid(t *testing.T) {
+	requirePDFTK(t)
+	pdfFile := writeTestPDF(t, "bookmarks.pdf", 1)
+
+	tests := []struct {
+		name      string
+		bookmarks []Bookmark
+	}{
+		{"first level 2", []Bookmark{{Title: "A", Level: 2, Page: 1}}},
+		{"level skipped", []Bookmark{{Title: "A", Level: 1, Page: 1}, {Title: "B", Level: 3, Page: 1}}},
+		{"no page", []Bookmark{{Title: "A", Level: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetBookmarks(pdfFile, pdfFile+".out", tt.bookmarks); err == nil {
+				t.Error("SetBookmarks succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestValidatePDF(t *testing.T) {
+	valid, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		e       Executor
+		wantErr string
+	}{
+		{"valid", valid, &recordExecutor{stdout: []byte("NumberOfPages: 1\n")}, ""},
+		{"no header", valid[5:], &recordExecutor{stdout: []byte("NumberOfPages: 1\n")}, "missing header"},
+		{"truncated", valid[:len(valid)/2], &recordExecutor{stdout: []byte("NumberOfPages: 1\n")}, "missing end of file marker"},
+		{"no pages", valid, &recordExecutor{stdout: []byte("NumberOfPages: 0\n")}, "no pages"},
+		{"unreadable", valid, failExecutor{errors.New("Error: Unable to find file")}, "invalid PDF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExecutor(t, tt.e)
+			pdfFile := filepath.Join(t.TempDir(), "output.pdf")
+			if err := ioutil.WriteFile(pdfFile, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidatePDF(pdfFile)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePDF() = %v, want nil", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePDF() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFillValidation(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	valid, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, output := range [][]byte{valid, valid[:len(valid)/2]} {
+		e := &recordExecutor{stdout: []byte("NumberOfPages: 1\n"), output: output}
+		useExecutor(t, e)
+		wantErr := len(output) < len(valid)
+
+		dest := filepath.Join(t.TempDir(), "filled.pdf")
+		err := Fill(Form{"name": "Ann"}, template, dest, "Yes", "Off", false,
+			WithBackend(PDFTKBackend{}),
+			WithValidation(),
+			WithOutputOptions(OutputOptions{OwnerPassword: "owner"}),
+		)
+		if (err != nil) != wantErr {
+			t.Errorf("Fill() of %d bytes error = %v, want error %v", len(output), err, wantErr)
+		}
+		if _, statErr := os.Stat(dest); wantErr != os.IsNotExist(statErr) {
+			t.Errorf("Fill() of %d bytes: destination exists = %v, want %v", len(output), statErr == nil, !wantErr)
+		}
+		if !wantErr {
+			// The encrypted output is validated with its password.
+			if call := e.lastCall(); !containsString(call, "input_pw") || !containsString(call, "owner") {
+				t.Errorf("validation call = %q, want input_pw owner", call)
+			}
+		}
+
+		_, err = FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", WithBackend(PDFTKBackend{}), WithValidation())
+		if (err != nil) != wantErr {
+			t.Errorf("FillPDFToBytes() of %d bytes error = %v, want error %v", len(output), err, wantErr)
+		}
+	}
+}
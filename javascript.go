@@ -0,0 +1,198 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"sort"
+)
+
+// JavaScript is a JavaScript action found in a PDF document.
+type JavaScript struct {
+	// Name is the name of document level scripts and empty for scripts
+	// attached to pages, annotations, fields or the open action.
+	Name string
+	// Object is the number of the PDF object containing the action.
+	Object int
+	Source string
+}
+
+// HasJavaScript returns whenever the PDF document contains JavaScript actions.
+func HasJavaScript(pdfFile string) (bool, error) {
+	scripts, err := FindJavaScript(pdfFile)
+	return len(scripts) > 0, err
+}
+
+// FindJavaScript returns all JavaScript actions of the PDF document, sorted by
+// the object containing them. Scripts inside of XFA forms are not reported.
+func FindJavaScript(pdfFile string) ([]JavaScript, error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	return doc.javaScripts(), nil
+}
+
+// StripJavaScript removes the document level scripts of the input PDF,
+// empties the code of all other JavaScript actions and writes the result to
+// output. WithTempDir applies like for a fill.
+func StripJavaScript(input, output string, opts ...Option) error {
+	doc, err := loadPDFFile(input)
+	if err != nil {
+		return err
+	}
+	return stripJSUpdate(doc).save(output, newOptions(opts).tempDir)
+}
+
+// stripJSUpdate removes the JavaScript like StripJavaScript.
+func stripJSUpdate(doc *pdfDocument) *pdfUpdate {
+	u := doc.update()
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	for _, num := range nums {
+		if v, changed := doc.stripJS(doc.objects[num]); changed {
+			u.set(pdfRef{num: num}, v)
+		}
+	}
+
+	// Remove the document level scripts from the name dictionary.
+	catalog := doc.catalog()
+	names := doc.dict(catalog["Names"])
+	if _, ok := names["JavaScript"]; ok {
+		names = copyDict(names)
+		delete(names, "JavaScript")
+		if ref, ok := catalog["Names"].(pdfRef); ok {
+			u.set(ref, names)
+		} else if root, ok := doc.trailer["Root"].(pdfRef); ok {
+			catalog = copyDict(catalog)
+			catalog["Names"] = names
+			u.set(root, catalog)
+		}
+	}
+	return u
+}
+
+func (d *pdfDocument) javaScripts() []JavaScript {
+	// Map the document level scripts to their names.
+	names := make(map[int]string)
+	var walk func(v interface{}, depth int)
+	walk = func(v interface{}, depth int) {
+		node := d.dict(v)
+		if node == nil || depth > 64 {
+			return
+		}
+		entries := d.array(node["Names"])
+		for i := 0; i+1 < len(entries); i += 2 {
+			if ref, ok := entries[i+1].(pdfRef); ok {
+				names[ref.num] = d.text(entries[i])
+			}
+		}
+		for _, kid := range d.array(node["Kids"]) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(d.dict(d.catalog()["Names"])["JavaScript"], 0)
+
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	var scripts []JavaScript
+	for _, num := range nums {
+		d.collectJS(d.objects[num], func(action pdfDict) {
+			js := JavaScript{
+				Name:   names[num],
+				Object: num,
+			}
+			switch src := d.resolve(action["JS"]).(type) {
+			case pdfString:
+				js.Source = decodeTextString(src)
+			case *pdfStream:
+				js.Source = decodeTextString(src.data)
+			}
+			scripts = append(scripts, js)
+		})
+	}
+	return scripts
+}
+
+// isJSAction returns whenever the dictionary is a JavaScript action.
+func (d *pdfDocument) isJSAction(dict pdfDict) bool {
+	return d.name(dict["S"]) == "JavaScript"
+}
+
+// collectJS calls found for each JavaScript action directly contained in v.
+// Indirect references are not followed, they are visited on their own.
+func (d *pdfDocument) collectJS(v interface{}, found func(action pdfDict)) {
+	switch t := v.(type) {
+	case pdfDict:
+		if d.isJSAction(t) {
+			found(t)
+		}
+		for _, e := range t {
+			d.collectJS(e, found)
+		}
+	case pdfArray:
+		for _, e := range t {
+			d.collectJS(e, found)
+		}
+	}
+}
+
+// stripJS returns a copy of v with the code of all directly contained
+// JavaScript actions removed. changed is false if v contains none.
+func (d *pdfDocument) stripJS(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case pdfDict:
+		c, changed := t, false
+		for k, e := range t {
+			if ne, ch := d.stripJS(e); ch {
+				if !changed {
+					c, changed = copyDict(t), true
+				}
+				c[k] = ne
+			}
+		}
+		if d.isJSAction(t) {
+			if !changed {
+				c, changed = copyDict(t), true
+			}
+			c["JS"] = pdfString("")
+		}
+		return c, changed
+	case pdfArray:
+		var c pdfArray
+		for i, e := range t {
+			if ne, ch := d.stripJS(e); ch {
+				if c == nil {
+					c = append(pdfArray{}, t...)
+				}
+				c[i] = ne
+			}
+		}
+		if c != nil {
+			return c, true
+		}
+	}
+	return v, false
+}
@@ -0,0 +1,135 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeJavaScriptPDF writes a PDF with a document level script, an open
+// action and a field calculation script.
+func writeJavaScriptPDF(t testing.TB) []byte {
+	t.Helper()
+	w := &pdfWriter{}
+	pages := w.add(nil)
+	page := w.add(nil)
+
+	init := w.add(pdfDict{"S": pdfName("JavaScript"), "JS": w.add(&pdfStream{dict: pdfDict{}, data: []byte("app.alert('init');")})})
+	field := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+		"FT": pdfName("Tx"), "T": pdfString("total"), "Rect": pdfArray{72, 700, 272, 720},
+		"AA": pdfDict{"C": pdfDict{"S": pdfName("JavaScript"), "JS": pdfString("event.value = 42;")}},
+	})
+	w.set(page, pdfDict{
+		"Type": pdfName("Page"), "Parent": pages, "MediaBox": pdfArray{0, 0, 595, 842},
+		"Annots": pdfArray{field},
+	})
+	w.set(pages, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{page}, "Count": 1})
+	root := w.add(pdfDict{
+		"Type":       pdfName("Catalog"),
+		"Pages":      pages,
+		"AcroForm":   pdfDict{"Fields": pdfArray{field}},
+		"OpenAction": pdfDict{"S": pdfName("JavaScript"), "JS": pdfString("this.print();")},
+		"Names":      w.add(pdfDict{"JavaScript": pdfDict{"Names": pdfArray{pdfString("init"), init}}}),
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFindJavaScript(t *testing.T) {
+	data := writeJavaScriptPDF(t)
+	input := filepath.Join(t.TempDir(), "js.pdf")
+	if err := ioutil.WriteFile(input, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	scripts, err := FindJavaScript(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []JavaScript
+	for _, js := range scripts {
+		got = append(got, JavaScript{Name: js.Name, Source: js.Source})
+	}
+	want := []JavaScript{
+		{Name: "init", Source: "app.alert('init');"},
+		{Source: "event.value = 42;"},
+		{Source: "this.print();"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindJavaScript() = %+v, want %+v", got, want)
+	}
+	for i := 1; i < len(scripts); i++ {
+		if scripts[i].Object < scripts[i-1].Object {
+			t.Errorf("scripts are not sorted by object: %+v", scripts)
+		}
+	}
+
+	if ok, err := HasJavaScript(input); err != nil || !ok {
+		t.Errorf("HasJavaScript() = %v, %v, want true", ok, err)
+	}
+}
+
+func TestHasJavaScriptNone(t *testing.T) {
+	input := writeTestPDF(t, "pages.pdf", 1)
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	if ok, err := HasJavaScript(input); err != nil || ok {
+		t.Errorf("HasJavaScript() = %v, %v, want false", ok, err)
+	}
+}
+
+func TestStripJavaScript(t *testing.T) {
+	doc, err := parseNativePDF(writeJavaScriptPDF(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := stripJSUpdate(doc).bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = parseNativePDF(out); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := doc.dict(doc.catalog()["Names"])["JavaScript"]; ok {
+		t.Error("the document level scripts are not removed")
+	}
+	for _, js := range doc.javaScripts() {
+		if js.Name != "" || js.Source != "" {
+			t.Errorf("script after strip = %+v, want an empty action", js)
+		}
+	}
+	if fields := doc.formFields(); len(fields) != 1 {
+		t.Errorf("fields after strip = %v, want the field kept", fields)
+	}
+}
@@ -21,17 +21,112 @@ package fillpdf
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 	"time"
 )
 
 // Merge concatenates all input <files> and outputs one single pdf in <output>
 func Merge(files ...string) (io.Reader, error) {
-	args := []string{}
+	return MergeContext(context.Background(), files...)
+}
+
+// MergeContext is like Merge, but kills pdftk once the context is done.
+func MergeContext(ctx context.Context, files ...string) (io.Reader, error) {
+	return mergeContext(ctx, nil, newOptions(nil), files)
+}
+
+// MergeWithPasswords is like Merge for password protected input files. The
+// passwords are looked up by the file paths as given. Files without password
+// may be mixed in. The passwords are never part of returned errors.
+func MergeWithPasswords(passwords map[string]string, files ...string) (io.Reader, error) {
+	return mergeContext(context.Background(), passwords, newOptions(nil), files)
+}
+
+// MergeWithOutputOptions is like Merge, but encrypts the output with the
+// passwords and permissions of the output options. The passwords are never
+// part of returned errors.
+func MergeWithOutputOptions(output OutputOptions, files ...string) (io.Reader, error) {
+	return mergeContext(context.Background(), nil, newOptions([]Option{WithOutputOptions(output)}), files)
+}
+
+// MergeWithOptions is like Merge with the options WithBackend, WithTempDir,
+// WithOutputOptions and WithInputPassword, which opens all files with the
+// password.
+func MergeWithOptions(files []string, opts ...Option) (io.Reader, error) {
+	return mergeFiles(files, newOptions(opts))
+}
+
+// mergeFiles merges the files like MergeWithOptions.
+func mergeFiles(files []string, o *options) (io.Reader, error) {
+	var passwords map[string]string
+	if o.password != "" {
+		passwords = make(map[string]string, len(files))
+		for _, f := range files {
+			passwords[f] = o.password
+		}
+	}
+	return mergeContext(context.Background(), passwords, o, files)
+}
+
+// mergeContext merges the files with the Backend of the call, if it is a
+// MergeBackend and neither passwords nor output options are given, or else
+// with pdftk.
+func mergeContext(ctx context.Context, passwords map[string]string, o *options, files []string) (io.Reader, error) {
+	if err := o.output.validate(); err != nil {
+		return nil, err
+	}
+	if b, ok := o.getBackend().(MergeBackend); ok && len(passwords) == 0 && len(o.output.args()) == 0 {
+		return mergeWithBackend(ctx, b, files, o)
+	}
+	return pdftkMerge(ctx, passwords, o, files)
+}
+
+// mergeWithBackend merges the files with the MergeBackend.
+func mergeWithBackend(ctx context.Context, b MergeBackend, files []string, o *options) (io.Reader, error) {
+	inputs := make([]string, len(files))
+	for i, f := range files {
+		fAbsPath, err := getAbs(f)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = fAbsPath
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	if err := b.Merge(ctx, inputs, outputFile); err != nil {
+		return nil, err
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
+
+// pdftkMerge merges the files with pdftk.
+func pdftkMerge(ctx context.Context, passwords map[string]string, o *options, files []string) (io.Reader, error) {
+	inputs := []string{}
+	absPasswords := make(map[string]string)
 
 	// Get abs path for all input files while verifying their existence
 	for _, f := range files {
@@ -39,11 +134,18 @@ func Merge(files ...string) (io.Reader, error) {
 		if err != nil {
 			return nil, err
 		}
-		args = append(args, fAbsPath)
+		inputs = append(inputs, fAbsPath)
+		if pw := passwords[f]; pw != "" {
+			absPasswords[fAbsPath] = pw
+		}
+	}
+	args, err := pdftkInputs(inputs, absPasswords)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create a temporary directory.
-	tmpDir, err := ioutil.TempDir("", "fillpdf-")
+	tmpDir, err := createTempDir(o.tempDir)
 	if err != nil {
 		return nil, err
 	}
@@ -57,12 +159,102 @@ func Merge(files ...string) (io.Reader, error) {
 	outputFile := filepath.Join(tmpDir, fmt.Sprintf("%d.pdf", time.Now().Unix()))
 
 	// Create the pdftk command line arguments.
-	args = append(args, "cat", "output", outputFile)
+	args = append(append(args, "cat", "output", outputFile), o.output.args()...)
+
+	// Run the pdftk utility.
+	err = runCommandInPathContext(ctx, tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
+
+// MergeSpec selects the pages of an input file of MergePages.
+type MergeSpec struct {
+	File string
+	// Pages are pdftk page ranges like "1-3", "5", "end", "r1" or
+	// "1-endodd". All pages are selected if empty.
+	Pages []string
+	// Rotation is the pdftk rotation keyword of the selected pages, like
+	// "east" or "left", see RotateAll. The pages are not rotated if empty.
+	Rotation string
+	// Password opens a password protected file. It is never part of
+	// returned errors.
+	Password string
+}
+
+// MergePages concatenates the selected pages of the input files in the
+// given order with the pdftk cat operation. A file may be given several
+// times, e.g. to interleave its pages with the ones of other files. Every
+// spec takes one of the pdftk handles A to Z, so at most 26 specs are
+// supported. WithTempDir applies like for a fill.
+func MergePages(specs []MergeSpec, opts ...Option) (io.Reader, error) {
+	if len(specs) > maxPDFTKHandles {
+		return nil, fmt.Errorf("too many merge specs: at most %d are supported", maxPDFTKHandles)
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	var inputs, pws, ranges []string
+	for i, spec := range specs {
+		file, err := getAbs(spec.File)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Rotation != "" && !rotateDirections[spec.Rotation] {
+			return nil, fmt.Errorf("invalid rotation direction: '%s'", spec.Rotation)
+		}
+
+		handle := pdftkHandle(i)
+		inputs = append(inputs, handle+"="+file)
+		if spec.Password != "" {
+			pws = append(pws, handle+"="+spec.Password)
+		}
+
+		pages := spec.Pages
+		if len(pages) == 0 {
+			pages = []string{"1-end"}
+		}
+		for _, r := range pages {
+			if !pageRangeRegex.MatchString(r) {
+				return nil, fmt.Errorf("invalid page range: '%s'", r)
+			}
+			ranges = append(ranges, handle+r+spec.Rotation)
+		}
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(newOptions(opts).tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the pdftk command line arguments.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	args := inputs
+	if len(pws) > 0 {
+		args = append(append(args, "input_pw"), pws...)
+	}
+	args = append(append(append(args, "cat"), ranges...), "output", outputFile)
 
 	// Run the pdftk utility.
 	err = runCommandInPath(tmpDir, "pdftk", args...)
 	if err != nil {
-		return nil, fmt.Errorf("pdftk error: %v", err)
+		return nil, fmt.Errorf("pdftk error: %w", err)
 	}
 
 	fb, err := ioutil.ReadFile(outputFile)
@@ -72,3 +264,314 @@ func Merge(files ...string) (io.Reader, error) {
 
 	return bytes.NewReader(fb), nil
 }
+
+// MergeReaders is like MergeWithOptions, but reads the input PDFs from the
+// readers.
+func MergeReaders(readers []io.Reader, opts ...Option) (io.Reader, error) {
+	o := newOptions(opts)
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	files := make([]string, len(readers))
+	for i, r := range readers {
+		if files[i], err = spoolReader(r, tmpDir, fmt.Sprintf("input_%d.pdf", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	return mergeFiles(files, o)
+}
+
+// MergeToWriter is like MergeReaders, but streams the merged PDF to w
+// instead of buffering it in memory. WithInputPassword opens all inputs with
+// the password, WithOutputOptions, WithTempDir, WithProgress and WithBackend
+// apply like for a fill.
+func MergeToWriter(w io.Writer, readers []io.Reader, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.output.validate(); err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	files := make([]string, len(readers))
+	passwords := make(map[string]string)
+	for i, r := range readers {
+		if files[i], err = spoolReader(r, tmpDir, fmt.Sprintf("input_%d.pdf", i)); err != nil {
+			return err
+		}
+		if o.password != "" {
+			passwords[files[i]] = o.password
+		}
+	}
+
+	if b, ok := o.getBackend().(MergeBackend); ok && o.password == "" && len(o.output.args()) == 0 {
+		r, err := mergeWithBackend(context.Background(), b, files, o)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(o.progressWriter(w), r)
+		return err
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return err
+	}
+
+	// Run the pdftk utility.
+	args, err := pdftkInputs(files, passwords)
+	if err != nil {
+		return err
+	}
+	args = append(append(args, "cat", "output", "-"), o.output.args()...)
+	if err := runCommandToWriter(tmpDir, o.progressWriter(w), "pdftk", args...); err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+	return nil
+}
+
+// MergeBytes is like MergeReaders for input PDFs in memory.
+func MergeBytes(pdfs ...[]byte) (io.Reader, error) {
+	readers := make([]io.Reader, len(pdfs))
+	for i, pdf := range pdfs {
+		readers[i] = bytes.NewReader(pdf)
+	}
+	return MergeReaders(readers)
+}
+
+// MergeChunked concatenates all input <files> and splits the result into
+// chunks of at most <maxPages> pages each. A reader is returned per chunk.
+// WithInputPassword, WithTempDir and WithBackend apply like for
+// MergeWithOptions.
+func MergeChunked(maxPages int, files []string, opts ...Option) ([]io.Reader, error) {
+	if maxPages < 1 {
+		return nil, fmt.Errorf("invalid maximum page count: %d", maxPages)
+	}
+
+	// The merged file is split again, so it must not be encrypted.
+	o := newOptions(opts)
+	o.output = OutputOptions{}
+	merged, err := mergeFiles(files, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	mergedFile, err := spoolReader(merged, tmpDir, "merged.pdf")
+	if err != nil {
+		return nil, err
+	}
+
+	return splitChunks(mergedFile, tmpDir, maxPages)
+}
+
+// MergeJob lists the files MergeBatch concatenates into one PDF.
+type MergeJob struct {
+	Files []string
+}
+
+// MergeResult is the result of a MergeJob. Either Output or Err is set.
+type MergeResult struct {
+	Output io.Reader
+	Err    error
+}
+
+// MergeBatch runs all merge jobs concurrently with a pool of <workers>
+// goroutines and returns the results in the order of the jobs. A failing job
+// does not affect the others. If workers is <= 0, the limit set with
+// SetMaxConcurrency or else the number of CPUs is used. The package-wide
+// process limit is honored in any case.
+func MergeBatch(jobs []MergeJob, workers int) []MergeResult {
+	if workers <= 0 {
+		workers = MaxConcurrency()
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]MergeResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				out, err := Merge(jobs[i].Files...)
+				results[i] = MergeResult{Output: out, Err: err}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// RenamedField reports a form field renamed by MergeUniqueFields. From and To
+// are fully qualified field names.
+type RenamedField struct {
+	// Source is the index of the input file containing the field.
+	Source int
+	From   string
+	To     string
+}
+
+// MergeUniqueFields concatenates all input <files> like Merge, but renames
+// form fields whose fully qualified names already occur in a previous file,
+// so viewers don't link them. The partial name of the field gets the index
+// of its source file appended, e.g. "address.city_1". Renaming a field
+// renames all its child fields too. WithTempDir, WithBackend and
+// WithOutputOptions apply like for MergeWithOptions.
+func MergeUniqueFields(files []string, opts ...Option) (io.Reader, []RenamedField, error) {
+	o := newOptions(opts)
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	used := make(map[string]bool)
+	var renamed []RenamedField
+	inputs := make([]string, len(files))
+
+	for i, f := range files {
+		doc, err := loadPDFFile(f)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		u := doc.update()
+		renamed = append(renamed, doc.uniqueFieldNames(u, used, i)...)
+
+		// Only rewrite the files with renamed fields.
+		if len(u.objects) == 0 {
+			inputs[i] = f
+			continue
+		}
+		inputs[i] = filepath.Join(tmpDir, fmt.Sprintf("input_%d.pdf", i))
+		if err := u.save(inputs[i], o.tempDir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	out, err := mergeFiles(inputs, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, renamed, nil
+}
+
+// uniqueFieldNames renames the fields of the document whose fully qualified
+// names collide with the used names of previous files, see
+// MergeUniqueFields. used maps each name to whether it names a terminal
+// field and gets the names of the document added. A terminal field collides
+// with any used name, a field with child fields only with a terminal one, so
+// the children of equally named parents are compared one by one.
+func (d *pdfDocument) uniqueFieldNames(u *pdfUpdate, used map[string]bool, source int) []RenamedField {
+	var renamed []RenamedField
+	names := make(map[string]bool)
+	qualify := func(prefix, name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	var walk func(v interface{}, prefix string, depth int)
+	walk = func(v interface{}, prefix string, depth int) {
+		field := d.dict(v)
+		if field == nil || depth > 64 {
+			return
+		}
+
+		// Kids with a /T are child fields, others are plain widgets.
+		var kids pdfArray
+		for _, kid := range d.array(field["Kids"]) {
+			if _, ok := d.dict(kid)["T"]; ok {
+				kids = append(kids, kid)
+			}
+		}
+		terminal := len(kids) == 0
+
+		name := prefix
+		if _, ok := field["T"]; ok {
+			partial := d.text(field["T"])
+			name = qualify(prefix, partial)
+
+			ref, ok := v.(pdfRef)
+			if usedTerminal, isUsed := used[name]; ok && isUsed && (terminal || usedTerminal) {
+				newPartial := fmt.Sprintf("%s_%d", partial, source)
+				for n := 2; ; n++ {
+					_, isUsed := used[qualify(prefix, newPartial)]
+					_, isName := names[qualify(prefix, newPartial)]
+					if !isUsed && !isName {
+						break
+					}
+					newPartial = fmt.Sprintf("%s_%d_%d", partial, source, n)
+				}
+
+				field = copyDict(field)
+				field["T"] = encodeTextString(newPartial)
+				u.set(ref, field)
+				renamed = append(renamed, RenamedField{Source: source, From: name, To: qualify(prefix, newPartial)})
+				name = qualify(prefix, newPartial)
+			}
+			names[name] = names[name] || terminal
+		}
+
+		for _, kid := range kids {
+			walk(kid, name, depth+1)
+		}
+	}
+
+	acro := d.dict(d.catalog()["AcroForm"])
+	for _, f := range d.array(acro["Fields"]) {
+		walk(f, "", 0)
+	}
+
+	for name, terminal := range names {
+		used[name] = used[name] || terminal
+	}
+	return renamed
+}
@@ -0,0 +1,441 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMergeToWriterOptions(t *testing.T) {
+	e := &recordExecutor{stdout: []byte("%PDF-merged")}
+	useExecutor(t, e)
+
+	var out bytes.Buffer
+	readers := []io.Reader{strings.NewReader("%PDF-a"), strings.NewReader("%PDF-b")}
+	err := MergeToWriter(&out, readers,
+		WithTempDir(t.TempDir()),
+		WithInputPassword("secret"),
+		WithOutputOptions(OutputOptions{OwnerPassword: "owner"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "%PDF-merged" {
+		t.Errorf("output = %q, want the pdftk output", out.String())
+	}
+
+	args := e.lastCall()
+	for i, arg := range args {
+		if j := strings.IndexByte(arg, '='); j > 0 && filepath.IsAbs(arg[j+1:]) {
+			args[i] = arg[:j+1] + filepath.Base(arg[j+1:])
+		}
+	}
+	if got, want := strings.Join(args, " "),
+		"pdftk A=input_0.pdf B=input_1.pdf input_pw A=secret B=secret cat output - owner_pw owner"; got != want {
+		t.Errorf("pdftk args = %q, want %q", got, want)
+	}
+}
+
+func TestMergeToWriterProgress(t *testing.T) {
+	merged := bytes.Repeat([]byte("x"), 1000)
+	useExecutor(t, &recordExecutor{stdout: merged})
+
+	var written int64
+	var out bytes.Buffer
+	readers := []io.Reader{strings.NewReader("%PDF-a"), strings.NewReader("%PDF-b")}
+	err := MergeToWriter(&out, readers, WithProgress(func(n int64) {
+		written = n
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(len(merged)) {
+		t.Errorf("progress = %d, want %d", written, len(merged))
+	}
+}
+
+func TestMergeChunkedRanges(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 15)
+	b := writeTestPDF(t, "b.pdf", 10)
+
+	tests := []struct {
+		maxPages int
+		want     []string
+	}{
+		{10, []string{"1-10", "11-20", "21-25"}},
+		{25, []string{"1-25"}},
+		{30, []string{"1-25"}},
+		{1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.maxPages), func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte("NumberOfPages: 25\n"), output: []byte("%PDF-chunk")}
+			useExecutor(t, e)
+
+			chunks, err := MergeChunked(tt.maxPages, []string{a, b})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if len(chunks) != 25 {
+					t.Errorf("chunks = %d, want 25", len(chunks))
+				}
+				return
+			}
+
+			var ranges []string
+			for _, call := range e.calls {
+				if len(call) > 3 && call[2] == "cat" {
+					ranges = append(ranges, call[3])
+				}
+			}
+			if strings.Join(ranges, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("ranges = %q, want %q", ranges, tt.want)
+			}
+			if len(chunks) != len(tt.want) {
+				t.Errorf("chunks = %d, want %d", len(chunks), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMergeChunkedInvalid(t *testing.T) {
+	if _, err := MergeChunked(0, []string{writeTestPDF(t, "a.pdf", 1)}); err == nil {
+		t.Error("MergeChunked with 0 pages succeeded")
+	}
+}
+
+func TestMergeChunked(t *testing.T) {
+	requirePDFTK(t)
+	a := writeTestPDF(t, "a.pdf", 15)
+	b := writeTestPDF(t, "b.pdf", 10)
+
+	chunks, err := MergeChunked(10, []string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("chunks = %d, want 3", len(chunks))
+	}
+	for i, want := range []int{10, 10, 5} {
+		data, err := ioutil.ReadAll(chunks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunkFile := filepath.Join(t.TempDir(), "chunk.pdf")
+		if err := ioutil.WriteFile(chunkFile, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := NumPages(chunkFile); err != nil || n != want {
+			t.Errorf("chunk %d pages = %d, %v, want %d", i+1, n, err, want)
+		}
+	}
+}
+
+func TestMergeUniqueFields(t *testing.T) {
+	requirePDFTK(t)
+	a := writeTestForm(t, "a.pdf")
+	b := writeTestForm(t, "b.pdf")
+	c := writeTestForm(t, "c.pdf")
+
+	r, renamed, err := MergeUniqueFields([]string{a, b, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RenamedField{
+		{1, "name", "name_1"}, {1, "agree", "agree_1"}, {1, "address.city", "address.city_1"}, {1, "color", "color_1"},
+		{2, "name", "name_2"}, {2, "agree", "agree_2"}, {2, "address.city", "address.city_2"}, {2, "color", "color_2"},
+	}
+	if !reflect.DeepEqual(renamed, want) {
+		t.Errorf("renamed = %v, want %v", renamed, want)
+	}
+
+	merged := filepath.Join(t.TempDir(), "merged.pdf")
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(merged, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := GetFields(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+	for _, name := range []string{"name", "name_1", "name_2", "address.city", "address.city_1", "address.city_2"} {
+		if !names[name] {
+			t.Errorf("merged PDF has no field '%s'", name)
+		}
+	}
+}
+
+func TestUniqueFieldNames(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestForm(t, "form.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []*pdfDocument{
+		parseTestPDF(t, bytes.NewReader(data)),
+		fieldTreePDF(t, "address.city", "address.zip", "color.red", "agreement"),
+		fieldTreePDF(t, "address", "color_1.red", "color_1.blue"),
+	}
+	want := [][]RenamedField{
+		nil,
+		{{1, "address.city", "address.city_1"}, {1, "color", "color_1"}},
+		{{2, "address", "address_2"}, {2, "color_1.red", "color_1.red_2"}},
+	}
+	wantNames := [][]string{
+		{"address.city", "agree", "color", "name"},
+		{"address.city_1", "address.zip", "agreement", "color_1.red"},
+		{"address_2", "color_1.blue", "color_1.red_2"},
+	}
+
+	used := make(map[string]bool)
+	for i, doc := range docs {
+		u := doc.update()
+		if renamed := doc.uniqueFieldNames(u, used, i); !reflect.DeepEqual(renamed, want[i]) {
+			t.Errorf("file %d: renamed = %v, want %v", i, renamed, want[i])
+		}
+
+		out, err := u.bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for name := range parseTestPDF(t, bytes.NewReader(out)).formFields() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, wantNames[i]) {
+			t.Errorf("file %d: fields = %q, want %q", i, names, wantNames[i])
+		}
+	}
+}
+
+// fieldTreePDF returns a document with text fields of the given fully
+// qualified names. Fields sharing a prefix share their parent fields.
+func fieldTreePDF(t *testing.T, names ...string) *pdfDocument {
+	t.Helper()
+	w := &pdfWriter{}
+	pages := w.add(pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{}, "Count": 0})
+
+	var fields pdfArray
+	parents := make(map[string]pdfRef)
+	kids := make(map[pdfRef]pdfArray)
+	for _, name := range names {
+		parts := strings.Split(name, ".")
+		var parent pdfRef
+		for i, part := range parts[:len(parts)-1] {
+			qualified := strings.Join(parts[:i+1], ".")
+			ref, ok := parents[qualified]
+			if !ok {
+				ref = w.add(nil)
+				parents[qualified] = ref
+				if i == 0 {
+					fields = append(fields, ref)
+				} else {
+					kids[parent] = append(kids[parent], ref)
+				}
+				w.set(ref, pdfDict{"T": pdfString(part)})
+			}
+			parent = ref
+		}
+
+		field := pdfDict{"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "FT": pdfName("Tx"), "T": pdfString(parts[len(parts)-1])}
+		if len(parts) == 1 {
+			fields = append(fields, w.add(field))
+		} else {
+			field["Parent"] = parent
+			kids[parent] = append(kids[parent], w.add(field))
+		}
+	}
+	for qualified, ref := range parents {
+		w.set(ref, pdfDict{"T": pdfString(qualified[strings.LastIndexByte(qualified, '.')+1:]), "Kids": kids[ref]})
+	}
+
+	root := w.add(pdfDict{"Type": pdfName("Catalog"), "Pages": pages, "AcroForm": pdfDict{"Fields": fields}})
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseTestPDF(t, bytes.NewReader(data))
+}
+
+func TestMergeTempDir(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 1)
+	b := writeTestPDF(t, "b.pdf", 1)
+
+	tests := []struct {
+		name  string
+		merge func(dir string) error
+	}{
+		{"MergePages", func(dir string) error {
+			_, err := MergePages([]MergeSpec{{File: a}, {File: b}}, WithTempDir(dir))
+			return err
+		}},
+		{"MergeReaders", func(dir string) error {
+			_, err := MergeReaders([]io.Reader{strings.NewReader("%PDF-a")}, WithTempDir(dir), WithInputPassword("secret"))
+			return err
+		}},
+		{"MergeChunked", func(dir string) error {
+			_, err := MergeChunked(1, []string{a, b}, WithTempDir(dir), WithInputPassword("secret"))
+			return err
+		}},
+		{"MergeWithTOC", func(dir string) error {
+			_, err := MergeWithTOC(true, []string{a, b}, WithTempDir(dir), WithInputPassword("secret"))
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte("NumberOfPages: 2\n"), output: []byte("%PDF-merged")}
+			useExecutor(t, e)
+
+			dir := t.TempDir()
+			if err := tt.merge(dir); err != nil {
+				t.Fatal(err)
+			}
+			checkOutputsIn(t, e.calls, dir)
+		})
+	}
+}
+
+// checkOutputsIn reports pdftk output files outside of dir.
+func checkOutputsIn(t *testing.T, calls [][]string, dir string) {
+	t.Helper()
+	if len(calls) == 0 {
+		t.Fatal("pdftk was not run")
+	}
+	for _, call := range calls {
+		for i, arg := range call {
+			if arg != "output" || i+1 == len(call) || call[i+1] == "-" {
+				continue
+			}
+			if rel, err := filepath.Rel(dir, call[i+1]); err != nil || strings.HasPrefix(rel, "..") {
+				t.Errorf("pdftk output %q is not in %q", call[i+1], dir)
+			}
+		}
+	}
+}
+
+// countExecutor counts the concurrently running commands and writes the
+// name of the first input file as output.
+type countExecutor struct {
+	mutex   sync.Mutex
+	running int
+	max     int
+}
+
+// Run implements Executor.
+func (e *countExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	e.running++
+	if e.running > e.max {
+		e.max = e.running
+	}
+	e.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	e.mutex.Lock()
+	e.running--
+	e.mutex.Unlock()
+
+	for i, arg := range args {
+		if arg == "output" && i+1 < len(args) {
+			return nil, nil, ioutil.WriteFile(args[i+1], []byte(filepath.Base(args[0])), 0600)
+		}
+	}
+	return nil, nil, nil
+}
+
+func TestMergeBatch(t *testing.T) {
+	e := &countExecutor{}
+	useExecutor(t, e)
+
+	var jobs []MergeJob
+	for i := 0; i < 8; i++ {
+		a := writeTestPDF(t, fmt.Sprintf("job%d.pdf", i), 1)
+		jobs = append(jobs, MergeJob{Files: []string{a, writeTestPDF(t, "b.pdf", 1)}})
+	}
+	jobs[3].Files = append(jobs[3].Files, filepath.Join(t.TempDir(), "missing.pdf"))
+
+	results := MergeBatch(jobs, 2)
+	if len(results) != len(jobs) {
+		t.Fatalf("results = %d, want %d", len(results), len(jobs))
+	}
+	for i, r := range results {
+		if i == 3 {
+			if r.Err == nil || r.Output != nil {
+				t.Errorf("job 3 = %v, %v, want an error for the missing file", r.Output, r.Err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("job %d: %v", i, r.Err)
+			continue
+		}
+		if out, _ := ioutil.ReadAll(r.Output); string(out) != fmt.Sprintf("job%d.pdf", i) {
+			t.Errorf("job %d output = %q, want the merge of job%d.pdf", i, out, i)
+		}
+	}
+	if e.max > 2 {
+		t.Errorf("concurrent pdftk processes = %d, want at most 2 workers", e.max)
+	}
+}
+
+func TestMergeBatchMaxConcurrency(t *testing.T) {
+	e := &countExecutor{}
+	useExecutor(t, e)
+	SetMaxConcurrency(3)
+	defer SetMaxConcurrency(0)
+
+	jobs := make([]MergeJob, 12)
+	for i := range jobs {
+		jobs[i] = MergeJob{Files: []string{writeTestPDF(t, "a.pdf", 1)}}
+	}
+
+	// More workers than the package-wide process limit.
+	for _, workers := range []int{0, 10} {
+		e.max = 0
+		for i, r := range MergeBatch(jobs, workers) {
+			if r.Err != nil {
+				t.Errorf("workers %d, job %d: %v", workers, i, r.Err)
+			}
+		}
+		if e.max > 3 {
+			t.Errorf("workers %d: concurrent pdftk processes = %d, want at most 3", workers, e.max)
+		}
+	}
+}
@@ -0,0 +1,729 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NativeBackend fills forms in pure Go without pdftk, e.g. in minimal
+// containers. It supports text, choice, checkbox and radio button fields of
+// unencrypted documents and flattening. The field appearances are created
+// with the fonts of the form in WinAnsiEncoding, values with other characters
+// return ErrNotSupported, like the options relying on pdftk. XFA forms return
+// ErrXFAForm. NativeBackend also merges, stamps and lists the fields of
+// unencrypted documents. All other functions of the package, e.g. StampPages,
+// Burst or the page operations, still require pdftk.
+type NativeBackend struct{}
+
+// FillForm implements Backend.
+func (NativeBackend) FillForm(ctx context.Context, form Form, templateFile, outputFile, checkedString, uncheckedString string, flatten bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		return err
+	}
+	if doc.hasXFA() {
+		return ErrXFAForm
+	}
+
+	u := doc.update()
+	if err := u.fillForm(form, checkedString, uncheckedString); err != nil {
+		return err
+	}
+	if flatten {
+		u.flattenForm()
+	} else {
+		u.setNeedAppearances()
+	}
+
+	return u.writeFile(outputFile)
+}
+
+// parseNativePDF parses a PDF file as stored, without normalizing it with
+// pdftk first. The objects of compressed object streams are expanded.
+func parseNativePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("invalid PDF: missing header")
+	}
+
+	doc, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("%w: encrypted documents", ErrNotSupported)
+	}
+
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		s, ok := doc.objects[num].(*pdfStream)
+		if !ok || doc.name(s.dict["Type"]) != "ObjStm" {
+			continue
+		}
+		if err := doc.expandObjectStream(s); err != nil {
+			return nil, fmt.Errorf("invalid object stream %d: %w", num, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// expandObjectStream adds the objects of the object stream. Objects defined
+// outside of object streams take precedence.
+func (d *pdfDocument) expandObjectStream(s *pdfStream) error {
+	data, err := d.streamData(s)
+	if err != nil {
+		return err
+	}
+	n, _ := d.number(s.dict["N"])
+	first, _ := d.number(s.dict["First"])
+
+	// Each header entry takes at least two bytes, so N is limited by the
+	// size of the data. Check it before allocating the header.
+	if n < 0 || n > float64(len(data)/4) || n != float64(int(n)) {
+		return fmt.Errorf("invalid number of objects: %v", n)
+	}
+	if first < 0 || first > float64(len(data)) || first != float64(int(first)) {
+		return fmt.Errorf("invalid offset of the first object: %v", first)
+	}
+
+	// The header lists pairs of object numbers and offsets.
+	l := &pdfLexer{data: data}
+	header := make([]int, 0, 2*int(n))
+	for i := 0; i < 2*int(n); i++ {
+		v, err := l.parseValue()
+		if err != nil {
+			return err
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("invalid header")
+		}
+		header = append(header, int(f))
+	}
+
+	for i := 0; i < len(header); i += 2 {
+		if _, ok := d.objects[header[i]]; ok {
+			continue
+		}
+		pos := int(first) + header[i+1]
+		if pos < 0 || pos >= len(data) {
+			return fmt.Errorf("invalid offset of object %d", header[i])
+		}
+		l := &pdfLexer{data: data, pos: pos}
+		v, err := l.parseValue()
+		if err != nil {
+			return err
+		}
+		d.objects[header[i]] = v
+	}
+	return nil
+}
+
+// streamData returns the decoded data of the stream. Only the FlateDecode
+// filter without predictors is supported.
+func (d *pdfDocument) streamData(s *pdfStream) ([]byte, error) {
+	var filters []pdfName
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = append(filters, f)
+	case pdfArray:
+		for _, e := range f {
+			filters = append(filters, d.name(e))
+		}
+	}
+	if p, ok := d.number(d.dict(s.dict["DecodeParms"])["Predictor"]); ok && p > 1 {
+		return nil, fmt.Errorf("%w: stream predictors", ErrNotSupported)
+	}
+
+	data := s.data
+	for _, f := range filters {
+		if f != "FlateDecode" {
+			return nil, fmt.Errorf("%w: %s streams", ErrNotSupported, f)
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// formField is a terminal form field with its widget annotations.
+type formField struct {
+	ref     pdfRef
+	widgets []pdfRef
+}
+
+// formFields returns the terminal fields keyed by their fully qualified names.
+func (d *pdfDocument) formFields() map[string]formField {
+	fields := make(map[string]formField)
+	d.walkFormFields(func(name string, f formField) {
+		fields[name] = f
+	})
+	return fields
+}
+
+// walkFormFields calls fn for the terminal fields in document order with
+// their fully qualified names.
+func (d *pdfDocument) walkFormFields(fn func(name string, f formField)) {
+	var walk func(v interface{}, prefix string, depth int)
+	walk = func(v interface{}, prefix string, depth int) {
+		ref, ok := v.(pdfRef)
+		field := d.dict(v)
+		if !ok || field == nil || depth > 64 {
+			return
+		}
+		name := prefix
+		if _, ok := field["T"]; ok {
+			if name != "" {
+				name += "."
+			}
+			name += d.text(field["T"])
+		}
+
+		// Kids with a /T are child fields, others are plain widgets.
+		f := formField{ref: ref}
+		if d.name(field["Subtype"]) == "Widget" {
+			f.widgets = append(f.widgets, ref)
+		}
+		isTerminal := true
+		for _, kid := range d.array(field["Kids"]) {
+			if _, ok := d.dict(kid)["T"]; ok {
+				isTerminal = false
+				walk(kid, name, depth+1)
+			} else if kidRef, ok := kid.(pdfRef); ok {
+				f.widgets = append(f.widgets, kidRef)
+			}
+		}
+		if isTerminal {
+			fn(name, f)
+		}
+	}
+
+	acro := d.dict(d.catalog()["AcroForm"])
+	for _, f := range d.array(acro["Fields"]) {
+		walk(f, "", 0)
+	}
+}
+
+// Fields implements FieldsBackend.
+func (NativeBackend) Fields(ctx context.Context, pdfFile string) ([]Field, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []Field
+	doc.walkFormFields(func(name string, f formField) {
+		fields = append(fields, doc.field(name, f))
+	})
+	return fields, nil
+}
+
+// pdfFieldTypes maps the /FT field types to the ones of pdftk.
+var pdfFieldTypes = map[pdfName]string{
+	"Btn": "Button",
+	"Tx":  "Text",
+	"Ch":  "Choice",
+	"Sig": "Signature",
+}
+
+// field describes the terminal form field like pdftk dump_data_fields.
+func (d *pdfDocument) field(name string, f formField) Field {
+	dict := d.dict(f.ref)
+	flags, _ := d.number(d.inherited(dict, "Ff"))
+	maxLength, _ := d.number(d.inherited(dict, "MaxLen"))
+	field := Field{
+		Name:         name,
+		AltName:      d.text(dict["TU"]),
+		Type:         fieldType(pdfFieldTypes[d.name(d.inherited(dict, "FT"))], int(flags)),
+		Flags:        int(flags),
+		Value:        d.fieldValue(d.inherited(dict, "V")),
+		DefaultValue: d.fieldValue(d.inherited(dict, "DV")),
+		MaxLength:    int(maxLength),
+	}
+
+	switch field.Type {
+	case FieldTypeComboBox, FieldTypeListBox:
+		// Options are export values or pairs of export and display value.
+		for _, o := range d.array(d.inherited(dict, "Opt")) {
+			if pair := d.array(o); len(pair) > 0 {
+				o = pair[0]
+			}
+			field.Options = append(field.Options, d.text(o))
+		}
+	case FieldTypeCheckbox, FieldTypeRadio:
+		// The states are the normal appearances of the widgets.
+		states := make(map[string]bool)
+		for _, ref := range f.widgets {
+			for state := range d.dict(d.dict(d.dict(ref)["AP"])["N"]) {
+				states[string(state)] = true
+			}
+		}
+		for state := range states {
+			field.Options = append(field.Options, state)
+		}
+		sort.Strings(field.Options)
+	}
+	return field
+}
+
+// fieldValue returns a field value like fieldValues.
+func (d *pdfDocument) fieldValue(v interface{}) string {
+	switch t := d.resolve(v).(type) {
+	case pdfString:
+		return decodeTextString(t)
+	case pdfName:
+		return string(t)
+	case pdfArray:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = d.text(e)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+// fillForm sets the values of the form fields and creates the appearances of
+// the text and choice fields. Values of unknown fields are ignored like pdftk
+// does.
+func (u *pdfUpdate) fillForm(form Form, checkedString, uncheckedString string) error {
+	d := u.doc
+	fields := d.formFields()
+	a := d.newTextAppearance()
+
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, ok := fields[name]
+		if !ok {
+			continue
+		}
+		value := normalizeLineBreaks(formValueString(form[name], checkedString, uncheckedString))
+		field := copyDict(d.dict(f.ref))
+		flags, _ := d.number(d.inherited(field, "Ff"))
+
+		switch d.name(d.inherited(field, "FT")) {
+		case "Tx", "Ch":
+			field["V"] = encodeTextString(value)
+			u.set(f.ref, field)
+			for _, ref := range f.widgets {
+				widget := copyDict(d.dict(ref))
+				ap, err := a.create(widget, value)
+				if err != nil {
+					return fmt.Errorf("field '%s': %w", name, err)
+				}
+				widget["AP"] = pdfDict{"N": u.add(ap)}
+				u.set(ref, widget)
+			}
+		case "Btn":
+			if int(flags)&fieldFlagPushButton != 0 {
+				continue
+			}
+			field["V"] = pdfName(value)
+			u.set(f.ref, field)
+
+			// Show the state of the value, if the widget has one.
+			for _, ref := range f.widgets {
+				widget := copyDict(d.dict(ref))
+				widget["AS"] = pdfName("Off")
+				if _, ok := d.dict(d.dict(widget["AP"])["N"])[pdfName(value)]; ok {
+					widget["AS"] = pdfName(value)
+				}
+				u.set(ref, widget)
+			}
+		default:
+			return fmt.Errorf("%w: type of field '%s'", ErrNotSupported, name)
+		}
+	}
+	return nil
+}
+
+// textAppearance creates the appearance streams of text and choice fields.
+type textAppearance struct {
+	doc     *pdfDocument
+	da      string
+	q       float64
+	fonts   pdfDict
+	metrics FontMetrics
+}
+
+func (d *pdfDocument) newTextAppearance() *textAppearance {
+	acro := d.dict(d.catalog()["AcroForm"])
+	a := &textAppearance{
+		doc:     d,
+		da:      d.text(acro["DA"]),
+		fonts:   copyDict(d.dict(d.dict(acro["DR"])["Font"])),
+		metrics: fontMetricsChain{d.formFontMetrics(), StandardFontMetrics},
+	}
+	a.q, _ = d.number(acro["Q"])
+	return a
+}
+
+// create returns the appearance stream showing the value in the widget.
+func (a *textAppearance) create(widget pdfDict, value string) (*pdfStream, error) {
+	d := a.doc
+	w := pdfWidget{dict: widget}
+	for i, v := range d.array(widget["Rect"]) {
+		if i < 4 {
+			w.rect[i], _ = d.number(v)
+		}
+	}
+	width, height := w.width(), w.height()
+
+	flags, _ := d.number(d.inherited(widget, "Ff"))
+	if n, ok := d.number(d.inherited(widget, "MaxLen")); ok && int(n) > 0 && len([]rune(value)) > int(n) {
+		value = string([]rune(value)[:int(n)])
+	}
+	q := a.q
+	if n, ok := d.number(d.inherited(widget, "Q")); ok {
+		q = n
+	}
+
+	// Split the default appearance into the font and the color operators.
+	da := d.text(d.inherited(widget, "DA"))
+	if da == "" {
+		da = a.da
+	}
+	font, size := parseDA(da)
+	var color []string
+	tokens := strings.Fields(da)
+	for i := 0; i < len(tokens); i++ {
+		if i+2 < len(tokens) && tokens[i+2] == "Tf" {
+			i += 2
+			continue
+		}
+		color = append(color, tokens[i])
+	}
+	if font == "" {
+		font = "Helv"
+	}
+	fonts := copyDict(a.fonts)
+	if d.dict(fonts[pdfName(font)]) == nil {
+		fonts[pdfName(font)] = helveticaResources()["Font"].(pdfDict)["F1"]
+	}
+	baseFont := string(d.name(d.dict(fonts[pdfName(font)])["BaseFont"]))
+
+	measure := func(s string, size float64) float64 {
+		return measureText(s, baseFont, size, a.metrics)
+	}
+	boxWidth, boxHeight := width-2*fieldPadding, height-2*fieldPadding
+
+	// Auto sized text (size 0) shrinks until it fits.
+	var lines []string
+	if int(flags)&fieldFlagMultiline != 0 {
+		if size <= 0 {
+			for size = 12; size > 4; size -= 0.5 {
+				lines = wrapLines(value, boxWidth, func(s string) float64 { return measure(s, size) })
+				if float64(len(lines))*size*fieldLineHeight <= boxHeight {
+					break
+				}
+			}
+		}
+		lines = wrapLines(value, boxWidth, func(s string) float64 { return measure(s, size) })
+	} else {
+		value = strings.Replace(value, "\r", " ", -1)
+		if size <= 0 {
+			size = math.Min(12, boxHeight/fieldLineHeight)
+			if tw := measure(value, 1); tw > 0 {
+				size = math.Min(size, boxWidth/tw)
+			}
+			size = math.Max(size, 4)
+		}
+		lines = []string{value}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "/Tx BMC\nq\n1 1 %s %s re W n\nBT\n/%s %s Tf %s\n",
+		pdfNumber(width-2), pdfNumber(height-2), font, pdfNumber(size), strings.Join(color, " "))
+	for i, line := range lines {
+		text, err := encodeWinAnsi(line)
+		if err != nil {
+			return nil, err
+		}
+
+		x := fieldPadding
+		switch tw := measure(line, size); q {
+		case 1:
+			x = (width - tw) / 2
+		case 2:
+			x = width - fieldPadding - tw
+		}
+		y := (height - 0.7*size) / 2
+		if len(lines) > 1 || int(flags)&fieldFlagMultiline != 0 {
+			y = height - fieldPadding - 0.8*size - float64(i)*size*fieldLineHeight
+		}
+		fmt.Fprintf(&b, "1 0 0 1 %s %s Tm <%X> Tj\n", pdfNumber(x), pdfNumber(y), text)
+	}
+	b.WriteString("ET\nQ\nEMC\n")
+
+	s := &pdfStream{
+		dict: pdfDict{
+			"Type":      pdfName("XObject"),
+			"Subtype":   pdfName("Form"),
+			"BBox":      pdfArray{0.0, 0.0, width, height},
+			"Resources": pdfDict{"Font": fonts},
+		},
+		data: b.Bytes(),
+	}
+
+	// Rotate the appearance like the widget.
+	mk := d.dict(widget["MK"])
+	if r, _ := d.number(mk["R"]); int(r)%360 != 0 {
+		switch (int(r)%360 + 360) % 360 {
+		case 90:
+			s.dict["Matrix"] = pdfArray{0.0, 1.0, -1.0, 0.0, 0.0, 0.0}
+		case 180:
+			s.dict["Matrix"] = pdfArray{-1.0, 0.0, 0.0, -1.0, 0.0, 0.0}
+		case 270:
+			s.dict["Matrix"] = pdfArray{0.0, -1.0, 1.0, 0.0, 0.0, 0.0}
+		}
+	}
+	return s, nil
+}
+
+// wrapLines splits the value into paragraphs and wraps their words into
+// lines fitting into the width.
+func wrapLines(value string, width float64, measure func(string) float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(value, "\r") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && measure(line+" "+word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfNumber formats a number for content streams.
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// winAnsiSpecial maps the characters of WinAnsiEncoding outside of Latin-1.
+var winAnsiSpecial = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86,
+	'‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C,
+	'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encodeWinAnsi encodes s for a font with WinAnsiEncoding.
+func encodeWinAnsi(s string) ([]byte, error) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+			b = append(b, byte(r))
+		} else if c, ok := winAnsiSpecial[r]; ok {
+			b = append(b, c)
+		} else {
+			return nil, fmt.Errorf("%w: character %q", ErrNotSupported, r)
+		}
+	}
+	return b, nil
+}
+
+// flattenForm draws the appearances of all visible widgets into the page
+// contents and removes the widgets and the form.
+func (u *pdfUpdate) flattenForm() {
+	d := u.doc
+	var begin pdfRef
+	for _, pageRef := range d.pages() {
+		page := d.dict(pageRef)
+		annots := d.array(page["Annots"])
+		resources := copyDict(d.dict(d.inherited(page, "Resources")))
+		xobjects := copyDict(d.dict(resources["XObject"]))
+
+		var keep pdfArray
+		var ops bytes.Buffer
+		for i, annot := range annots {
+			dict := d.dict(annot)
+			if d.name(dict["Subtype"]) != "Widget" {
+				keep = append(keep, annot)
+				continue
+			}
+
+			// Skip hidden widgets.
+			if f, _ := d.number(dict["F"]); int(f)&(1<<1|1<<5) != 0 {
+				continue
+			}
+			ref, s := d.normalAppearance(dict)
+			if s == nil {
+				continue
+			}
+			if d.name(s.dict["Subtype"]) != "Form" {
+				fixed := &pdfStream{dict: copyDict(s.dict), data: s.data}
+				fixed.dict["Type"] = pdfName("XObject")
+				fixed.dict["Subtype"] = pdfName("Form")
+				u.set(ref, fixed)
+			}
+
+			name := fmt.Sprintf("FillPDFField%d", i)
+			xobjects[pdfName(name)] = ref
+			m := appearanceMatrix(d, dict, s)
+			fmt.Fprintf(&ops, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+				pdfNumber(m[0]), pdfNumber(m[1]), pdfNumber(m[2]), pdfNumber(m[3]), name)
+		}
+		if len(keep) == len(annots) {
+			continue
+		}
+
+		page = copyDict(page)
+		if len(keep) > 0 {
+			page["Annots"] = keep
+		} else {
+			delete(page, "Annots")
+		}
+		if ops.Len() > 0 {
+			resources["XObject"] = xobjects
+			page["Resources"] = resources
+
+			// Protect the appearances from the graphics state of the page.
+			if begin == (pdfRef{}) {
+				begin = u.add(&pdfStream{dict: pdfDict{}, data: []byte("q\n")})
+			}
+			contents := pdfArray{begin}
+			switch c := page["Contents"].(type) {
+			case pdfRef:
+				if arr := d.array(c); arr != nil {
+					contents = append(contents, arr...)
+				} else {
+					contents = append(contents, c)
+				}
+			case pdfArray:
+				contents = append(contents, c...)
+			}
+			end := u.add(&pdfStream{dict: pdfDict{}, data: append([]byte("\nQ\n"), ops.Bytes()...)})
+			page["Contents"] = append(contents, end)
+		}
+		u.set(pageRef, page)
+	}
+
+	if root, ok := d.trailer["Root"].(pdfRef); ok {
+		catalog := copyDict(d.catalog())
+		delete(catalog, "AcroForm")
+		u.set(root, catalog)
+	}
+}
+
+// normalAppearance returns the normal appearance stream of the widget in its
+// current state.
+func (d *pdfDocument) normalAppearance(widget pdfDict) (pdfRef, *pdfStream) {
+	n := d.dict(widget["AP"])["N"]
+	if states, ok := d.resolve(n).(pdfDict); ok {
+		n = states[d.name(widget["AS"])]
+	}
+	ref, ok := n.(pdfRef)
+	if !ok {
+		return pdfRef{}, nil
+	}
+	s, _ := d.resolve(ref).(*pdfStream)
+	return ref, s
+}
+
+// appearanceMatrix returns the scale and translation mapping the transformed
+// bounding box of the appearance stream onto the widget rectangle, like
+// viewers place appearances.
+func appearanceMatrix(d *pdfDocument, widget pdfDict, s *pdfStream) [4]float64 {
+	var rect, bbox [4]float64
+	for i, v := range d.array(widget["Rect"]) {
+		if i < 4 {
+			rect[i], _ = d.number(v)
+		}
+	}
+	for i, v := range d.array(s.dict["BBox"]) {
+		if i < 4 {
+			bbox[i], _ = d.number(v)
+		}
+	}
+	matrix := [6]float64{1, 0, 0, 1, 0, 0}
+	if arr := d.array(s.dict["Matrix"]); len(arr) == 6 {
+		for i, v := range arr {
+			matrix[i], _ = d.number(v)
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{bbox[0], bbox[1]}, {bbox[2], bbox[1]}, {bbox[0], bbox[3]}, {bbox[2], bbox[3]}} {
+		x := matrix[0]*p[0] + matrix[2]*p[1] + matrix[4]
+		y := matrix[1]*p[0] + matrix[3]*p[1] + matrix[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	sx, sy := 1.0, 1.0
+	if maxX > minX {
+		sx = abs(rect[2]-rect[0]) / (maxX - minX)
+	}
+	if maxY > minY {
+		sy = abs(rect[3]-rect[1]) / (maxY - minY)
+	}
+	x0, y0 := math.Min(rect[0], rect[2]), math.Min(rect[1], rect[3])
+	return [4]float64{sx, sy, x0 - minX*sx, y0 - minY*sy}
+}
@@ -0,0 +1,344 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestForm writes a one page PDF form with a text field, a checkbox, a
+// nested text field and a combo box and returns its path.
+func writeTestForm(t testing.TB, name string) string {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+	page := w.add(nil)
+
+	state := func(s string) pdfRef {
+		return w.add(&pdfStream{dict: pdfDict{"Subtype": pdfName("Form"), "BBox": pdfArray{0, 0, 10, 10}}, data: []byte(s)})
+	}
+	text := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+		"FT": pdfName("Tx"), "T": pdfString("name"), "TU": pdfString("Your name"),
+		"V": pdfString("Ann"), "MaxLen": 20, "Rect": pdfArray{72, 700, 272, 720},
+	})
+	checkbox := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+		"FT": pdfName("Btn"), "T": pdfString("agree"), "V": pdfName("Yes"), "AS": pdfName("Yes"),
+		"AP":   pdfDict{"N": pdfDict{"Yes": state("0 g"), "Off": state("")}},
+		"Rect": pdfArray{72, 660, 82, 670},
+	})
+	address := w.add(nil)
+	city := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page, "Parent": address,
+		"T": pdfString("city"), "V": pdfString("Berlin"), "Rect": pdfArray{72, 620, 272, 640},
+	})
+	w.set(address, pdfDict{"FT": pdfName("Tx"), "T": pdfString("address"), "Kids": pdfArray{city}})
+	combo := w.add(pdfDict{
+		"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+		"FT": pdfName("Ch"), "T": pdfString("color"), "Ff": fieldFlagCombo, "V": pdfString("g"),
+		"Opt":  pdfArray{pdfString("red"), pdfArray{pdfString("g"), pdfString("green")}},
+		"Rect": pdfArray{72, 580, 272, 600},
+	})
+
+	w.set(page, pdfDict{
+		"Type":      pdfName("Page"),
+		"Parent":    parent,
+		"MediaBox":  pdfArray{0, 0, 595, 842},
+		"Resources": helveticaResources(),
+		"Contents":  w.add(&pdfStream{dict: pdfDict{}, data: []byte("BT /F1 24 Tf 72 760 Td (Form) Tj ET")}),
+		"Annots":    pdfArray{text, checkbox, city, combo},
+	})
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{page}, "Count": 1})
+	root := w.add(pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": parent,
+		"AcroForm": pdfDict{
+			"Fields": pdfArray{text, checkbox, address, combo},
+			"DA":     pdfString("/Helv 0 Tf 0 g"),
+		},
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeStampPDF writes a PDF with n pages showing "Stamp <page>".
+func writeStampPDF(t testing.TB, name string, n int) string {
+	t.Helper()
+	pages := make([]pdfPage, n)
+	for i := range pages {
+		pages[i] = pdfPage{
+			width:     595,
+			height:    842,
+			content:   []byte(fmt.Sprintf("BT /F1 48 Tf 200 400 Td (Stamp %d) Tj ET", i+1)),
+			resources: helveticaResources(),
+		}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := writePDF(path, pages); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// parseTestPDF parses the PDF of the reader like NativeBackend does.
+func parseTestPDF(t testing.TB, r io.Reader) *pdfDocument {
+	t.Helper()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// pageText returns the joined content streams of the page.
+func pageText(t testing.TB, doc *pdfDocument, ref pdfRef) string {
+	t.Helper()
+	var b bytes.Buffer
+	for _, c := range doc.pageContents(doc.dict(ref)) {
+		s, ok := doc.resolve(c).(*pdfStream)
+		if !ok {
+			t.Fatalf("invalid content stream %v", c)
+		}
+		data, err := doc.streamData(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+	}
+	return b.String()
+}
+
+func TestNativeBackendFields(t *testing.T) {
+	form := writeTestForm(t, "form.pdf")
+
+	fields, err := GetFields(form, WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{
+		{Name: "name", AltName: "Your name", Type: FieldTypeText, Value: "Ann", MaxLength: 20},
+		{Name: "agree", Type: FieldTypeCheckbox, Value: "Yes", Options: []string{"Off", "Yes"}},
+		{Name: "address.city", Type: FieldTypeText, Value: "Berlin"},
+		{Name: "color", Type: FieldTypeComboBox, Flags: fieldFlagCombo, Value: "g", Options: []string{"red", "g"}},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields =\n%+v\nwant\n%+v", fields, want)
+	}
+}
+
+func TestNativeBackendMerge(t *testing.T) {
+	pages := writeTestPDF(t, "pages.pdf", 2)
+	form := writeTestForm(t, "form.pdf")
+
+	r, err := MergeWithOptions([]string{pages, form}, WithBackend(NativeBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseTestPDF(t, r)
+
+	refs := doc.pages()
+	if len(refs) != 3 {
+		t.Fatalf("pages = %d, want 3", len(refs))
+	}
+	for i, want := range []string{"(Page 1)", "(Page 2)", "(Form)"} {
+		if text := pageText(t, doc, refs[i]); !strings.Contains(text, want) {
+			t.Errorf("page %d = %q, want %s", i+1, text, want)
+		}
+	}
+
+	// The fields of the form are kept on its page.
+	var names []string
+	doc.walkFormFields(func(name string, f formField) {
+		names = append(names, name)
+		for _, w := range f.widgets {
+			if p := doc.dict(w)["P"]; p != refs[2] {
+				t.Errorf("widget of '%s' on %v, want %v", name, p, refs[2])
+			}
+		}
+	})
+	if want := []string{"name", "agree", "address.city", "color"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("fields = %q, want %q", names, want)
+	}
+}
+
+func TestNativeBackendMergeNoFiles(t *testing.T) {
+	err := NativeBackend{}.Merge(context.Background(), nil, filepath.Join(t.TempDir(), "out.pdf"))
+	if err == nil {
+		t.Error("merge of no files succeeded")
+	}
+}
+
+func TestNativeBackendStamp(t *testing.T) {
+	pages := writeTestPDF(t, "pages.pdf", 3)
+	stamp := writeStampPDF(t, "stamp.pdf", 2)
+
+	tests := []struct {
+		name       string
+		stamp      func(a, b string, opts ...Option) (io.Reader, error)
+		background bool
+		stamps     []string
+	}{
+		{"Stamp", Stamp, false, []string{"Stamp 1", "Stamp 1", "Stamp 1"}},
+		{"Multistamp", Multistamp, false, []string{"Stamp 1", "Stamp 2", "Stamp 2"}},
+		{"Background", Background, true, []string{"Stamp 1", "Stamp 1", "Stamp 1"}},
+		{"Multibackground", Multibackground, true, []string{"Stamp 1", "Stamp 2", "Stamp 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.stamp(pages, stamp, WithBackend(NativeBackend{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := parseTestPDF(t, r)
+
+			refs := doc.pages()
+			if len(refs) != 3 {
+				t.Fatalf("pages = %d, want 3", len(refs))
+			}
+			for i, ref := range refs {
+				text := pageText(t, doc, ref)
+				draw := strings.Index(text, "/FillPDFStamp Do")
+				page := strings.Index(text, fmt.Sprintf("(Page %d)", i+1))
+				if draw < 0 || page < 0 {
+					t.Fatalf("page %d = %q", i+1, text)
+				}
+				if tt.background != (draw < page) {
+					t.Errorf("page %d: stamp drawn at %d, page at %d", i+1, draw, page)
+				}
+
+				xobject, ok := doc.resolve(doc.dict(doc.dict(doc.dict(ref)["Resources"])["XObject"])["FillPDFStamp"]).(*pdfStream)
+				if !ok {
+					t.Fatalf("page %d has no stamp XObject", i+1)
+				}
+				if !bytes.Contains(xobject.data, []byte(tt.stamps[i])) {
+					t.Errorf("page %d stamp = %q, want %s", i+1, xobject.data, tt.stamps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNativeBackendStampTwice(t *testing.T) {
+	pages := writeTestPDF(t, "pages.pdf", 1)
+	stamp := writeStampPDF(t, "stamp.pdf", 1)
+	dir := t.TempDir()
+
+	first := filepath.Join(dir, "first.pdf")
+	second := filepath.Join(dir, "second.pdf")
+	if err := (NativeBackend{}).Stamp(context.Background(), pages, stamp, first, StampModeStamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := (NativeBackend{}).Stamp(context.Background(), first, stamp, second, StampModeStamp); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseTestPDF(t, bytes.NewReader(f))
+	text := pageText(t, doc, doc.pages()[0])
+	for _, want := range []string{"/FillPDFStamp Do", "/FillPDFStamp1 Do"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("page = %q, want %s", text, want)
+		}
+	}
+}
+
+// objectStreamPDF returns a PDF with an uncompressed object stream with the
+// /N and /First entries and the data.
+func objectStreamPDF(n, first, data string) []byte {
+	return []byte(fmt.Sprintf("%%PDF-1.5\n"+
+		"1 0 obj\n<< /Type /ObjStm /N %s /First %s /Length %d >>\nstream\n%s\nendstream\nendobj\n"+
+		"trailer\n<< /Root 2 0 R >>\n%%%%EOF\n", n, first, len(data), data))
+}
+
+func TestParseNativePDFObjectStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       string
+		first   string
+		data    string
+		wantErr bool
+	}{
+		{"valid", "1", "4", "2 0 << /Type /Catalog >>", false},
+		{"empty", "0", "0", "", false},
+		{"negative N", "-1", "4", "2 0 << /Type /Catalog >>", true},
+		{"huge N", "1000000000000", "4", "2 0 << /Type /Catalog >>", true},
+		{"N beyond data", "10", "4", "2 0 << /Type /Catalog >>", true},
+		{"fractional N", "0.5", "4", "2 0 << /Type /Catalog >>", true},
+		{"negative First", "1", "-4", "2 0 << /Type /Catalog >>", true},
+		{"First beyond data", "1", "1000", "2 0 << /Type /Catalog >>", true},
+		{"offset beyond data", "1", "4", "2 500 << /Type /Catalog >>", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseNativePDF(objectStreamPDF(tt.n, tt.first, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNativePDF() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.n == "1" && doc.name(doc.catalog()["Type"]) != "Catalog" {
+				t.Errorf("catalog = %v, want the object of the stream", doc.catalog())
+			}
+		})
+	}
+}
+
+func FuzzParseNativePDF(f *testing.F) {
+	for _, path := range []string{writeTestForm(f, "form.pdf"), writeTestPDF(f, "pages.pdf", 2)} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add(objectStreamPDF("1", "4", "2 0 << /Type /Catalog >>"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Length -1 >>\nstream\nx\nendstream\nendobj\ntrailer\n<< >>\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := parseNativePDF(data)
+		if err != nil {
+			return
+		}
+		doc.formFields()
+		doc.pages()
+	})
+}
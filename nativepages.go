@@ -0,0 +1,361 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// Merge implements MergeBackend. The pages of the files are concatenated and
+// the form fields of all files are kept, fields of the same name stay
+// separate fields. Outlines and other document level data are dropped.
+func (NativeBackend) Merge(ctx context.Context, files []string, outputFile string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no files to merge")
+	}
+
+	w := &pdfWriter{}
+	parent := w.add(nil)
+
+	kids := pdfArray{}
+	fields := pdfArray{}
+	acroForm := pdfDict{}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		doc, err := parseNativePDF(data)
+		if err != nil {
+			return fmt.Errorf("'%s': %w", f, err)
+		}
+		if doc.hasXFA() {
+			return fmt.Errorf("'%s': %w", f, ErrXFAForm)
+		}
+
+		imp := newPDFImporter(doc, w)
+		refs := doc.pages()
+
+		// Reserve the page numbers first, so the annotations referencing
+		// their page are imported with the new page.
+		for _, ref := range refs {
+			imp.refs[ref.num] = w.add(nil)
+		}
+		for _, ref := range refs {
+			w.set(imp.refs[ref.num], imp.importPage(ref, parent))
+			kids = append(kids, imp.refs[ref.num])
+		}
+
+		acro := doc.dict(doc.catalog()["AcroForm"])
+		for _, field := range doc.array(acro["Fields"]) {
+			fields = append(fields, imp.importValue(field))
+		}
+		// The first form provides the default appearance and resources.
+		for _, key := range []pdfName{"DA", "DR", "Q"} {
+			if _, ok := acroForm[key]; !ok && acro[key] != nil {
+				acroForm[key] = imp.importValue(acro[key])
+			}
+		}
+	}
+	w.set(parent, pdfDict{
+		"Type":  pdfName("Pages"),
+		"Kids":  kids,
+		"Count": len(kids),
+	})
+
+	catalog := pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": parent,
+	}
+	if len(fields) > 0 {
+		acroForm["Fields"] = fields
+		catalog["AcroForm"] = acroForm
+	}
+
+	data, err := w.bytes(w.add(catalog))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputFile, data, 0644)
+}
+
+// Stamp implements StampBackend. The stamp pages are scaled to fit the
+// pages, keeping their aspect ratio, and centered. Annotations of the stamp
+// pages are dropped.
+func (NativeBackend) Stamp(ctx context.Context, pdfFile, stampFile, outputFile string, mode StampMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(pdfFile)
+	if err != nil {
+		return err
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		return err
+	}
+
+	stampData, err := ioutil.ReadFile(stampFile)
+	if err != nil {
+		return err
+	}
+	stampDoc, err := parseNativePDF(stampData)
+	if err != nil {
+		return fmt.Errorf("stamp: %w", err)
+	}
+	stampRefs := stampDoc.pages()
+	if len(stampRefs) == 0 {
+		return fmt.Errorf("stamp PDF has no pages")
+	}
+
+	var background, multi bool
+	switch mode {
+	case StampModeStamp:
+	case StampModeMultistamp:
+		multi = true
+	case StampModeBackground:
+		background = true
+	case StampModeMultibackground:
+		background, multi = true, true
+	default:
+		return fmt.Errorf("invalid stamp mode: '%s'", mode)
+	}
+
+	u := doc.update()
+	imp := newPDFImporter(stampDoc, u)
+	xobjects := make(map[int]pdfRef)
+
+	// The original content is wrapped into a graphics state, so changes of
+	// it don't affect the stamp.
+	begin := u.add(&pdfStream{dict: pdfDict{}, data: []byte("q\n")})
+	end := u.add(&pdfStream{dict: pdfDict{}, data: []byte("\nQ")})
+
+	for i, ref := range doc.pages() {
+		// Like pdftk, the last stamp page is repeated for the remaining
+		// pages.
+		n := 0
+		if multi {
+			n = i
+			if n >= len(stampRefs) {
+				n = len(stampRefs) - 1
+			}
+		}
+		xobject, ok := xobjects[n]
+		if !ok {
+			stream, err := imp.formXObject(stampRefs[n])
+			if err != nil {
+				return fmt.Errorf("stamp page %d: %w", n+1, err)
+			}
+			xobject = u.add(stream)
+			xobjects[n] = xobject
+		}
+
+		page := copyDict(doc.dict(ref))
+		resources := copyDict(doc.dict(doc.inherited(page, "Resources")))
+		xobjectResources := copyDict(doc.dict(resources["XObject"]))
+		name := pdfName("FillPDFStamp")
+		for j := 1; xobjectResources[name] != nil; j++ {
+			name = pdfName(fmt.Sprintf("FillPDFStamp%d", j))
+		}
+		xobjectResources[name] = xobject
+		resources["XObject"] = xobjectResources
+		page["Resources"] = resources
+
+		// Fit the stamp box into the page box.
+		box := doc.mediaBox(page)
+		stampBox := stampDoc.mediaBox(stampDoc.dict(stampRefs[n]))
+		scale := math.Min((box[2]-box[0])/(stampBox[2]-stampBox[0]), (box[3]-box[1])/(stampBox[3]-stampBox[1]))
+		if math.IsInf(scale, 0) || math.IsNaN(scale) || scale <= 0 {
+			scale = 1
+		}
+		x := box[0] + ((box[2]-box[0])-(stampBox[2]-stampBox[0])*scale)/2 - stampBox[0]*scale
+		y := box[1] + ((box[3]-box[1])-(stampBox[3]-stampBox[1])*scale)/2 - stampBox[1]*scale
+		draw := u.add(&pdfStream{dict: pdfDict{}, data: []byte(fmt.Sprintf("\nq %s 0 0 %s %s %s cm /%s Do Q\n",
+			pdfNumber(scale), pdfNumber(scale), pdfNumber(x), pdfNumber(y), name))})
+
+		contents := append(pdfArray{begin}, doc.pageContents(page)...)
+		contents = append(contents, end)
+		if background {
+			contents = append(pdfArray{draw}, contents...)
+		} else {
+			contents = append(contents, draw)
+		}
+		page["Contents"] = contents
+
+		u.set(ref, page)
+	}
+
+	return u.writeFile(outputFile)
+}
+
+// pageContents returns the content streams of the page.
+func (d *pdfDocument) pageContents(page pdfDict) pdfArray {
+	switch c := page["Contents"].(type) {
+	case pdfRef:
+		if arr := d.array(c); arr != nil {
+			return append(pdfArray{}, arr...)
+		}
+		return pdfArray{c}
+	case pdfArray:
+		return append(pdfArray{}, c...)
+	}
+	return pdfArray{}
+}
+
+// mediaBox returns the inherited media box of the page.
+func (d *pdfDocument) mediaBox(page pdfDict) [4]float64 {
+	box := d.array(d.inherited(page, "MediaBox"))
+	var r [4]float64
+	for i := 0; i < 4 && i < len(box); i++ {
+		r[i], _ = d.number(box[i])
+	}
+	return r
+}
+
+// pdfObjectAdder adds indirect objects to a new document or an update.
+type pdfObjectAdder interface {
+	add(v interface{}) pdfRef
+	set(ref pdfRef, v interface{})
+}
+
+// pdfImporter copies objects of a document to another one, renumbering the
+// references. Every object is copied once.
+type pdfImporter struct {
+	doc  *pdfDocument
+	dst  pdfObjectAdder
+	refs map[int]pdfRef
+}
+
+func newPDFImporter(doc *pdfDocument, dst pdfObjectAdder) *pdfImporter {
+	return &pdfImporter{doc: doc, dst: dst, refs: make(map[int]pdfRef)}
+}
+
+// importValue returns a copy of v with the referenced objects imported.
+func (i *pdfImporter) importValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case pdfRef:
+		return i.importRef(t)
+	case pdfDict:
+		c := make(pdfDict, len(t))
+		for k, e := range t {
+			c[k] = i.importValue(e)
+		}
+		return c
+	case pdfArray:
+		c := make(pdfArray, len(t))
+		for j, e := range t {
+			c[j] = i.importValue(e)
+		}
+		return c
+	case *pdfStream:
+		// The length is set when writing the stream.
+		dict := copyDict(t.dict)
+		delete(dict, "Length")
+		return &pdfStream{dict: i.importValue(dict).(pdfDict), data: t.data}
+	}
+	return v
+}
+
+// importRef imports the referenced object and returns its new reference.
+func (i *pdfImporter) importRef(ref pdfRef) pdfRef {
+	if r, ok := i.refs[ref.num]; ok {
+		return r
+	}
+	r := i.dst.add(nil)
+	i.refs[ref.num] = r
+	i.dst.set(r, i.importValue(i.doc.objects[ref.num]))
+	return r
+}
+
+// importPage returns a copy of the page for the page tree parent. Inherited
+// attributes are copied into the page.
+func (i *pdfImporter) importPage(ref, parent pdfRef) pdfDict {
+	page := copyDict(i.doc.dict(ref))
+	for _, key := range []pdfName{"Resources", "MediaBox", "CropBox", "Rotate"} {
+		if v := i.doc.inherited(page, key); v != nil {
+			page[key] = v
+		}
+	}
+	delete(page, "Parent")
+
+	imported := i.importValue(page).(pdfDict)
+	imported["Parent"] = parent
+	return imported
+}
+
+// formXObject returns the page as form XObject with its media box as
+// bounding box. A single content stream is copied as is, several ones are
+// decoded and joined.
+func (i *pdfImporter) formXObject(ref pdfRef) (*pdfStream, error) {
+	d := i.doc
+	page := d.dict(ref)
+	box := d.mediaBox(page)
+
+	dict := pdfDict{
+		"Type":     pdfName("XObject"),
+		"Subtype":  pdfName("Form"),
+		"BBox":     pdfArray{box[0], box[1], box[2], box[3]},
+		"FormType": 1,
+	}
+	if resources := d.inherited(page, "Resources"); resources != nil {
+		dict["Resources"] = i.importValue(resources)
+	}
+
+	var data []byte
+	contents := d.pageContents(page)
+	if len(contents) == 1 {
+		s, ok := d.resolve(contents[0]).(*pdfStream)
+		if !ok {
+			return nil, fmt.Errorf("invalid content stream")
+		}
+		for _, key := range []pdfName{"Filter", "DecodeParms"} {
+			if v, ok := s.dict[key]; ok {
+				dict[key] = i.importValue(v)
+			}
+		}
+		data = s.data
+	} else {
+		var b bytes.Buffer
+		for _, c := range contents {
+			s, ok := d.resolve(c).(*pdfStream)
+			if !ok {
+				return nil, fmt.Errorf("invalid content stream")
+			}
+			cd, err := d.streamData(s)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(cd)
+			b.WriteByte('\n')
+		}
+		data = b.Bytes()
+	}
+
+	return &pdfStream{dict: dict, data: data}, nil
+}
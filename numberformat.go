@@ -0,0 +1,150 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat describes how numeric form values are written to the fields.
+type NumberFormat struct {
+	DecimalSeparator string
+	GroupSeparator   string
+	// Decimals is the number of decimal places of floats.
+	// -1 uses the smallest number of places necessary.
+	Decimals int
+}
+
+// localeNumberFormats are the number formats of the supported locales.
+// Regional variants fall back to their language. Locales grouping digits by
+// spaces use a no-break space, which is available in the standard fonts.
+var localeNumberFormats = map[string]NumberFormat{
+	"en":    {".", ",", -1},
+	"de":    {",", ".", -1},
+	"de-ch": {".", "\u2019", -1},
+	"fr":    {",", "\u00a0", -1},
+	"it":    {",", ".", -1},
+	"es":    {",", ".", -1},
+	"pt":    {",", ".", -1},
+	"nl":    {",", ".", -1},
+	"da":    {",", ".", -1},
+	"sv":    {",", "\u00a0", -1},
+	"nb":    {",", "\u00a0", -1},
+	"fi":    {",", "\u00a0", -1},
+	"pl":    {",", "\u00a0", -1},
+	"cs":    {",", "\u00a0", -1},
+	"ru":    {",", "\u00a0", -1},
+	"ja":    {".", ",", -1},
+	"zh":    {".", ",", -1},
+}
+
+// LocaleNumberFormat returns the number format of a locale like "de" or
+// "de-CH". Unknown regions fall back to the format of their language.
+func LocaleNumberFormat(locale string) (NumberFormat, error) {
+	key := strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if f, ok := localeNumberFormats[key]; ok {
+		return f, nil
+	}
+	if i := strings.Index(key, "-"); i > 0 {
+		if f, ok := localeNumberFormats[key[:i]]; ok {
+			return f, nil
+		}
+	}
+	return NumberFormat{}, fmt.Errorf("unsupported locale: '%s'", locale)
+}
+
+// Format formats an integer or float value. ok is false for other types.
+func (f NumberFormat) Format(value interface{}) (s string, ok bool) {
+	switch v := value.(type) {
+	case int:
+		s = strconv.FormatInt(int64(v), 10)
+	case int8:
+		s = strconv.FormatInt(int64(v), 10)
+	case int16:
+		s = strconv.FormatInt(int64(v), 10)
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'f', f.Decimals, 32)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', f.Decimals, 64)
+	default:
+		return "", false
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	// Insert the group separator every three digits.
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(f.GroupSeparator)
+		}
+		b.WriteRune(c)
+	}
+	if fracPart != "" {
+		b.WriteString(f.DecimalSeparator)
+		b.WriteString(fracPart)
+	}
+	return b.String(), true
+}
+
+// formatNumbers returns a copy of the form with all numeric values formatted
+// by the field's number format or the locale format.
+func formatNumbers(form Form, locale *NumberFormat, fields map[string]NumberFormat) Form {
+	formatted := make(Form, len(form))
+	for key, value := range form {
+		formatted[key] = value
+
+		f, ok := fields[key]
+		if !ok {
+			if locale == nil {
+				continue
+			}
+			f = *locale
+		}
+		if s, ok := f.Format(value); ok {
+			formatted[key] = s
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,108 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocaleNumberFormat(t *testing.T) {
+	tests := []struct {
+		locale  string
+		want    NumberFormat
+		wantErr bool
+	}{
+		{locale: "en", want: NumberFormat{".", ",", -1}},
+		{locale: "de", want: NumberFormat{",", ".", -1}},
+		{locale: "de-AT", want: NumberFormat{",", ".", -1}},
+		{locale: "de_CH", want: NumberFormat{".", "\u2019", -1}},
+		{locale: "FR", want: NumberFormat{",", "\u00a0", -1}},
+		{locale: "xx", wantErr: true},
+		{locale: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		f, err := LocaleNumberFormat(tt.locale)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("LocaleNumberFormat(%q) error = %v, want error %v", tt.locale, err, tt.wantErr)
+			continue
+		}
+		if f != tt.want {
+			t.Errorf("LocaleNumberFormat(%q) = %+v, want %+v", tt.locale, f, tt.want)
+		}
+	}
+}
+
+func TestNumberFormatFormat(t *testing.T) {
+	de := NumberFormat{",", ".", -1}
+	de2 := NumberFormat{",", ".", 2}
+	fr := NumberFormat{",", "\u00a0", -1}
+
+	tests := []struct {
+		format NumberFormat
+		value  interface{}
+		want   string
+		ok     bool
+	}{
+		{de, 0, "0", true},
+		{de, 999, "999", true},
+		{de, 1000, "1.000", true},
+		{de, -1234567, "-1.234.567", true},
+		{de, int64(1234567890), "1.234.567.890", true},
+		{de, uint8(255), "255", true},
+		{de, 1234.5, "1.234,5", true},
+		{de2, 1234.5, "1.234,50", true},
+		{de2, -0.125, "-0,12", true},
+		{de, float32(2.5), "2,5", true},
+		{fr, 1234567.25, "1\u00a0234\u00a0567,25", true},
+		{de, "1234", "", false},
+		{de, true, "", false},
+	}
+	for _, tt := range tests {
+		s, ok := tt.format.Format(tt.value)
+		if s != tt.want || ok != tt.ok {
+			t.Errorf("%+v.Format(%#v) = %q, %v, want %q, %v", tt.format, tt.value, s, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatNumbers(t *testing.T) {
+	de := NumberFormat{",", ".", -1}
+	form := Form{"amount": 1234.5, "count": 1000, "name": "1000", "checked": true}
+
+	tests := []struct {
+		name   string
+		locale *NumberFormat
+		fields map[string]NumberFormat
+		want   Form
+	}{
+		{"none", nil, nil, form},
+		{"locale", &de, nil, Form{"amount": "1.234,5", "count": "1.000", "name": "1000", "checked": true}},
+		{"field", nil, map[string]NumberFormat{"amount": {".", "", 2}}, Form{"amount": "1234.50", "count": 1000, "name": "1000", "checked": true}},
+		{"field and locale", &de, map[string]NumberFormat{"count": {".", "", -1}}, Form{"amount": "1.234,5", "count": "1000", "name": "1000", "checked": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatNumbers(form, tt.locale, tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatNumbers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
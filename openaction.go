@@ -0,0 +1,107 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+)
+
+// SetOpenPage sets the /OpenAction of the input PDF, so viewers open it at
+// the given page (starting at 1), and writes the result to output. zoom is
+// the magnification factor, e.g. 1.5 for 150%. A zoom <= 0 keeps the zoom
+// of the viewer. WithTempDir applies like for a fill.
+func SetOpenPage(input, output string, page int, zoom float64, opts ...Option) error {
+	doc, err := loadPDFFile(input)
+	if err != nil {
+		return err
+	}
+
+	u, err := openPageUpdate(doc, page, zoom)
+	if err != nil {
+		return err
+	}
+	return u.save(output, newOptions(opts).tempDir)
+}
+
+// openPageUpdate sets the /OpenAction like SetOpenPage.
+func openPageUpdate(doc *pdfDocument, page int, zoom float64) (*pdfUpdate, error) {
+	pages := doc.pages()
+	if page < 1 || page > len(pages) {
+		return nil, fmt.Errorf("invalid page %d: document has %d pages", page, len(pages))
+	}
+
+	root, ok := doc.trailer["Root"].(pdfRef)
+	if !ok {
+		return nil, fmt.Errorf("invalid PDF: missing document catalog")
+	}
+
+	// Keep the position on the page and optionally set the zoom.
+	var z interface{}
+	if zoom > 0 {
+		z = zoom
+	}
+
+	u := doc.update()
+	catalog := copyDict(doc.catalog())
+	catalog["OpenAction"] = pdfArray{pages[page-1], pdfName("XYZ"), nil, nil, z}
+	u.set(root, catalog)
+	return u, nil
+}
+
+// GetOpenPage returns the page (starting at 1) and zoom the PDF opens at.
+// page is 0 if the document does not define an explicit open page. zoom is
+// 0 if the zoom of the viewer is kept.
+func GetOpenPage(pdfFile string) (page int, zoom float64, err error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	page, zoom = doc.openPage()
+	return page, zoom, nil
+}
+
+// openPage returns the page and zoom like GetOpenPage.
+func (d *pdfDocument) openPage() (page int, zoom float64) {
+	// The open action is either a destination or a GoTo action.
+	action := d.catalog()["OpenAction"]
+	dest := d.array(action)
+	if dict := d.dict(action); dict != nil && d.name(dict["S"]) == "GoTo" {
+		dest = d.array(dict["D"])
+	}
+	if len(dest) == 0 {
+		return 0, 0
+	}
+
+	ref, ok := dest[0].(pdfRef)
+	if !ok {
+		return 0, 0
+	}
+	for i, p := range d.pages() {
+		if p.num == ref.num {
+			page = i + 1
+			break
+		}
+	}
+	if len(dest) == 5 && d.name(dest[1]) == "XYZ" {
+		zoom, _ = d.number(dest[4])
+	}
+	return page, zoom
+}
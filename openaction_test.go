@@ -0,0 +1,133 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenPageUpdate(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		page     int
+		zoom     float64
+		wantZoom float64
+		wantErr  bool
+	}{
+		{1, 0, 0, false},
+		{2, 1.5, 1.5, false},
+		{3, -1, 0, false},
+		{0, 1, 0, true},
+		{4, 1, 0, true},
+		{-1, 1, 0, true},
+	}
+	for _, tt := range tests {
+		doc, err := parseNativePDF(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page, zoom := doc.openPage(); page != 0 || zoom != 0 {
+			t.Fatalf("open page of the test PDF = %d, %v, want none", page, zoom)
+		}
+
+		u, err := openPageUpdate(doc, tt.page, tt.zoom)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("openPageUpdate(%d, %v) error = %v, want error %v", tt.page, tt.zoom, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		out, err := u.bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc, err = parseNativePDF(out); err != nil {
+			t.Fatal(err)
+		}
+		if page, zoom := doc.openPage(); page != tt.page || zoom != tt.wantZoom {
+			t.Errorf("open page after openPageUpdate(%d, %v) = %d, %v, want %d, %v", tt.page, tt.zoom, page, zoom, tt.page, tt.wantZoom)
+		}
+	}
+}
+
+func TestOpenPageGoTo(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog := copyDict(doc.catalog())
+	catalog["OpenAction"] = pdfDict{
+		"S": pdfName("GoTo"),
+		"D": pdfArray{doc.pages()[2], pdfName("XYZ"), nil, nil, 2.0},
+	}
+	doc.objects[doc.trailer["Root"].(pdfRef).num] = catalog
+	if page, zoom := doc.openPage(); page != 3 || zoom != 2 {
+		t.Errorf("open page of the GoTo action = %d, %v, want 3, 2", page, zoom)
+	}
+}
+
+func TestSetOpenPage(t *testing.T) {
+	requirePDFTK(t)
+	input := writeTestPDF(t, "pages.pdf", 3)
+	output := filepath.Join(t.TempDir(), "open.pdf")
+
+	if err := SetOpenPage(input, output, 2, 1.25); err != nil {
+		t.Fatal(err)
+	}
+	if page, zoom, err := GetOpenPage(output); err != nil || page != 2 || zoom != 1.25 {
+		t.Errorf("GetOpenPage() = %d, %v, %v, want 2, 1.25", page, zoom, err)
+	}
+
+	if err := SetOpenPage(input, output, 4, 0); err == nil {
+		t.Error("SetOpenPage beyond the last page succeeded")
+	}
+}
+
+func TestSetOpenPageTempDir(t *testing.T) {
+	input := writeTestPDF(t, "pages.pdf", 3)
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &recordExecutor{stdout: data, output: []byte("%PDF-open")}
+	useExecutor(t, e)
+
+	dir := t.TempDir()
+	output := filepath.Join(t.TempDir(), "open.pdf")
+	if err := SetOpenPage(input, output, 2, 0, WithTempDir(dir)); err != nil {
+		t.Fatal(err)
+	}
+	checkOutputsIn(t, e.calls, dir)
+	if data, err := ioutil.ReadFile(output); err != nil || string(data) != "%PDF-open" {
+		t.Errorf("output = %q, %v, want the pdftk output", data, err)
+	}
+}
@@ -0,0 +1,427 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Option configures optional behavior of Fill and FillPDFToBytes.
+type Option func(*options)
+
+type options struct {
+	flatten         bool
+	flattenSkipped  *bool
+	looseFieldNames bool
+	pageFieldNames  bool
+	utf8FDF         bool
+	xfdf            bool
+	hexFDF          bool
+	tempDir         string
+	dumpFDF         io.Writer
+	validateFields  bool
+	password        string
+	output          OutputOptions
+	needAppearances bool
+	validate        bool
+	locale          string
+	numberFormats   map[string]NumberFormat
+	progress        func(written int64)
+	backend         Backend
+
+	dropUnusedFields bool
+	keepFields       []string
+	info             map[string]string
+	dropXFA          bool
+	repair           bool
+
+	// checkedString, uncheckedString and overwrite are the settings of
+	// FillFile, which the functions taking them as parameters ignore.
+	checkedString   string
+	uncheckedString string
+	overwrite       bool
+
+	// patchNeedAppearances is set by runFill, if the output needs the
+	// NeedAppearances flag set by this package.
+	patchNeedAppearances bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		flatten:         true,
+		checkedString:   "Yes",
+		uncheckedString: "Off",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFlatten controls whether the filled form is flattened, which is the
+// default. Without flattening the fields stay editable. Combine it with
+// WithNeedAppearances, so all values show up correctly.
+func WithFlatten(flatten bool) Option {
+	return func(o *options) {
+		o.flatten = flatten
+	}
+}
+
+// WithFlattenFallback retries the fill without flattening if pdftk fails to
+// flatten the form, instead of returning the error. skipped is set to true
+// whenever the output was not flattened, so callers know about it.
+func WithFlattenFallback(skipped *bool) Option {
+	return func(o *options) {
+		o.flattenSkipped = skipped
+	}
+}
+
+// WithNeedAppearances sets the NeedAppearances flag of forms which are not
+// flattened, so viewers regenerate the field appearances and show the values
+// immediately. The pdftk need_appearances option is used, if supported, else
+// the flag is set in the output afterwards. NativeBackend always sets the flag
+// of forms it does not flatten. Flattened output is not affected.
+func WithNeedAppearances() Option {
+	return func(o *options) {
+		o.needAppearances = true
+	}
+}
+
+// WithEditable keeps the filled form interactive, so the values can be
+// reviewed and adjusted in a viewer. It is the same as WithFlatten(false)
+// together with WithNeedAppearances.
+func WithEditable() Option {
+	return func(o *options) {
+		o.flatten = false
+		o.needAppearances = true
+	}
+}
+
+// WithLooseFieldNames matches the form keys to the template fields ignoring
+// case and surrounding whitespace. The field names are read from the template
+// before the fill and an error is returned for ambiguous matches.
+func WithLooseFieldNames() Option {
+	return func(o *options) {
+		o.looseFieldNames = true
+	}
+}
+
+// WithValidation checks the generated PDF with ValidatePDF before it is
+// returned. This costs an additional pdftk run per fill.
+func WithValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// WithFieldValidation checks the form against the fields of the template
+// before the fill and fails with a *FormError listing all form keys without
+// matching field, all required fields without value and all values of combo
+// and list boxes, which are none of their options. XFA forms without AcroForm
+// fields fail with ErrXFAForm. This costs an additional pdftk run per fill.
+func WithFieldValidation() Option {
+	return func(o *options) {
+		o.validateFields = true
+	}
+}
+
+// WithInputPassword opens a password protected template with the password.
+// The password is never part of returned errors.
+func WithInputPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+// WithOutputOptions encrypts the generated PDF. See OutputOptions.
+func WithOutputOptions(output OutputOptions) Option {
+	return func(o *options) {
+		o.output = output
+	}
+}
+
+// WithLocale formats integer and float values with the decimal and group
+// separators of the locale, e.g. "de" writes 1234.5 as "1.234,5".
+// See LocaleNumberFormat for the supported locales.
+func WithLocale(locale string) Option {
+	return func(o *options) {
+		o.locale = locale
+	}
+}
+
+// WithNumberFormat sets the number format of a single field, overriding
+// the format of the locale.
+func WithNumberFormat(field string, format NumberFormat) Option {
+	return func(o *options) {
+		if o.numberFormats == nil {
+			o.numberFormats = make(map[string]NumberFormat)
+		}
+		o.numberFormats[field] = format
+	}
+}
+
+// WithUTF8FDF passes the form data to pdftk as UTF-8 encoded XFDF instead of
+// UTF-16 encoded FDF, which is easier to debug. pdftk builds without UTF-8
+// support, detected by the missing *_utf8 operations, get the UTF-16 FDF.
+func WithUTF8FDF() Option {
+	return func(o *options) {
+		o.utf8FDF = true
+	}
+}
+
+// WithXFDF always passes the form data to pdftk as UTF-8 encoded XFDF, with
+// XML escaped field names and values. Unlike WithUTF8FDF it does not fall
+// back to FDF, so it avoids the UTF-16 encoding for all pdftk builds. FDF
+// stays the default for compatibility with older pdftk versions.
+func WithXFDF() Option {
+	return func(o *options) {
+		o.xfdf = true
+	}
+}
+
+// WithInfo sets the document info entries like "Title" or "Author" of the
+// filled PDF, instead of keeping the ones of the template. Entries not given
+// are kept.
+func WithInfo(info map[string]string) Option {
+	return func(o *options) {
+		o.info = info
+	}
+}
+
+// WithDropXFA removes the XFA data of the filled form, so viewers show the
+// filled AcroForm fields instead of the empty XFA form.
+func WithDropXFA() Option {
+	return func(o *options) {
+		o.dropXFA = true
+	}
+}
+
+// WithRepair repairs the template with pdftk like Repair and fills it again,
+// if pdftk fails to parse it, e.g. slightly broken templates of customers.
+func WithRepair() Option {
+	return func(o *options) {
+		o.repair = true
+	}
+}
+
+// WithHexFDF writes the field names and values of the FDF data file as hex
+// strings instead of escaped string literals, e.g. to debug values with
+// unusual characters. It has no effect together with WithXFDF.
+func WithHexFDF() Option {
+	return func(o *options) {
+		o.hexFDF = true
+	}
+}
+
+// WithTempDir creates the temporary working directory of the call in dir
+// instead of the directory set with SetTempDir. The working directory is
+// removed again when the call returns.
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// WithCheckedString sets the value of checked checkboxes of FillFile, "Yes" by
+// default.
+func WithCheckedString(checkedString string) Option {
+	return func(o *options) {
+		o.checkedString = checkedString
+	}
+}
+
+// WithUncheckedString sets the value of unchecked checkboxes of FillFile, "Off"
+// by default.
+func WithUncheckedString(uncheckedString string) Option {
+	return func(o *options) {
+		o.uncheckedString = uncheckedString
+	}
+}
+
+// WithOverwrite lets FillFile replace an existing destination file.
+func WithOverwrite() Option {
+	return func(o *options) {
+		o.overwrite = true
+	}
+}
+
+// WithDumpFDF writes a copy of the FDF or XFDF data file passed to pdftk to
+// w, e.g. to inspect the field names and values of an unexpected fill.
+func WithDumpFDF(w io.Writer) Option {
+	return func(o *options) {
+		o.dumpFDF = w
+	}
+}
+
+// WithProgress calls progress with the total number of bytes written so far,
+// whenever the functions writing to an io.Writer write a chunk of output,
+// like FillToWriter, MergeToWriter and MultistampToWriter.
+func WithProgress(progress func(written int64)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// progressWriter wraps w to report the progress, if requested.
+func (o *options) progressWriter(w io.Writer) io.Writer {
+	if o.progress == nil {
+		return w
+	}
+	return &progressWriter{w: w, progress: o.progress}
+}
+
+// progressWriter counts the bytes written to w.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	progress func(written int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written)
+	return n, err
+}
+
+// prepareForm applies the options changing the form values or the template
+// before the FDF file is created. A modified template is written to
+// templateFile. The template to fill is returned.
+func (o *options) prepareForm(form Form, formPDFFile, templateFile string) (Form, string, error) {
+	var err error
+	if err := o.output.validate(); err != nil {
+		return nil, "", err
+	}
+
+	if form, err = qualifyFieldNames(form); err != nil {
+		return nil, "", err
+	}
+
+	if o.pageFieldNames {
+		if form, formPDFFile, err = splitPageFields(form, formPDFFile, templateFile, o.password); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if o.looseFieldNames {
+		names, err := dumpFieldNames(formPDFFile, o)
+		if err != nil {
+			return nil, "", err
+		}
+		if form, err = resolveFieldNames(form, names); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if o.validateFields {
+		fields, err := getFields(formPDFFile, o)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(fields) == 0 {
+			if doc, err := loadPDFFileWithPassword(formPDFFile, o.password); err == nil && doc.hasXFA() {
+				return nil, "", ErrXFAForm
+			}
+		}
+		if err := validateForm(form, fields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if o.dropUnusedFields || len(o.keepFields) > 0 {
+		keep := make(map[string]bool)
+		for _, name := range o.keepFields {
+			keep[name] = true
+		}
+		if o.dropUnusedFields {
+			for name := range form {
+				keep[name] = true
+			}
+		}
+		if formPDFFile, err = dropFields(keep, formPDFFile, templateFile, o.password); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(o.info) > 0 {
+		fb, err := updateInfo(formPDFFile, o.password, o.info, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := ioutil.WriteFile(templateFile, fb, 0644); err != nil {
+			return nil, "", err
+		}
+		formPDFFile = templateFile
+	}
+
+	if form, err = o.formatNumbers(form); err != nil {
+		return nil, "", err
+	}
+
+	return form, formPDFFile, nil
+}
+
+// formatNumbers applies the locale and number format options to the form.
+func (o *options) formatNumbers(form Form) (Form, error) {
+	if o.locale == "" && len(o.numberFormats) == 0 {
+		return form, nil
+	}
+
+	var locale *NumberFormat
+	if o.locale != "" {
+		f, err := LocaleNumberFormat(o.locale)
+		if err != nil {
+			return nil, err
+		}
+		locale = &f
+	}
+	return formatNumbers(form, locale, o.numberFormats), nil
+}
+
+// createDataFile writes the form data file passed to pdftk fill_form.
+func (o *options) createDataFile(form Form, path, checkedString, uncheckedString string) error {
+	var err error
+	if o.xfdf || (o.utf8FDF && supportsUTF8()) {
+		err = createXfdfFile(form, path, checkedString, uncheckedString)
+	} else {
+		err = createFdfFile(form, path, checkedString, uncheckedString, o.hexFDF)
+	}
+	if err != nil || o.dumpFDF == nil {
+		return err
+	}
+
+	// Pass a copy of the data file to the caller.
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(o.dumpFDF, f)
+	return err
+}
+
+// supportsUTF8 reports whether pdftk reads UTF-8 encoded form data.
+func supportsUTF8() bool {
+	ok, err := SupportsOperation("dump_data_fields_utf8")
+	return err == nil && ok
+}
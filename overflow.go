@@ -0,0 +1,326 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Layout constants approximating how viewers render text fields.
+const (
+	fieldPadding    = 2.0
+	fieldLineHeight = 1.15
+)
+
+// FontMetrics provides the glyph widths used to estimate how wide a value
+// renders in a field. Widths are returned in 1/1000 of the font size, like
+// in the PDF /Widths array. The font is identified by its /BaseFont name.
+// ok is false if the metrics don't know the font or glyph.
+type FontMetrics interface {
+	GlyphWidth(font string, r rune) (width float64, ok bool)
+}
+
+// Overflow describes a form value that does not fit into its field.
+type Overflow struct {
+	Field    string
+	Page     int
+	Value    string
+	FontSize float64
+
+	// TextWidth is the width of the value (or its widest word for multi-line
+	// fields) and BoxWidth the usable width of the field, both in points.
+	TextWidth float64
+	BoxWidth  float64
+
+	// Lines and MaxLines are only set for multi-line fields.
+	Lines    int
+	MaxLines int
+
+	Reason string
+}
+
+// DetectOverflow estimates for each value in the form, whenever it fits into
+// its field box at the field's font size. The field rectangles and default
+// appearances are read from the template. Fields using auto font size
+// (size 0) shrink the text to fit and are never reported.
+//
+// The glyph widths are looked up in metrics first, then in the /Widths of the
+// fonts embedded in the form and finally in StandardFontMetrics. Pass nil to
+// rely on the form fonts only.
+//
+// Nested forms are matched by their fully qualified field names like in Fill.
+// WithInputPassword opens a password protected template, the other options
+// are ignored.
+func DetectOverflow(form Form, formPDFFile string, metrics FontMetrics, opts ...Option) ([]Overflow, error) {
+	o := newOptions(opts)
+
+	form, err := qualifyFieldNames(form)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := loadPDFFileWithPassword(formPDFFile, o.password)
+	if err != nil {
+		return nil, err
+	}
+	return doc.detectOverflow(form, metrics), nil
+}
+
+// detectOverflow implements DetectOverflow for the parsed template.
+func (d *pdfDocument) detectOverflow(form Form, metrics FontMetrics) []Overflow {
+	chain := fontMetricsChain{}
+	if metrics != nil {
+		chain = append(chain, metrics)
+	}
+	chain = append(chain, d.formFontMetrics(), StandardFontMetrics)
+
+	fonts := d.formFonts()
+
+	var list []Overflow
+	for _, w := range d.widgets() {
+		if w.fieldType != "Tx" {
+			continue
+		}
+		value, ok := form[w.name]
+		if !ok {
+			continue
+		}
+		switch value.(type) {
+		case bool, Checkbox, *Checkbox:
+			continue
+		}
+
+		fontRes, size := parseDA(w.da)
+		if size <= 0 {
+			continue
+		}
+		o := checkOverflow(w, fmt.Sprintf("%v", value), fonts[fontRes], size, chain)
+		if o != nil {
+			list = append(list, *o)
+		}
+	}
+
+	return list
+}
+
+func checkOverflow(w pdfWidget, value, font string, size float64, metrics FontMetrics) *Overflow {
+	o := &Overflow{
+		Field:    w.name,
+		Page:     w.page,
+		Value:    value,
+		FontSize: size,
+		BoxWidth: w.width() - 2*fieldPadding,
+	}
+
+	// Viewers truncate values exceeding the maximum length.
+	if w.maxLen > 0 && len([]rune(value)) > w.maxLen {
+		o.Reason = fmt.Sprintf("value exceeds the maximum length of %d characters", w.maxLen)
+		return o
+	}
+	if w.flags&fieldFlagComb != 0 {
+		return nil
+	}
+
+	textWidth := func(s string) float64 {
+		return measureText(s, font, size, metrics)
+	}
+
+	if w.flags&fieldFlagMultiline == 0 {
+		o.TextWidth = textWidth(value)
+		if o.TextWidth <= o.BoxWidth {
+			return nil
+		}
+		o.Reason = fmt.Sprintf("text is %.1fpt wide, the field only %.1fpt", o.TextWidth, o.BoxWidth)
+		return o
+	}
+
+	// Wrap the words of each paragraph into lines.
+	space := textWidth(" ")
+	value = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(value)
+	for _, paragraph := range strings.Split(value, "\n") {
+		o.Lines++
+		lineWidth := 0.0
+		for _, word := range strings.Fields(paragraph) {
+			ww := textWidth(word)
+			o.TextWidth = math.Max(o.TextWidth, ww)
+			if lineWidth > 0 && lineWidth+space+ww > o.BoxWidth {
+				o.Lines++
+				lineWidth = 0
+			}
+			if lineWidth > 0 {
+				lineWidth += space
+			}
+			lineWidth += ww
+		}
+	}
+
+	o.MaxLines = int((w.height() - 2*fieldPadding) / (size * fieldLineHeight))
+	if o.MaxLines < 1 {
+		o.MaxLines = 1
+	}
+
+	switch {
+	case o.TextWidth > o.BoxWidth:
+		o.Reason = fmt.Sprintf("a word is %.1fpt wide, the field only %.1fpt", o.TextWidth, o.BoxWidth)
+	case o.Lines > o.MaxLines:
+		o.Reason = fmt.Sprintf("text needs %d lines, the field only fits %d", o.Lines, o.MaxLines)
+	default:
+		return nil
+	}
+	return o
+}
+
+// measureText returns the width of s in points.
+func measureText(s, font string, size float64, metrics FontMetrics) float64 {
+	width := 0.0
+	for _, r := range s {
+		w, _ := metrics.GlyphWidth(font, r)
+		width += w
+	}
+	return width * size / 1000
+}
+
+// fontMetricsChain asks each metrics in turn until one knows the glyph.
+type fontMetricsChain []FontMetrics
+
+func (c fontMetricsChain) GlyphWidth(font string, r rune) (float64, bool) {
+	for _, m := range c {
+		if w, ok := m.GlyphWidth(font, r); ok {
+			return w, true
+		}
+	}
+	return 0, false
+}
+
+// pdfFontMetrics holds the /Widths of simple fonts keyed by /BaseFont.
+type pdfFontMetrics map[string]pdfFontWidths
+
+type pdfFontWidths struct {
+	firstChar    int
+	widths       []float64
+	missingWidth float64
+}
+
+func (m pdfFontMetrics) GlyphWidth(font string, r rune) (float64, bool) {
+	f, ok := m[font]
+	if !ok {
+		return 0, false
+	}
+	if i := int(r) - f.firstChar; i >= 0 && i < len(f.widths) && r < 256 {
+		return f.widths[i], true
+	}
+	if f.missingWidth > 0 {
+		return f.missingWidth, true
+	}
+	return 0, false
+}
+
+// formFonts maps the font resource names of the AcroForm /DR to base font names.
+func (d *pdfDocument) formFonts() map[string]string {
+	fonts := make(map[string]string)
+	if a := d.formAppearance(); a != nil {
+		for _, f := range a.Fonts {
+			fonts[f.Name] = f.BaseFont
+		}
+	}
+	return fonts
+}
+
+// formFontMetrics collects the widths of all simple fonts in the AcroForm /DR.
+func (d *pdfDocument) formFontMetrics() pdfFontMetrics {
+	m := pdfFontMetrics{}
+	acro := d.dict(d.catalog()["AcroForm"])
+	for _, v := range d.dict(d.dict(acro["DR"])["Font"]) {
+		font := d.dict(v)
+		widths := d.array(font["Widths"])
+		if len(widths) == 0 {
+			continue
+		}
+		f := pdfFontWidths{}
+		if n, ok := d.number(font["FirstChar"]); ok {
+			f.firstChar = int(n)
+		}
+		for _, w := range widths {
+			n, _ := d.number(w)
+			f.widths = append(f.widths, n)
+		}
+		if n, ok := d.number(d.dict(font["FontDescriptor"])["MissingWidth"]); ok {
+			f.missingWidth = n
+		}
+		m[string(d.name(font["BaseFont"]))] = f
+	}
+	return m
+}
+
+// StandardFontMetrics provides the widths of the standard 14 fonts for the
+// printable ASCII range. Unknown fonts are measured as Helvetica and other
+// characters with an average glyph width, so the result is always an estimate.
+var StandardFontMetrics FontMetrics = standardFontMetrics{}
+
+type standardFontMetrics struct{}
+
+func (standardFontMetrics) GlyphWidth(font string, r rune) (float64, bool) {
+	if strings.Contains(font, "Courier") {
+		return 600, true
+	}
+
+	widths := helveticaWidths
+	switch {
+	case strings.Contains(font, "Times"):
+		widths = timesRomanWidths
+	case strings.Contains(font, "Bold"):
+		widths = helveticaBoldWidths
+	}
+
+	if r >= 32 && r < 32+rune(len(widths)) {
+		return float64(widths[r-32]), true
+	}
+	return 556, true
+}
+
+// AFM widths of the characters 32 to 126.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [...]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+var timesRomanWidths = [...]int{
+	250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+	921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+	556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+	333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+	500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+}
@@ -0,0 +1,135 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeOverflowForm writes a form with text fields of a fixed font size: the
+// 100pt wide "name", the 200pt wide nested "address.city", the "code" with
+// at most 3 characters and the multi-line "notes" fitting 2 lines.
+func writeOverflowForm(t testing.TB, name string) string {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+	page := w.add(nil)
+
+	widget := func(d pdfDict) pdfRef {
+		d["Type"] = pdfName("Annot")
+		d["Subtype"] = pdfName("Widget")
+		d["P"] = page
+		d["DA"] = pdfString("/Helv 12 Tf 0 g")
+		return w.add(d)
+	}
+	text := widget(pdfDict{"FT": pdfName("Tx"), "T": pdfString("name"), "Rect": pdfArray{72, 700, 172, 720}})
+	address := w.add(nil)
+	city := widget(pdfDict{"Parent": address, "T": pdfString("city"), "Rect": pdfArray{72, 660, 272, 680}})
+	w.set(address, pdfDict{"FT": pdfName("Tx"), "T": pdfString("address"), "Kids": pdfArray{city}})
+	code := widget(pdfDict{"FT": pdfName("Tx"), "T": pdfString("code"), "MaxLen": 3, "Rect": pdfArray{72, 620, 272, 640}})
+	notes := widget(pdfDict{
+		"FT": pdfName("Tx"), "T": pdfString("notes"), "Ff": fieldFlagMultiline,
+		"Rect": pdfArray{72, 540, 272, 574},
+	})
+
+	w.set(page, pdfDict{
+		"Type":     pdfName("Page"),
+		"Parent":   parent,
+		"MediaBox": pdfArray{0, 0, 595, 842},
+		"Annots":   pdfArray{text, city, code, notes},
+	})
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{page}, "Count": 1})
+	root := w.add(pdfDict{
+		"Type":     pdfName("Catalog"),
+		"Pages":    parent,
+		"AcroForm": pdfDict{"Fields": pdfArray{text, address, code, notes}},
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetectOverflowFields(t *testing.T) {
+	data, err := ioutil.ReadFile(writeOverflowForm(t, "form.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		form Form
+		want []string
+	}{
+		{"fits", Form{"name": "Ann", "address.city": "Berlin", "code": "abc", "notes": "one\ntwo"}, nil},
+		{"too wide", Form{"name": "Annabelle Maximiliane Smith", "address.city": "Berlin"}, []string{"name"}},
+		{"nested", Form{"address": Form{"city": "Llanfairpwllgwyngyllgogerychwyndrobwllllantysiliogogogoch"}}, []string{"address.city"}},
+		{"max length", Form{"code": "abcd"}, []string{"code"}},
+		{"too many lines", Form{"notes": "one\ntwo\nthree"}, []string{"notes"}},
+		{"unknown and checkbox", Form{"missing": "x", "name": true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, err := qualifyFieldNames(tt.form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, o := range doc.detectOverflow(form, nil) {
+				got = append(got, o.Field)
+				if o.Reason == "" || o.Page != 1 || o.FontSize != 12 {
+					t.Errorf("overflow = %+v, want a reason, page 1 and font size 12", o)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overflowing fields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectOverflow(t *testing.T) {
+	requirePDFTK(t)
+	template := writeOverflowForm(t, "form.pdf")
+
+	list, err := DetectOverflow(Form{
+		"name":    "Annabelle Maximiliane Smith",
+		"address": Form{"city": "Berlin"},
+	}, template, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Field != "name" || list[0].TextWidth <= list[0].BoxWidth {
+		t.Errorf("overflows = %+v, want the name only", list)
+	}
+}
@@ -0,0 +1,200 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithPageFieldNames enables page qualified form keys like "date@2".
+//
+// A field shown on several pages is one field with one value, so a plain key
+// like "date" sets all its widgets. This is what templates repeating a field
+// on every page expect. If the widgets on a page should show a distinct
+// value, set it with the key "date@2" (pages start at 1). The widgets on that
+// page are split into a separate field for the fill, so "date" keeps setting
+// the widgets on all other pages. Distinct fields which only share their
+// partial name, like "page1.date" and "page2.date", are set by their fully
+// qualified names and need no page qualification.
+//
+// The split fields keep their qualified names in non-flattened output.
+func WithPageFieldNames() Option {
+	return func(o *options) {
+		o.pageFieldNames = true
+	}
+}
+
+// parsePageFieldName splits a page qualified key into the field name and page.
+func parsePageFieldName(key string) (name string, page int, ok bool) {
+	i := strings.LastIndexByte(key, '@')
+	if i <= 0 {
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(key[i+1:])
+	if err != nil || page < 1 {
+		return "", 0, false
+	}
+	return key[:i], page, true
+}
+
+// splitPageFields resolves the page qualified keys of the form. Widgets
+// addressed by them are split into separate fields and the modified template
+// is written to templateFile, decrypted if a password is given. The template
+// to fill is returned.
+func splitPageFields(form Form, formPDFFile, templateFile, password string) (Form, string, error) {
+	qualified := false
+	for key := range form {
+		if _, _, ok := parsePageFieldName(key); ok {
+			qualified = true
+			break
+		}
+	}
+	if !qualified {
+		return form, formPDFFile, nil
+	}
+
+	doc, err := loadPDFFileWithPassword(formPDFFile, password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	widgets := make(map[string][]pdfWidget)
+	for _, w := range doc.widgets() {
+		widgets[w.name] = append(widgets[w.name], w)
+	}
+
+	// Handle the plain keys first, so page qualified keys take precedence.
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		_, _, qi := parsePageFieldName(keys[i])
+		_, _, qj := parsePageFieldName(keys[j])
+		if qi != qj {
+			return qj
+		}
+		return keys[i] < keys[j]
+	})
+
+	u := doc.update()
+	resolved := make(Form, len(form))
+	for _, key := range keys {
+		name, page, ok := parsePageFieldName(key)
+		if _, exists := widgets[key]; !ok || exists {
+			resolved[key] = form[key]
+			continue
+		}
+
+		var onPage []pdfWidget
+		for _, w := range widgets[name] {
+			if w.page == page {
+				onPage = append(onPage, w)
+			}
+		}
+		if len(onPage) == 0 {
+			return nil, "", fmt.Errorf("form field '%s' has no widget on page %d", name, page)
+		}
+
+		// Nothing to split if the field is only shown on this page.
+		if len(onPage) == len(widgets[name]) {
+			resolved[name] = form[key]
+			continue
+		}
+
+		if err := doc.splitField(u, onPage, page); err != nil {
+			return nil, "", err
+		}
+		resolved[key] = form[key]
+	}
+
+	if err := u.writeFile(templateFile); err != nil {
+		return nil, "", err
+	}
+	return resolved, templateFile, nil
+}
+
+// splitField moves the widgets from their field into a new sibling field,
+// which has the page appended to its name.
+func (d *pdfDocument) splitField(u *pdfUpdate, widgets []pdfWidget, page int) error {
+	fieldRef, ok := widgets[0].dict["Parent"].(pdfRef)
+	if !ok {
+		return fmt.Errorf("invalid PDF: form field '%s' has no parent", widgets[0].name)
+	}
+	field := d.dict(fieldRef)
+
+	moved := make(map[int]bool)
+	newField := copyDict(field)
+	var kids pdfArray
+	for _, w := range widgets {
+		if w.ref.num == 0 {
+			return fmt.Errorf("invalid PDF: widget of form field '%s' is no indirect object", w.name)
+		}
+		moved[w.ref.num] = true
+		kids = append(kids, w.ref)
+	}
+	newField["Kids"] = kids
+	newField["T"] = encodeTextString(fmt.Sprintf("%s@%d", d.text(field["T"]), page))
+	newRef := u.add(newField)
+
+	for _, w := range widgets {
+		widget := copyDict(d.dict(w.ref))
+		widget["Parent"] = newRef
+		u.set(w.ref, widget)
+	}
+
+	// Remove the widgets from the old field.
+	var remaining pdfArray
+	for _, kid := range d.array(field["Kids"]) {
+		if ref, ok := kid.(pdfRef); !ok || !moved[ref.num] {
+			remaining = append(remaining, kid)
+		}
+	}
+	field = copyDict(field)
+	field["Kids"] = remaining
+	u.set(fieldRef, field)
+
+	// Add the new field next to the old one.
+	if parentRef, ok := field["Parent"].(pdfRef); ok {
+		parent := copyDict(d.dict(parentRef))
+		parent["Kids"] = append(append(pdfArray{}, d.array(parent["Kids"])...), newRef)
+		u.set(parentRef, parent)
+		return nil
+	}
+
+	catalog := d.catalog()
+	acro := copyDict(d.dict(catalog["AcroForm"]))
+	acro["Fields"] = append(append(pdfArray{}, d.array(acro["Fields"])...), newRef)
+	if acroRef, ok := catalog["AcroForm"].(pdfRef); ok {
+		u.set(acroRef, acro)
+		return nil
+	}
+	root, ok := d.trailer["Root"].(pdfRef)
+	if !ok {
+		return fmt.Errorf("invalid PDF: missing document catalog")
+	}
+	catalog = copyDict(catalog)
+	catalog["AcroForm"] = acro
+	u.set(root, catalog)
+	return nil
+}
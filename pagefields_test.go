@@ -0,0 +1,166 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParsePageFieldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		name string
+		page int
+		ok   bool
+	}{
+		{"date@2", "date", 2, true},
+		{"a@b@10", "a@b", 10, true},
+		{"date", "", 0, false},
+		{"@2", "", 0, false},
+		{"date@0", "", 0, false},
+		{"date@x", "", 0, false},
+		{"mail@example.com", "", 0, false},
+	}
+	for _, tt := range tests {
+		name, page, ok := parsePageFieldName(tt.key)
+		if name != tt.name || page != tt.page || ok != tt.ok {
+			t.Errorf("parsePageFieldName(%q) = %q, %d, %v, want %q, %d, %v", tt.key, name, page, ok, tt.name, tt.page, tt.ok)
+		}
+	}
+}
+
+// writeRepeatedForm writes a three page PDF form with the field "date" shown
+// on every page and the field "title" only shown on the first page.
+func writeRepeatedForm(t testing.TB) []byte {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+	date := w.add(nil)
+
+	var pages, dates pdfArray
+	var title pdfRef
+	for i := 0; i < 3; i++ {
+		page := w.add(nil)
+		widget := w.add(pdfDict{
+			"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page, "Parent": date,
+			"Rect": pdfArray{72, 700, 272, 720},
+		})
+		annots := pdfArray{widget}
+		if i == 0 {
+			title = w.add(pdfDict{
+				"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+				"FT": pdfName("Tx"), "T": pdfString("title"), "Rect": pdfArray{72, 660, 272, 680},
+			})
+			annots = append(annots, title)
+		}
+		w.set(page, pdfDict{
+			"Type":     pdfName("Page"),
+			"Parent":   parent,
+			"MediaBox": pdfArray{0, 0, 595, 842},
+			"Annots":   annots,
+		})
+		pages = append(pages, page)
+		dates = append(dates, widget)
+	}
+	w.set(date, pdfDict{"FT": pdfName("Tx"), "T": pdfString("date"), "Kids": dates})
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": pages, "Count": len(pages)})
+	root := w.add(pdfDict{
+		"Type":     pdfName("Catalog"),
+		"Pages":    parent,
+		"AcroForm": pdfDict{"Fields": pdfArray{date, title}},
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSplitPageFields(t *testing.T) {
+	data := writeRepeatedForm(t)
+	input := filepath.Join(t.TempDir(), "form.pdf")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	template := filepath.Join(t.TempDir(), "template.pdf")
+
+	t.Run("plain keys", func(t *testing.T) {
+		e := &recordExecutor{stdout: data}
+		useExecutor(t, e)
+
+		form := Form{"date": "1.1.", "title": "Dr."}
+		resolved, file, err := splitPageFields(form, input, template, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resolved, form) || file != input || len(e.calls) != 0 {
+			t.Errorf("splitPageFields() = %v, %s with %d calls, want the unchanged form", resolved, file, len(e.calls))
+		}
+	})
+
+	t.Run("page qualified", func(t *testing.T) {
+		useExecutor(t, &recordExecutor{stdout: data})
+
+		form := Form{"date": "1.1.", "date@2": "2.1.", "title@1": "Dr."}
+		resolved, file, err := splitPageFields(form, input, template, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (Form{"date": "1.1.", "date@2": "2.1.", "title": "Dr."}); !reflect.DeepEqual(resolved, want) {
+			t.Errorf("resolved form = %v, want %v", resolved, want)
+		}
+		if file != template {
+			t.Errorf("template = %s, want %s", file, template)
+		}
+
+		out, err := ioutil.ReadFile(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := parseNativePDF(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, w := range doc.widgets() {
+			got = append(got, fmt.Sprintf("%s %d", w.name, w.page))
+		}
+		sort.Strings(got)
+		if want := []string{"date 1", "date 3", "date@2 2", "title 1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("widgets = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("missing page", func(t *testing.T) {
+		useExecutor(t, &recordExecutor{stdout: data})
+
+		_, _, err := splitPageFields(Form{"date@4": "x"}, input, template, "")
+		if err == nil || !strings.Contains(err.Error(), "no widget on page 4") {
+			t.Errorf("splitPageFields(date@4) error = %v, want no widget on page 4", err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeepPages writes only the listed pages of the input PDF to output, in the
+// given order. Pages may be reordered, e.g. []int{3, 1, 2}.
+func KeepPages(input string, pages []int, output string) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages to keep")
+	}
+
+	numPages, err := NumPages(input)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"cat"}
+	for _, p := range pages {
+		if p < 1 || p > numPages {
+			return fmt.Errorf("invalid page number %d: document has %d pages", p, numPages)
+		}
+		args = append(args, strconv.Itoa(p))
+	}
+
+	return runPdftkFile(input, output, args...)
+}
+
+// RemovePages writes the input PDF without the listed pages to output.
+// The remaining pages keep their order.
+func RemovePages(input string, pages []int, output string) error {
+	numPages, err := NumPages(input)
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[int]bool, len(pages))
+	for _, p := range pages {
+		if p < 1 || p > numPages {
+			return fmt.Errorf("invalid page number %d: document has %d pages", p, numPages)
+		}
+		remove[p] = true
+	}
+
+	var keep []int
+	for p := 1; p <= numPages; p++ {
+		if !remove[p] {
+			keep = append(keep, p)
+		}
+	}
+	if len(keep) == 0 {
+		return fmt.Errorf("can't remove all pages")
+	}
+
+	return KeepPages(input, keep, output)
+}
+
+// selectPages resolves the pdftk page ranges like "1-3 r1 2-endeven" of a
+// document with numPages pages and reports per page whenever it is selected.
+func selectPages(pages string, numPages int) ([]bool, error) {
+	selected := make([]bool, numPages+1)
+	ranges := strings.FieldsFunc(pages, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for _, r := range ranges {
+		m := pageRangeRegex.FindStringSubmatch(r)
+		if m == nil {
+			return nil, fmt.Errorf("invalid page range: '%s'", r)
+		}
+
+		first, err := resolvePage(m[1], numPages)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if m[3] != "" {
+			if last, err = resolvePage(m[3], numPages); err != nil {
+				return nil, err
+			}
+		}
+		if first > last {
+			first, last = last, first
+		}
+
+		for p := first; p <= last; p++ {
+			if (m[4] == "even" && p%2 != 0) || (m[4] == "odd" && p%2 == 0) {
+				continue
+			}
+			selected[p] = true
+		}
+	}
+	return selected[1:], nil
+}
+
+// resolvePage returns the page number of a pdftk page reference like "3",
+// "end" or "r2", which counts from the end.
+func resolvePage(ref string, numPages int) (int, error) {
+	reverse := strings.HasPrefix(ref, "r")
+	ref = strings.TrimPrefix(ref, "r")
+
+	p := numPages
+	if ref != "end" {
+		var err error
+		if p, err = strconv.Atoi(ref); err != nil {
+			return 0, fmt.Errorf("invalid page number: '%s'", ref)
+		}
+	}
+	if reverse {
+		p = numPages - p + 1
+	}
+	if p < 1 || p > numPages {
+		return 0, fmt.Errorf("invalid page number %s: document has %d pages", ref, numPages)
+	}
+	return p, nil
+}
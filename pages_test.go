@@ -0,0 +1,133 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectPages(t *testing.T) {
+	tests := []struct {
+		pages   string
+		want    []int
+		wantErr bool
+	}{
+		{pages: "1", want: []int{1}},
+		{pages: "2-4", want: []int{2, 3, 4}},
+		{pages: "4-2", want: []int{2, 3, 4}},
+		{pages: "1 3,5", want: []int{1, 3, 5}},
+		{pages: "end", want: []int{6}},
+		{pages: "r1", want: []int{6}},
+		{pages: "r2-end", want: []int{5, 6}},
+		{pages: "1-endodd", want: []int{1, 3, 5}},
+		{pages: "1-endeven", want: []int{2, 4, 6}},
+		{pages: "", want: nil},
+		{pages: "7", wantErr: true},
+		{pages: "0", wantErr: true},
+		{pages: "r7", wantErr: true},
+		{pages: "1-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		selected, err := selectPages(tt.pages, 6)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("selectPages(%q) error = %v, want error %v", tt.pages, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var got []int
+		for i, s := range selected {
+			if s {
+				got = append(got, i+1)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectPages(%q) = %v, want %v", tt.pages, got, tt.want)
+		}
+	}
+}
+
+func TestKeepAndRemovePagesCommandLine(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 5)
+	output := filepath.Join(t.TempDir(), "output.pdf")
+
+	tests := []struct {
+		name    string
+		run     func() error
+		want    string
+		wantErr bool
+	}{
+		{name: "keep", run: func() error { return KeepPages(input, []int{3, 1, 2}, output) }, want: "cat 3 1 2"},
+		{name: "keep none", run: func() error { return KeepPages(input, nil, output) }, wantErr: true},
+		{name: "keep invalid", run: func() error { return KeepPages(input, []int{6}, output) }, wantErr: true},
+		{name: "remove", run: func() error { return RemovePages(input, []int{2, 4}, output) }, want: "cat 1 3 5"},
+		{name: "remove duplicates", run: func() error { return RemovePages(input, []int{5, 5}, output) }, want: "cat 1 2 3 4"},
+		{name: "remove all", run: func() error { return RemovePages(input, []int{1, 2, 3, 4, 5}, output) }, wantErr: true},
+		{name: "remove invalid", run: func() error { return RemovePages(input, []int{0}, output) }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte("NumberOfPages: 5\n"), output: []byte("%PDF-1.4")}
+			useExecutor(t, e)
+
+			err := tt.run()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if call := strings.Join(e.lastCall(), " "); !strings.Contains(call, " "+tt.want+" output ") {
+				t.Errorf("pdftk call = %q, want %q", call, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepPages(t *testing.T) {
+	requirePDFTK(t)
+	input := writeTestPDF(t, "input.pdf", 5)
+	output := filepath.Join(t.TempDir(), "output.pdf")
+
+	if err := KeepPages(input, []int{3, 1}, output); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loadPDFFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := doc.pages()
+	if len(refs) != 2 {
+		t.Fatalf("pages = %d, want 2", len(refs))
+	}
+	for i, want := range []int{3, 1} {
+		if text := pageText(t, doc, refs[i]); !strings.Contains(text, fmt.Sprintf("(Page %d)", want)) {
+			t.Errorf("page %d = %q, want page %d", i+1, text, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// OutputOptions encrypt the output PDF with 128 bit RC4, the pdftk default,
+// or the selected Encryption and compress it. A user password is required to
+// open the document. The owner password grants all permissions, others only
+// get the allowed ones. The passwords are never part of returned errors.
+type OutputOptions struct {
+	OwnerPassword string
+	UserPassword  string
+	// Encryption selects the cipher of the encrypted output. If empty,
+	// pdftk uses Encrypt128Bit.
+	Encryption Encryption
+	// AllowPermissions are the pdftk permissions of users without the owner
+	// password, e.g. "Printing" or "CopyContents". If empty, pdftk allows
+	// nothing.
+	AllowPermissions []string
+	// Compress compresses the page streams of the output like Compress, which
+	// shrinks e.g. merged packets of uncompressed documents.
+	Compress bool
+}
+
+// Encryption is the cipher of encrypted output PDFs.
+type Encryption string
+
+const (
+	// Encrypt40Bit is 40 bit RC4 for very old viewers.
+	Encrypt40Bit Encryption = "encrypt_40bit"
+	// Encrypt128Bit is 128 bit RC4.
+	Encrypt128Bit Encryption = "encrypt_128bit"
+	// EncryptAES128 is 128 bit AES, which requires pdftk-java.
+	EncryptAES128 Encryption = "encrypt_aes128"
+)
+
+// pdftkPermissions are the permissions of the pdftk allow option.
+var pdftkPermissions = []string{
+	"Printing", "DegradedPrinting", "ModifyContents", "Assembly", "CopyContents",
+	"ScreenReaders", "ModifyAnnotations", "FillIn", "AllFeatures",
+}
+
+// args returns the pdftk output options.
+func (o OutputOptions) args() []string {
+	var args []string
+	if o.OwnerPassword != "" {
+		args = append(args, "owner_pw", o.OwnerPassword)
+	}
+	if o.UserPassword != "" {
+		args = append(args, "user_pw", o.UserPassword)
+	}
+	if o.Encryption != "" {
+		args = append(args, string(o.Encryption))
+	}
+	if len(o.AllowPermissions) > 0 {
+		args = append(append(args, "allow"), o.AllowPermissions...)
+	}
+	if o.Compress {
+		args = append(args, "compress")
+	}
+	return args
+}
+
+// validate checks the encryption and the permissions are known to pdftk.
+func (o OutputOptions) validate() error {
+	switch o.Encryption {
+	case "", Encrypt40Bit, Encrypt128Bit, EncryptAES128:
+	default:
+		return fmt.Errorf("invalid encryption: '%s'", o.Encryption)
+	}
+	if o.Encryption != "" && o.OwnerPassword == "" && o.UserPassword == "" {
+		return fmt.Errorf("encryption requires an owner or user password")
+	}
+	for _, p := range o.AllowPermissions {
+		if !containsString(pdftkPermissions, p) {
+			return fmt.Errorf("invalid permission: '%s'", p)
+		}
+	}
+	if len(o.AllowPermissions) > 0 && o.OwnerPassword == "" && o.UserPassword == "" {
+		return fmt.Errorf("permissions require an owner or user password")
+	}
+	return nil
+}
+
+// password returns a password opening the output PDF.
+func (o OutputOptions) password() string {
+	if o.OwnerPassword != "" {
+		return o.OwnerPassword
+	}
+	return o.UserPassword
+}
+
+// pdftkInput returns the pdftk arguments reading a single input file,
+// with the input_pw option if a password is given.
+func pdftkInput(pdfFile, password string) []string {
+	if password == "" {
+		return []string{pdfFile}
+	}
+	return []string{pdfFile, "input_pw", password}
+}
+
+// maxPDFTKHandles is the number of pdftk handles A to Z. Older pdftk builds
+// only accept single letter handles, so no more are used.
+const maxPDFTKHandles = 26
+
+// pdftkHandle returns the pdftk handle of the i-th input file, A to Z for
+// i < maxPDFTKHandles.
+func pdftkHandle(i int) string {
+	return string(rune('A' + i))
+}
+
+// pdftkInputs returns the pdftk arguments reading the input files. The files
+// with a password are given handles and the input_pw option lists the
+// passwords by handle. The other files are passed without handle, so any
+// number of them may be mixed in, but at most maxPDFTKHandles files may have
+// a password.
+func pdftkInputs(files []string, passwords map[string]string) ([]string, error) {
+	var args, pws []string
+	for _, f := range files {
+		pw := passwords[f]
+		if pw == "" {
+			args = append(args, f)
+			continue
+		}
+		if len(pws) == maxPDFTKHandles {
+			return nil, fmt.Errorf("too many password protected input files: at most %d are supported", maxPDFTKHandles)
+		}
+		handle := pdftkHandle(len(pws))
+		args = append(args, handle+"="+f)
+		pws = append(pws, handle+"="+pw)
+	}
+	if len(pws) > 0 {
+		args = append(append(args, "input_pw"), pws...)
+	}
+	return args, nil
+}
+
+// redactPasswords replaces the passwords of the input_pw, owner_pw and user_pw
+// options in args within the message, so error messages don't leak them. Only
+// whole words equal to a password are replaced, like the argument echoes of
+// pdftk, so the message stays readable even for short passwords.
+func redactPasswords(msg string, args []string) string {
+	secrets := make(map[string]bool)
+	for _, pw := range passwordArgs(args) {
+		secrets[args[pw.index]] = true
+		if pw.handle != "" {
+			secrets[args[pw.index][len(pw.handle)+1:]] = true
+		}
+	}
+	if len(secrets) == 0 {
+		return msg
+	}
+
+	// Passwords with spaces span several words.
+	for secret := range secrets {
+		if strings.IndexFunc(secret, unicode.IsSpace) >= 0 {
+			msg = strings.Replace(msg, secret, "***", -1)
+		}
+	}
+
+	return wordRegex.ReplaceAllStringFunc(msg, func(word string) string {
+		if secrets[word] {
+			return "***"
+		}
+		// Passwords might be quoted or followed by punctuation.
+		if core := strings.Trim(word, "'\"`()[]<>,;:.!?"); core != "" && secrets[core] {
+			return strings.Replace(word, core, "***", 1)
+		}
+		return word
+	})
+}
+
+// wordRegex matches the words of redactPasswords.
+var wordRegex = regexp.MustCompile(`\S+`)
+
+// redactArgs returns a copy of args with the passwords of the input_pw,
+// owner_pw and user_pw options replaced. The handles of "A=password" are
+// kept.
+func redactArgs(args []string) []string {
+	redacted := append([]string(nil), args...)
+	for _, pw := range passwordArgs(args) {
+		if pw.handle != "" {
+			redacted[pw.index] = pw.handle + "=***"
+		} else {
+			redacted[pw.index] = "***"
+		}
+	}
+	return redacted
+}
+
+// passwordArg is a password argument of the pdftk options input_pw, owner_pw
+// and user_pw. The password of an input file handle is given as
+// "A=password".
+type passwordArg struct {
+	index  int
+	handle string
+}
+
+// passwordArgs returns the password arguments of the pdftk arguments. The
+// passwords of input_pw are only taken as one "A=password" per handle, if the
+// input files were given with handles like pdftkInputs does for several
+// files. Otherwise the single argument following input_pw is the password,
+// even if it contains a '='.
+func passwordArgs(args []string) []passwordArg {
+	var pws []passwordArg
+	handles := make(map[string]bool)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "owner_pw", "user_pw":
+			if i+1 < len(args) {
+				i++
+				pws = append(pws, passwordArg{index: i})
+			}
+		case "input_pw":
+			if len(handles) == 0 {
+				if i+1 < len(args) {
+					i++
+					pws = append(pws, passwordArg{index: i})
+				}
+				continue
+			}
+			for i+1 < len(args) && handles[pdftkHandleOf(args[i+1])] {
+				i++
+				pws = append(pws, passwordArg{index: i, handle: pdftkHandleOf(args[i])})
+			}
+		default:
+			if h := pdftkHandleOf(args[i]); h != "" {
+				handles[h] = true
+			}
+		}
+	}
+	return pws
+}
+
+// pdftkHandleOf returns the handle of an argument like "A=file.pdf" or
+// "A=password", or "" if it has none.
+func pdftkHandleOf(arg string) string {
+	i := strings.IndexByte(arg, '=')
+	if i <= 0 || i == len(arg)-1 {
+		return ""
+	}
+	for _, c := range arg[:i] {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+	}
+	return arg[:i]
+}
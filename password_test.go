@@ -0,0 +1,242 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactPasswords(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		args []string
+		want string
+	}{
+		{
+			name: "echoed password",
+			msg:  "Error: Unexpected text in input_pw: secret",
+			args: []string{"in.pdf", "input_pw", "secret", "output", "-"},
+			want: "Error: Unexpected text in input_pw: ***",
+		},
+		{
+			name: "quoted password",
+			msg:  "Error: bad password 'secret'.",
+			args: []string{"in.pdf", "owner_pw", "secret"},
+			want: "Error: bad password '***'.",
+		},
+		{
+			name: "short password",
+			msg:  "Error: Failed to open PDF file: a.pdf\nDone. Input errors, so no output created.",
+			args: []string{"a.pdf", "input_pw", "a", "output", "-"},
+			want: "Error: Failed to open PDF file: a.pdf\nDone. Input errors, so no output created.",
+		},
+		{
+			name: "short password echoed",
+			msg:  "Error: Unexpected text in input_pw: 1",
+			args: []string{"in.pdf", "user_pw", "1"},
+			want: "Error: Unexpected text in input_pw: ***",
+		},
+		{
+			name: "handle passwords",
+			msg:  "Error: A=first B=second",
+			args: []string{"A=a.pdf", "B=b.pdf", "input_pw", "A=first", "B=second", "cat", "output", "-"},
+			want: "Error: *** ***",
+		},
+		{
+			name: "password with spaces",
+			msg:  "Error: Unexpected text: my secret",
+			args: []string{"in.pdf", "input_pw", "my secret"},
+			want: "Error: Unexpected text: ***",
+		},
+		{
+			name: "no password",
+			msg:  "Error: Failed to open PDF file",
+			args: []string{"in.pdf", "output", "-"},
+			want: "Error: Failed to open PDF file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactPasswords(tt.msg, tt.args); got != tt.want {
+				t.Errorf("redactPasswords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "single input",
+			args: []string{"/in.pdf", "input_pw", "secret", "output", "-"},
+			want: []string{"/in.pdf", "input_pw", "***", "output", "-"},
+		},
+		{
+			name: "single input with handle like password",
+			args: []string{"/in.pdf", "input_pw", "ABC=secret", "output", "-"},
+			want: []string{"/in.pdf", "input_pw", "***", "output", "-"},
+		},
+		{
+			name: "handles",
+			args: []string{"A=/a.pdf", "B=/b.pdf", "input_pw", "A=first", "B=C=second", "cat", "output", "-"},
+			want: []string{"A=/a.pdf", "B=/b.pdf", "input_pw", "A=***", "B=***", "cat", "output", "-"},
+		},
+		{
+			name: "unknown handle",
+			args: []string{"A=/a.pdf", "input_pw", "A=first", "Z=x", "cat"},
+			want: []string{"A=/a.pdf", "input_pw", "A=***", "Z=x", "cat"},
+		},
+		{
+			name: "output passwords",
+			args: []string{"/in.pdf", "output", "/out.pdf", "owner_pw", "o", "user_pw", "u", "allow", "Printing"},
+			want: []string{"/in.pdf", "output", "/out.pdf", "owner_pw", "***", "user_pw", "***", "allow", "Printing"},
+		},
+		{
+			name: "trailing option",
+			args: []string{"/in.pdf", "input_pw"},
+			want: []string{"/in.pdf", "input_pw"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactArgs(tt.args)
+			if len(got) != len(tt.want) {
+				t.Fatalf("redactArgs() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("redactArgs() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactPasswordsSingleInputWithEquals(t *testing.T) {
+	msg := "Error: Unexpected text in input_pw: ABC=secret"
+	args := []string{"/in.pdf", "input_pw", "ABC=secret", "output", "-"}
+	if got, want := redactPasswords(msg, args), "Error: Unexpected text in input_pw: ***"; got != want {
+		t.Errorf("redactPasswords() = %q, want %q", got, want)
+	}
+}
+
+func TestOutputOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  OutputOptions
+		args    string
+		invalid bool
+	}{
+		{"empty", OutputOptions{}, "", false},
+		{"passwords", OutputOptions{OwnerPassword: "o", UserPassword: "u"}, "owner_pw o user_pw u", false},
+		{"encryption", OutputOptions{OwnerPassword: "o", Encryption: Encrypt128Bit}, "owner_pw o encrypt_128bit", false},
+		{"aes", OutputOptions{UserPassword: "u", Encryption: EncryptAES128, AllowPermissions: []string{"Printing"}}, "user_pw u encrypt_aes128 allow Printing", false},
+		{"compress", OutputOptions{Compress: true}, "compress", false},
+		{"unknown encryption", OutputOptions{OwnerPassword: "o", Encryption: "encrypt_256bit"}, "", true},
+		{"encryption without password", OutputOptions{Encryption: Encrypt40Bit}, "", true},
+		{"unknown permission", OutputOptions{OwnerPassword: "o", AllowPermissions: []string{"Print"}}, "", true},
+		{"permission without password", OutputOptions{AllowPermissions: []string{"Printing"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.output.validate()
+			if tt.invalid {
+				if err == nil {
+					t.Error("validate() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate() = %v", err)
+			}
+			if got := strings.Join(tt.output.args(), " "); got != tt.args {
+				t.Errorf("args() = %q, want %q", got, tt.args)
+			}
+		})
+	}
+}
+
+func TestPDFTKInputs(t *testing.T) {
+	var files []string
+	for i := 0; i < 30; i++ {
+		files = append(files, fmt.Sprintf("%d.pdf", i))
+	}
+
+	args, err := pdftkInputs(files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(args, " "), strings.Join(files, " "); got != want {
+		t.Errorf("args without passwords = %q, want %q", got, want)
+	}
+
+	// Only the files with passwords get handles, single letters only.
+	args, err = pdftkInputs(files, map[string]string{"1.pdf": "one", "28.pdf": "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]string{"0.pdf", "A=1.pdf"}, files[2:28]...)
+	want = append(want, "B=28.pdf", "29.pdf", "input_pw", "A=one", "B=two")
+	if got := strings.Join(args, " "); got != strings.Join(want, " ") {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+
+	passwords := make(map[string]string)
+	for _, f := range files[:maxPDFTKHandles] {
+		passwords[f] = "secret"
+	}
+	if args, err = pdftkInputs(files, passwords); err != nil {
+		t.Fatal(err)
+	}
+	if h := pdftkHandleOf(args[maxPDFTKHandles-1]); h != "Z" {
+		t.Errorf("handle of input %d = %q, want %q", maxPDFTKHandles, h, "Z")
+	}
+
+	passwords[files[maxPDFTKHandles]] = "secret"
+	if _, err := pdftkInputs(files, passwords); err == nil {
+		t.Errorf("pdftkInputs with %d passwords succeeded", len(passwords))
+	}
+}
+
+func TestMergePagesTooManySpecs(t *testing.T) {
+	e := &recordExecutor{output: []byte("%PDF-merged")}
+	useExecutor(t, e)
+
+	specs := make([]MergeSpec, maxPDFTKHandles+1)
+	for i := range specs {
+		specs[i] = MergeSpec{File: writeTestPDF(t, fmt.Sprintf("%d.pdf", i), 1)}
+	}
+	if _, err := MergePages(specs); err == nil {
+		t.Errorf("MergePages with %d specs succeeded", len(specs))
+	}
+	if _, err := MergePages(specs[:maxPDFTKHandles]); err != nil {
+		t.Fatalf("MergePages with %d specs: %v", maxPDFTKHandles, err)
+	}
+	if args := e.lastCall(); !strings.HasPrefix(args[maxPDFTKHandles], "Z=") {
+		t.Errorf("last input = %q, want handle Z", args[maxPDFTKHandles])
+	}
+}
@@ -0,0 +1,558 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// This file contains a minimal PDF object reader. It is not a general purpose
+// PDF parser: documents are always normalized with "pdftk ... uncompress"
+// first, which guarantees a classic xref table, no object streams and
+// unfiltered stream data.
+
+type pdfName string
+
+type pdfRef struct {
+	num, gen int
+}
+
+type pdfDict map[pdfName]interface{}
+
+type pdfArray []interface{}
+
+type pdfString []byte
+
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+type pdfDocument struct {
+	data      []byte
+	objects   map[int]interface{}
+	trailer   pdfDict
+	startxref int
+}
+
+var pdfObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// loadPDFFile normalizes the PDF file with pdftk and parses the result.
+func loadPDFFile(pdfFile string) (*pdfDocument, error) {
+	return loadPDFFileWithPassword(pdfFile, "")
+}
+
+// loadPDFFileWithPassword is like loadPDFFile for a password protected file.
+// The document is decrypted on the way.
+func loadPDFFileWithPassword(pdfFile, password string) (*pdfDocument, error) {
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	pdfFile, err := getAbs(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append(pdftkInput(pdfFile, password), "output", "-", "uncompress")
+	data, err := runCommandWithOutput("", "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	return parsePDF(data)
+}
+
+// parsePDF parses all indirect objects and the trailer of an uncompressed PDF.
+func parsePDF(data []byte) (*pdfDocument, error) {
+	doc := &pdfDocument{
+		data:    data,
+		objects: make(map[int]interface{}),
+	}
+
+	pos := 0
+	for {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{data: data, pos: pos + loc[1]}
+		v, err := l.parseIndirect()
+		if err != nil {
+			// Skip the broken object and resynchronize on the next header.
+			pos += loc[1]
+			continue
+		}
+		doc.objects[num] = v
+		pos = l.pos
+	}
+
+	// Use the last trailer, which belongs to the most recent update.
+	if i := bytes.LastIndex(data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: data, pos: i + len("trailer")}
+		if v, err := l.parseValue(); err == nil {
+			doc.trailer, _ = v.(pdfDict)
+		}
+	}
+
+	if i := bytes.LastIndex(data, []byte("startxref")); i >= 0 {
+		l := &pdfLexer{data: data, pos: i + len("startxref")}
+		if v, err := l.parseValue(); err == nil {
+			if f, ok := v.(float64); ok {
+				doc.startxref = int(f)
+			}
+		}
+	}
+
+	// Documents with a cross-reference stream keep the trailer entries in
+	// the stream dictionary.
+	if doc.trailer == nil && doc.startxref > 0 && doc.startxref < len(data) {
+		if loc := pdfObjHeader.FindIndex(data[doc.startxref:]); loc != nil && loc[0] == 0 {
+			l := &pdfLexer{data: data, pos: doc.startxref + loc[1]}
+			if v, err := l.parseIndirect(); err == nil {
+				if s, ok := v.(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+					doc.trailer = pdfDict{}
+					for _, key := range []pdfName{"Size", "Root", "Info", "ID", "Encrypt"} {
+						if v, ok := s.dict[key]; ok {
+							doc.trailer[key] = v
+						}
+					}
+				}
+			}
+		}
+	}
+	if doc.trailer == nil {
+		return nil, fmt.Errorf("invalid PDF: missing trailer")
+	}
+
+	return doc, nil
+}
+
+// resolve follows indirect references.
+func (d *pdfDocument) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves v and returns it as dictionary. Stream dictionaries are
+// returned as well. The result is nil if v is not a dictionary.
+func (d *pdfDocument) dict(v interface{}) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+func (d *pdfDocument) array(v interface{}) pdfArray {
+	a, _ := d.resolve(v).(pdfArray)
+	return a
+}
+
+func (d *pdfDocument) number(v interface{}) (float64, bool) {
+	f, ok := d.resolve(v).(float64)
+	return f, ok
+}
+
+func (d *pdfDocument) name(v interface{}) pdfName {
+	n, _ := d.resolve(v).(pdfName)
+	return n
+}
+
+func (d *pdfDocument) text(v interface{}) string {
+	s, _ := d.resolve(v).(pdfString)
+	return decodeTextString(s)
+}
+
+// catalog returns the document catalog.
+func (d *pdfDocument) catalog() pdfDict {
+	return d.dict(d.trailer["Root"])
+}
+
+// pages returns the references of all page objects in document order.
+func (d *pdfDocument) pages() []pdfRef {
+	var refs []pdfRef
+	var walk func(v interface{}, depth int)
+	walk = func(v interface{}, depth int) {
+		node := d.dict(v)
+		if node == nil || depth > 64 {
+			return
+		}
+		if d.name(node["Type"]) == "Page" {
+			if ref, ok := v.(pdfRef); ok {
+				refs = append(refs, ref)
+			}
+			return
+		}
+		for _, kid := range d.array(node["Kids"]) {
+			walk(kid, depth+1)
+		}
+	}
+	walk(d.catalog()["Pages"], 0)
+	return refs
+}
+
+// inherited looks up a key in the dictionary or its /Parent chain.
+func (d *pdfDocument) inherited(dict pdfDict, key pdfName) interface{} {
+	for i := 0; dict != nil && i < 64; i++ {
+		if v, ok := dict[key]; ok {
+			return v
+		}
+		dict = d.dict(dict["Parent"])
+	}
+	return nil
+}
+
+// decodeTextString decodes a PDF text string, which is either UTF-16BE with
+// a byte order mark or PDFDocEncoding (treated as Latin-1).
+func decodeTextString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		// Ignore a trailing odd byte of broken strings.
+		decoded, _ := DecodeUTF16(s[:len(s)&^1])
+		return decoded
+	}
+	r := make([]rune, len(s))
+	for i, b := range s {
+		r[i] = rune(b)
+	}
+	return string(r)
+}
+
+// encodeTextString encodes s as PDF text string, using UTF-16BE with a
+// byte order mark for non-ASCII strings.
+func encodeTextString(s string) pdfString {
+	for _, r := range s {
+		if r >= 0x80 {
+			return pdfString(EncodeUTF16(s, true))
+		}
+	}
+	return pdfString(s)
+}
+
+// pdfLexer tokenizes and parses PDF objects.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+type pdfKeyword string
+
+func isPDFWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFWhitespace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+func (l *pdfLexer) regular() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// parseIndirect parses the body of an indirect object after "N G obj".
+func (l *pdfLexer) parseIndirect() (interface{}, error) {
+	v, err := l.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	l.skipSpace()
+	if bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		dict, ok := v.(pdfDict)
+		if !ok {
+			return nil, fmt.Errorf("stream without dictionary")
+		}
+		l.pos += len("stream")
+		if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+			l.pos++
+		}
+		if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+			l.pos++
+		}
+		start := l.pos
+
+		// Trust a direct /Length only if it is a valid length followed by
+		// endstream.
+		end := -1
+		if n, ok := dict["Length"].(float64); ok && n >= 0 && n <= float64(len(l.data)-start) && n == float64(int(n)) {
+			rest := l.data[start+int(n):]
+			if i := bytes.Index(rest, []byte("endstream")); i >= 0 && len(bytes.TrimSpace(rest[:i])) == 0 {
+				end = start + int(n)
+				l.pos = start + int(n) + i + len("endstream")
+			}
+		}
+		if end < 0 {
+			i := bytes.Index(l.data[start:], []byte("endstream"))
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated stream")
+			}
+			end = start + i
+			l.pos = end + len("endstream")
+			// Remove the end of line marker preceding endstream.
+			if end > start && l.data[end-1] == '\n' {
+				end--
+			}
+			if end > start && l.data[end-1] == '\r' {
+				end--
+			}
+		}
+		v = &pdfStream{dict: dict, data: l.data[start:end]}
+		l.skipSpace()
+	}
+
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("endobj")) {
+		return nil, fmt.Errorf("missing endobj")
+	}
+	l.pos += len("endobj")
+	return v, nil
+}
+
+func (l *pdfLexer) parseValue() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		l.pos++
+		return pdfName(decodeName(l.regular())), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		return l.dictionary()
+	case c == '<':
+		return l.hexString()
+	case c == '[':
+		l.pos++
+		arr := pdfArray{}
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, fmt.Errorf("unterminated array")
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			v, err := l.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case isPDFDelimiter(c):
+		return nil, fmt.Errorf("unexpected delimiter %q at offset %d", c, l.pos)
+	}
+
+	tok := l.regular()
+	switch string(tok) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(string(tok), 64)
+	if err != nil {
+		return pdfKeyword(tok), nil
+	}
+
+	// An integer might be the start of an indirect reference "N G R".
+	if _, err := strconv.Atoi(string(tok)); err == nil {
+		save := l.pos
+		l.skipSpace()
+		gen := l.regular()
+		if g, err := strconv.Atoi(string(gen)); err == nil {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' &&
+				(l.pos+1 == len(l.data) || isPDFWhitespace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: int(f), gen: g}, nil
+			}
+		}
+		l.pos = save
+	}
+	return f, nil
+}
+
+func (l *pdfLexer) dictionary() (interface{}, error) {
+	l.pos += 2
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		key, err := l.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("invalid dictionary key at offset %d", l.pos)
+		}
+		v, err := l.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = v
+	}
+}
+
+func (l *pdfLexer) literalString() (interface{}, error) {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(out), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+func (l *pdfLexer) hexString() (interface{}, error) {
+	l.pos++
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated hex string")
+	}
+	var digits []byte
+	for _, c := range l.data[l.pos : l.pos+end] {
+		if !isPDFWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		b, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string")
+		}
+		out[i] = byte(b)
+	}
+	return pdfString(out), nil
+}
+
+func decodeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
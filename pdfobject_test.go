@@ -0,0 +1,84 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextString(t *testing.T) {
+	tests := []struct {
+		text    string
+		encoded []byte
+	}{
+		{"", []byte{}},
+		{"name", []byte("name")},
+		{"Straße", []byte{0xFE, 0xFF, 0, 'S', 0, 't', 0, 'r', 0, 'a', 0, 0xDF, 0, 'e'}},
+		{"€", []byte{0xFE, 0xFF, 0x20, 0xAC}},
+	}
+	for _, tt := range tests {
+		encoded := encodeTextString(tt.text)
+		if !bytes.Equal(encoded, tt.encoded) {
+			t.Errorf("encodeTextString(%q) = % X, want % X", tt.text, encoded, tt.encoded)
+		}
+		if text := decodeTextString(encoded); text != tt.text {
+			t.Errorf("decodeTextString(% X) = %q, want %q", encoded, text, tt.text)
+		}
+	}
+
+	// PDFDocEncoding is decoded as Latin-1.
+	if text := decodeTextString([]byte{'K', 0xF6, 'l', 'n'}); text != "Köln" {
+		t.Errorf("decodeTextString of Latin-1 = %q, want %q", text, "Köln")
+	}
+}
+
+func TestParseIndirectStreamLength(t *testing.T) {
+	tests := []struct {
+		length string
+		want   string
+	}{
+		{"4", "data"},
+		{"2", "data"},
+		{"-1", "data"},
+		{"-1000", "data"},
+		{"1e300", "data"},
+		{"2.5", "data"},
+		{"/Four", "data"},
+		{"1000", "data"},
+		{"4 0 R", "data"},
+	}
+	for _, tt := range tests {
+		l := &pdfLexer{data: []byte("<< /Length " + tt.length + " >>\nstream\ndata\nendstream\nendobj\n")}
+		v, err := l.parseIndirect()
+		if err != nil {
+			t.Errorf("Length %s: %v", tt.length, err)
+			continue
+		}
+		s, ok := v.(*pdfStream)
+		if !ok {
+			t.Errorf("Length %s: object = %v, want a stream", tt.length, v)
+			continue
+		}
+		if string(s.data) != tt.want {
+			t.Errorf("Length %s: data = %q, want %q", tt.length, s.data, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// pdfUpdate collects changed and new objects and appends them to the
+// document as an incremental update. Running the result through pdftk
+// again consolidates the update into a regular file.
+type pdfUpdate struct {
+	doc     *pdfDocument
+	objects map[int]interface{}
+	gens    map[int]int
+	next    int
+}
+
+// update starts a new incremental update of the document.
+func (d *pdfDocument) update() *pdfUpdate {
+	u := &pdfUpdate{
+		doc:     d,
+		objects: make(map[int]interface{}),
+		gens:    make(map[int]int),
+		next:    1,
+	}
+	if n, ok := d.number(d.trailer["Size"]); ok {
+		u.next = int(n)
+	}
+	for num := range d.objects {
+		if num >= u.next {
+			u.next = num + 1
+		}
+	}
+	return u
+}
+
+// add adds a new indirect object and returns its reference.
+func (u *pdfUpdate) add(v interface{}) pdfRef {
+	ref := pdfRef{num: u.next}
+	u.next++
+	u.objects[ref.num] = v
+	u.doc.objects[ref.num] = v
+	return ref
+}
+
+// set replaces the value of an existing indirect object.
+func (u *pdfUpdate) set(ref pdfRef, v interface{}) {
+	u.objects[ref.num] = v
+	u.gens[ref.num] = ref.gen
+	u.doc.objects[ref.num] = v
+}
+
+// bytes returns the document with the update appended.
+func (u *pdfUpdate) bytes() ([]byte, error) {
+	var b bytes.Buffer
+	b.Write(u.doc.data)
+	if !bytes.HasSuffix(u.doc.data, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+
+	nums := make([]int, 0, len(u.objects))
+	for num := range u.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	offsets := make(map[int]int, len(nums))
+	for _, num := range nums {
+		offsets[num] = b.Len()
+		fmt.Fprintf(&b, "%d %d obj\n", num, u.gens[num])
+		if err := writePDFValue(&b, u.objects[num]); err != nil {
+			return nil, fmt.Errorf("object %d: %w", num, err)
+		}
+		b.WriteString("\nendobj\n")
+	}
+
+	// Write one xref subsection per object to keep it simple.
+	xref := b.Len()
+	b.WriteString("xref\n")
+	for _, num := range nums {
+		fmt.Fprintf(&b, "%d 1\n%010d %05d n \n", num, offsets[num], u.gens[num])
+	}
+
+	trailer := pdfDict{}
+	for k, v := range u.doc.trailer {
+		if k != "Prev" && k != "XRefStm" {
+			trailer[k] = v
+		}
+	}
+	trailer["Size"] = float64(u.next)
+	trailer["Prev"] = float64(u.doc.startxref)
+
+	b.WriteString("trailer\n")
+	if err := writePDFValue(&b, trailer); err != nil {
+		return nil, fmt.Errorf("trailer: %w", err)
+	}
+	fmt.Fprintf(&b, "\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	return b.Bytes(), nil
+}
+
+// writeFile writes the updated document to path.
+func (u *pdfUpdate) writeFile(path string) error {
+	data, err := u.bytes()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// save writes the updated document to the output file. pdftk consolidates
+// the update and compresses the streams again on the way. The working
+// directory is created in tempDir, see createTempDir.
+func (u *pdfUpdate) save(output, tempDir string) error {
+	return u.saveWith(output, tempDir)
+}
+
+// saveWith is like save, passing additional pdftk output options.
+func (u *pdfUpdate) saveWith(output, tempDir string, options ...string) error {
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	updateFile := filepath.Clean(tmpDir + "/update.pdf")
+	if err := u.writeFile(updateFile); err != nil {
+		return err
+	}
+
+	// Run the pdftk utility.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	args := append([]string{updateFile, "output", outputFile, "compress"}, options...)
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+
+	// On success, copy the output file to the final destination.
+	return copyFile(outputFile, output)
+}
+
+// writePDFValue serializes a PDF object. Values of other types than the ones
+// of the parser return an error.
+func writePDFValue(b *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(t))
+	case int:
+		b.WriteString(strconv.Itoa(t))
+	case float64:
+		b.WriteString(strconv.FormatFloat(t, 'f', -1, 64))
+	case pdfName:
+		b.WriteByte('/')
+		for _, c := range []byte(t) {
+			if c < '!' || c > '~' || c == '#' || isPDFDelimiter(c) {
+				fmt.Fprintf(b, "#%02X", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	case pdfString:
+		fmt.Fprintf(b, "<%X>", []byte(t))
+	case pdfKeyword:
+		b.WriteString(string(t))
+	case pdfRef:
+		fmt.Fprintf(b, "%d %d R", t.num, t.gen)
+	case pdfArray:
+		b.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if err := writePDFValue(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case pdfDict:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, k := range keys {
+			writePDFValue(b, pdfName(k))
+			b.WriteByte(' ')
+			if err := writePDFValue(b, t[pdfName(k)]); err != nil {
+				return fmt.Errorf("/%s: %w", k, err)
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(">>")
+	case *pdfStream:
+		dict := copyDict(t.dict)
+		dict["Length"] = float64(len(t.data))
+		if err := writePDFValue(b, dict); err != nil {
+			return err
+		}
+		b.WriteString("\nstream\n")
+		b.Write(t.data)
+		b.WriteString("\nendstream")
+	default:
+		return fmt.Errorf("cannot serialize PDF value of type %T", v)
+	}
+	return nil
+}
+
+// copyDict returns a shallow copy of the dictionary.
+func copyDict(d pdfDict) pdfDict {
+	c := make(pdfDict, len(d))
+	for k, v := range d {
+		c[k] = v
+	}
+	return c
+}
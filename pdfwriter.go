@@ -0,0 +1,171 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+)
+
+// pdfPage is a page of a PDF document created by writePDF.
+type pdfPage struct {
+	width, height float64
+	content       []byte
+	resources     pdfDict
+}
+
+// pdfWriter creates new PDF documents from scratch.
+type pdfWriter struct {
+	objects []interface{}
+}
+
+// add adds an indirect object and returns its reference.
+func (w *pdfWriter) add(v interface{}) pdfRef {
+	w.objects = append(w.objects, v)
+	return pdfRef{num: len(w.objects)}
+}
+
+// set replaces the value of an object, e.g. one added as nil placeholder.
+func (w *pdfWriter) set(ref pdfRef, v interface{}) {
+	w.objects[ref.num-1] = v
+}
+
+// bytes serializes all objects into a PDF file with the given catalog.
+func (w *pdfWriter) bytes(root pdfRef) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	offsets := make([]int, len(w.objects))
+	for i, v := range w.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		if err := writePDFValue(&b, v); err != nil {
+			return nil, fmt.Errorf("object %d: %w", i+1, err)
+		}
+		b.WriteString("\nendobj\n")
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+
+	b.WriteString("trailer\n")
+	writePDFValue(&b, pdfDict{
+		"Size": len(w.objects) + 1,
+		"Root": root,
+	})
+	fmt.Fprintf(&b, "\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	return b.Bytes(), nil
+}
+
+// indirectStreams returns v with all streams replaced by references to new
+// indirect objects, since streams must not be direct objects.
+func (w *pdfWriter) indirectStreams(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *pdfStream:
+		return w.add(t)
+	case pdfDict:
+		c := make(pdfDict, len(t))
+		for k, e := range t {
+			c[k] = w.indirectStreams(e)
+		}
+		return c
+	case pdfArray:
+		c := make(pdfArray, len(t))
+		for i, e := range t {
+			c[i] = w.indirectStreams(e)
+		}
+		return c
+	}
+	return v
+}
+
+// writePDF writes a new PDF document with the given pages to path.
+func writePDF(path string, pages []pdfPage) error {
+	w := &pdfWriter{}
+	parent := w.add(nil)
+
+	kids := pdfArray{}
+	for _, p := range pages {
+		kids = append(kids, w.add(pdfDict{
+			"Type":      pdfName("Page"),
+			"Parent":    parent,
+			"MediaBox":  pdfArray{0, 0, p.width, p.height},
+			"Resources": w.indirectStreams(p.resources),
+			"Contents":  w.add(&pdfStream{dict: pdfDict{}, data: p.content}),
+		}))
+	}
+	w.set(parent, pdfDict{
+		"Type":  pdfName("Pages"),
+		"Kids":  kids,
+		"Count": len(kids),
+	})
+
+	root := w.add(pdfDict{
+		"Type":  pdfName("Catalog"),
+		"Pages": parent,
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// helveticaResources are page resources providing Helvetica as /F1.
+func helveticaResources() pdfDict {
+	return pdfDict{
+		"Font": pdfDict{
+			"F1": pdfDict{
+				"Type":     pdfName("Font"),
+				"Subtype":  pdfName("Type1"),
+				"BaseFont": pdfName("Helvetica"),
+				"Encoding": pdfName("WinAnsiEncoding"),
+			},
+		},
+	}
+}
+
+// pdfLiteral returns s as PDF literal string for content streams using a
+// WinAnsiEncoding font. Characters outside of Latin-1 are replaced by '?'.
+func pdfLiteral(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Limits and defaults of the rasterization settings.
+const (
+	DefaultRasterizeDPI     = 150
+	DefaultRasterizeQuality = 85
+
+	minRasterizeDPI = 36
+	maxRasterizeDPI = 1200
+)
+
+// RasterizeOptions configures Rasterize. Zero values select the defaults.
+type RasterizeOptions struct {
+	// DPI is the render resolution, between 36 and 1200. Legal copies need
+	// 300 DPI or more, 100 DPI keeps email attachments small.
+	DPI int
+	// Quality is the JPEG quality of the page images, between 1 and 100.
+	Quality int
+}
+
+// Rasterize renders every page of the input PDF into a JPEG image and writes
+// a PDF containing only these images to output. The result can't be edited
+// or searched anymore and looks the same in every viewer. Rasterize requires
+// the pdftoppm utility of poppler-utils.
+func Rasterize(input, output string, opts RasterizeOptions) error {
+	if opts.DPI == 0 {
+		opts.DPI = DefaultRasterizeDPI
+	}
+	if opts.Quality == 0 {
+		opts.Quality = DefaultRasterizeQuality
+	}
+	if opts.DPI < minRasterizeDPI || opts.DPI > maxRasterizeDPI {
+		return fmt.Errorf("invalid DPI %d: must be between %d and %d", opts.DPI, minRasterizeDPI, maxRasterizeDPI)
+	}
+	if opts.Quality < 1 || opts.Quality > 100 {
+		return fmt.Errorf("invalid JPEG quality %d: must be between 1 and 100", opts.Quality)
+	}
+
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	images, err := renderPages(input, tmpDir, opts.DPI, opts.Quality)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("pdftoppm rendered no pages: '%s'", input)
+	}
+
+	pages := make([]pdfPage, len(images))
+	for i, path := range images {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if pages[i], err = jpegPage(data, opts.DPI); err != nil {
+			return err
+		}
+	}
+
+	return writePDF(output, pages)
+}
+
+// jpegPage returns a page showing the JPEG image at the given resolution.
+func jpegPage(data []byte, dpi int) (pdfPage, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return pdfPage{}, err
+	}
+
+	colorSpace := pdfName("DeviceRGB")
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		colorSpace = "DeviceGray"
+	case color.CMYKModel:
+		colorSpace = "DeviceCMYK"
+	}
+
+	width := float64(cfg.Width) * 72 / float64(dpi)
+	height := float64(cfg.Height) * 72 / float64(dpi)
+
+	return pdfPage{
+		width:   width,
+		height:  height,
+		content: []byte(fmt.Sprintf("q %.4f 0 0 %.4f 0 0 cm /Im0 Do Q", width, height)),
+		resources: pdfDict{
+			"XObject": pdfDict{
+				"Im0": &pdfStream{
+					dict: pdfDict{
+						"Type":             pdfName("XObject"),
+						"Subtype":          pdfName("Image"),
+						"Width":            cfg.Width,
+						"Height":           cfg.Height,
+						"ColorSpace":       colorSpace,
+						"BitsPerComponent": 8,
+						"Filter":           pdfName("DCTDecode"),
+					},
+					data: data,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,156 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pdftoppmExecutor records the pdftoppm command line and writes a JPEG image
+// of the given size per page, like pdftoppm -jpeg does.
+type pdftoppmExecutor struct {
+	args   []string
+	pages  int
+	width  int
+	height int
+}
+
+// Run implements Executor.
+func (e *pdftoppmExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.args = args
+	prefix := args[len(args)-1]
+	for i := 1; i <= e.pages; i++ {
+		data := testJPEG(e.width, e.height, color.RGBAModel)
+		if err := ioutil.WriteFile(fmt.Sprintf("%s-%d.jpg", prefix, i), data, 0600); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+// testJPEG returns a JPEG image of the given size and color model.
+func testJPEG(width, height int, model color.Model) []byte {
+	var img image.Image = image.NewRGBA(image.Rect(0, 0, width, height))
+	if model == color.GrayModel {
+		img = image.NewGray(image.Rect(0, 0, width, height))
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		panic(err)
+	}
+	return b.Bytes()
+}
+
+func TestRasterizeOptions(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 2)
+
+	tests := []struct {
+		name    string
+		opts    RasterizeOptions
+		args    string
+		size    float64
+		wantErr bool
+	}{
+		{name: "defaults", args: "-jpeg -jpegopt quality=85 -r 150", size: 300 * 72 / 150},
+		{name: "legal copy", opts: RasterizeOptions{DPI: 300, Quality: 95}, args: "-jpeg -jpegopt quality=95 -r 300", size: 300 * 72 / 300},
+		{name: "email", opts: RasterizeOptions{DPI: 100, Quality: 50}, args: "-jpeg -jpegopt quality=50 -r 100", size: 300 * 72 / 100},
+		{name: "DPI too low", opts: RasterizeOptions{DPI: 35}, wantErr: true},
+		{name: "DPI too high", opts: RasterizeOptions{DPI: 1201}, wantErr: true},
+		{name: "quality too high", opts: RasterizeOptions{Quality: 101}, wantErr: true},
+		{name: "negative quality", opts: RasterizeOptions{Quality: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &pdftoppmExecutor{pages: 2, width: 300, height: 300}
+			useExecutor(t, e)
+			output := filepath.Join(t.TempDir(), "output.pdf")
+
+			err := Rasterize(input, output, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Rasterize succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if args := strings.Join(e.args, " "); !strings.HasPrefix(args, tt.args+" ") {
+				t.Errorf("pdftoppm args = %q, want %q", args, tt.args)
+			}
+
+			data, err := ioutil.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := parseTestPDF(t, bytes.NewReader(data))
+			refs := doc.pages()
+			if len(refs) != 2 {
+				t.Fatalf("pages = %d, want 2", len(refs))
+			}
+			if box := doc.mediaBox(doc.dict(refs[0])); box[2] != tt.size || box[3] != tt.size {
+				t.Errorf("page size = %v x %v, want %v", box[2], box[3], tt.size)
+			}
+		})
+	}
+}
+
+func TestJPEGPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      color.Model
+		colorSpace pdfName
+	}{
+		{"rgb", color.RGBAModel, "DeviceRGB"},
+		{"gray", color.GrayModel, "DeviceGray"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testJPEG(200, 100, tt.model)
+			page, err := jpegPage(data, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.width != 72 || page.height != 36 {
+				t.Errorf("page size = %v x %v, want 72 x 36", page.width, page.height)
+			}
+			img := page.resources["XObject"].(pdfDict)["Im0"].(*pdfStream)
+			if img.dict["ColorSpace"] != tt.colorSpace {
+				t.Errorf("color space = %v, want %v", img.dict["ColorSpace"], tt.colorSpace)
+			}
+			if !bytes.Equal(img.data, data) {
+				t.Error("image data is not the JPEG file")
+			}
+		})
+	}
+
+	if _, err := jpegPage([]byte("no image"), 150); err == nil {
+		t.Error("jpegPage of invalid data succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// renderPages renders all pages of the PDF with pdftoppm into image files
+// in dir and returns their paths in page order. The pages are rendered as
+// JPEG of the given quality (1-100), or as PNG if quality is 0.
+func renderPages(pdfFile, dir string, dpi, jpegQuality int) ([]string, error) {
+	// Check if the pdftoppm utility exists.
+	if err := lookPath("pdftoppm"); err != nil {
+		return nil, fmt.Errorf("pdftoppm (poppler-utils) is required to render PDF pages: %v", err)
+	}
+
+	pdfFile, err := getAbs(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the pdftoppm command line arguments.
+	args := []string{"-png"}
+	ext := ".png"
+	if jpegQuality > 0 {
+		args = []string{"-jpeg", "-jpegopt", "quality=" + strconv.Itoa(jpegQuality)}
+		ext = ".jpg"
+	}
+	prefix := filepath.Join(dir, "page")
+	args = append(args, "-r", strconv.Itoa(dpi), pdfFile, prefix)
+
+	// Run the pdftoppm utility.
+	if err := runCommandInPath(dir, "pdftoppm", args...); err != nil {
+		return nil, fmt.Errorf("pdftoppm error: %v", err)
+	}
+
+	// The page numbers are zero padded to the same width, so sorting the
+	// names sorts the pages.
+	files, err := filepath.Glob(prefix + "-*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readPNG decodes the PNG image file.
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
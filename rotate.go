@@ -0,0 +1,157 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rotateDirections are the pdftk page rotation keywords. north, east, south
+// and west are absolute, left, right and down relative to the current rotation.
+var rotateDirections = map[string]bool{
+	"north": true,
+	"east":  true,
+	"south": true,
+	"west":  true,
+	"left":  true,
+	"right": true,
+	"down":  true,
+}
+
+// RotateAll rotates every page of the input PDF in the given direction and
+// writes the result to output. The direction is one of the pdftk keywords
+// north, east, south, west (absolute) or left, right, down (relative).
+func RotateAll(input, direction, output string) error {
+	if !rotateDirections[direction] {
+		return fmt.Errorf("invalid rotation direction: '%s'", direction)
+	}
+
+	return runPdftkFile(input, output, "rotate", "1-end"+direction)
+}
+
+// Rotation is a clockwise page rotation of RotatePages.
+type Rotation int
+
+const (
+	// Rotation90 turns the pages a quarter clockwise.
+	Rotation90 Rotation = 90
+	// Rotation180 turns the pages upside down.
+	Rotation180 Rotation = 180
+	// Rotation270 turns the pages a quarter counterclockwise.
+	Rotation270 Rotation = 270
+)
+
+// rotateDegrees maps clockwise rotations to the absolute pdftk keywords.
+var rotateDegrees = map[Rotation]string{
+	Rotation90:  "east",
+	Rotation180: "south",
+	Rotation270: "west",
+}
+
+// PageRange is a pdftk page range spec of RotatePages like "1-3 7" or
+// "2-endeven". The ranges must not carry a pdftk rotation keyword like
+// "1-3east", the rotation is given by the Rotation.
+type PageRange string
+
+// pageRangeRegex matches a single pdftk page range without a rotation, like
+// "3", "1-5", "r1", "2-endeven" or "1-endodd".
+var pageRangeRegex = regexp.MustCompile(`^(r?[0-9]+|r?end)(-(r?[0-9]+|r?end))?(even|odd)?$`)
+
+// Rotate rotates the given pages of the PDF file by 90, 180 or 270 degrees
+// clockwise and returns a reader to the rotated PDF. The pages are a pdftk
+// page range spec like "1-3 7" or "2-endeven", an empty string selects all
+// pages. Pages not selected are left unchanged.
+func Rotate(pdfFile string, degrees int, pages string) (io.Reader, error) {
+	if pages == "" {
+		pages = "1-end"
+	}
+	return RotatePages(pdfFile, map[PageRange]Rotation{PageRange(pages): Rotation(degrees)})
+}
+
+// RotatePages rotates the pages of the PDF file by different angles, e.g.
+// scanned pages which came in sideways, and returns a reader to the rotated
+// PDF. The keys are the page ranges, the values one of the rotations
+// Rotation90, Rotation180 or Rotation270. Pages not selected are left
+// unchanged.
+func RotatePages(pdfFile string, rotations map[PageRange]Rotation) (io.Reader, error) {
+	// Sort the page ranges for a stable command line.
+	specs := make([]PageRange, 0, len(rotations))
+	for spec := range rotations {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i] < specs[j]
+	})
+
+	operation := []string{"rotate"}
+	for _, spec := range specs {
+		direction, ok := rotateDegrees[rotations[spec]]
+		if !ok {
+			return nil, fmt.Errorf("invalid rotation: %d degrees, must be 90, 180 or 270", rotations[spec])
+		}
+
+		ranges := strings.FieldsFunc(string(spec), func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		if len(ranges) == 0 {
+			return nil, fmt.Errorf("invalid page range: '%s'", spec)
+		}
+		for _, r := range ranges {
+			if !pageRangeRegex.MatchString(r) {
+				return nil, fmt.Errorf("invalid page range: '%s'", r)
+			}
+			operation = append(operation, r+direction)
+		}
+	}
+	if len(operation) == 1 {
+		return nil, fmt.Errorf("no pages to rotate")
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	outputFile := filepath.Clean(tmpDir + "/rotated.pdf")
+	if err := runPdftkFile(pdfFile, outputFile, operation...); err != nil {
+		return nil, err
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
@@ -0,0 +1,154 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageRangeRegex(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{"3", true},
+		{"1-5", true},
+		{"r1", true},
+		{"end", true},
+		{"2-endeven", true},
+		{"1-endodd", true},
+		{"r3-r1", true},
+		{"", false},
+		{"1-", false},
+		{"-3", false},
+		{"1-5east", false},
+		{"a", false},
+		{"1 2", false},
+	}
+	for _, tt := range tests {
+		if valid := pageRangeRegex.MatchString(tt.spec); valid != tt.valid {
+			t.Errorf("pageRangeRegex.MatchString(%q) = %v, want %v", tt.spec, valid, tt.valid)
+		}
+	}
+}
+
+func TestRotateAllCommandLine(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 2)
+	output := filepath.Join(t.TempDir(), "output.pdf")
+
+	tests := []struct {
+		direction string
+		want      string
+		wantErr   bool
+	}{
+		{direction: "east", want: "rotate 1-endeast"},
+		{direction: "left", want: "rotate 1-endleft"},
+		{direction: "down", want: "rotate 1-enddown"},
+		{direction: "90", wantErr: true},
+		{direction: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			e := &recordExecutor{output: []byte("%PDF-1.4")}
+			useExecutor(t, e)
+
+			err := RotateAll(input, tt.direction, output)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RotateAll(%q) succeeded", tt.direction)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if call := strings.Join(e.lastCall(), " "); !strings.Contains(call, " "+tt.want+" output ") {
+				t.Errorf("pdftk call = %q, want %q", call, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotatePagesInvalid(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 2)
+
+	tests := []map[PageRange]Rotation{
+		{"1": 45},
+		{"1": 0},
+		{"1-x": Rotation90},
+		{"1-5east": Rotation90},
+		{" ": Rotation90},
+		{},
+	}
+	for _, rotations := range tests {
+		if _, err := RotatePages(input, rotations); err == nil {
+			t.Errorf("RotatePages(%v) succeeded", rotations)
+		}
+	}
+}
+
+func TestRotatePagesCommandLine(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 8)
+
+	tests := []struct {
+		rotations map[PageRange]Rotation
+		want      string
+	}{
+		{map[PageRange]Rotation{"1-end": Rotation90}, "rotate 1-endeast"},
+		{map[PageRange]Rotation{"3": Rotation270, "1-2": Rotation180}, "rotate 1-2south 3west"},
+		{map[PageRange]Rotation{"1 4,6-endeven": Rotation90}, "rotate 1east 4east 6-endeveneast"},
+	}
+	for _, tt := range tests {
+		e := &recordExecutor{output: []byte("%PDF-1.4")}
+		useExecutor(t, e)
+
+		if _, err := RotatePages(input, tt.rotations); err != nil {
+			t.Fatal(err)
+		}
+		if call := strings.Join(e.lastCall(), " "); !strings.Contains(call, " "+tt.want+" output ") {
+			t.Errorf("RotatePages(%v): pdftk call = %q, want %q", tt.rotations, call, tt.want)
+		}
+	}
+}
+
+func TestRotateAll(t *testing.T) {
+	requirePDFTK(t)
+	input := writeTestPDF(t, "input.pdf", 2)
+	output := filepath.Join(t.TempDir(), "output.pdf")
+
+	if err := RotateAll(input, "east", output); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loadPDFFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := doc.pages()
+	if len(refs) != 2 {
+		t.Fatalf("pages = %d, want 2", len(refs))
+	}
+	for i, ref := range refs {
+		if rotate, _ := doc.number(doc.inherited(doc.dict(ref), "Rotate")); rotate != 90 {
+			t.Errorf("page %d rotation = %v, want 90", i+1, rotate)
+		}
+	}
+}
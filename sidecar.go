@@ -0,0 +1,157 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveForm writes the form values as JSON (see FormJson) to path.
+func SaveForm(form Form, path string) error {
+	data, err := json.MarshalIndent(FormJson{Form: form}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// SidecarPath returns the default path of the JSON sidecar of a PDF file,
+// which is the PDF file path with the extension replaced by ".json".
+func SidecarPath(pdfFile string) string {
+	return strings.TrimSuffix(pdfFile, filepath.Ext(pdfFile)) + ".json"
+}
+
+// FillWithSidecar fills and flattens the PDF form like Fill and saves the form
+// values as JSON sidecar next to it. If sidecarFile is empty, SidecarPath of
+// the destination is used. Both files are created as temporary files first and
+// moved into place together, so on failure neither of them is written and
+// existing files are kept.
+func FillWithSidecar(form Form, formPDFFile, destPDFFile, sidecarFile, checkedString, uncheckedString string, overwrite bool, opts ...Option) error {
+	var err error
+
+	if destPDFFile, err = filepath.Abs(destPDFFile); err != nil {
+		return err
+	}
+	if sidecarFile == "" {
+		sidecarFile = SidecarPath(destPDFFile)
+	} else if sidecarFile, err = filepath.Abs(sidecarFile); err != nil {
+		return err
+	}
+
+	// Check if the destination files exist.
+	if !overwrite {
+		for _, f := range []string{destPDFFile, sidecarFile} {
+			e, err := exists(f)
+			if err != nil {
+				return err
+			} else if e {
+				return fmt.Errorf("%w: '%s'", ErrDestExists, f)
+			}
+		}
+	}
+
+	id, err := GetID("tmp")
+	if err != nil {
+		return err
+	}
+	tmpPDFFile := destPDFFile + "." + id
+	tmpSidecarFile := sidecarFile + "." + id
+
+	// Remove the temporary files on defer again.
+	defer func() {
+		os.Remove(tmpPDFFile)
+		os.Remove(tmpSidecarFile)
+	}()
+
+	if err := Fill(form, formPDFFile, tmpPDFFile, checkedString, uncheckedString, true, opts...); err != nil {
+		return err
+	}
+	if err := SaveForm(form, tmpSidecarFile); err != nil {
+		return err
+	}
+
+	return renameAll([][2]string{
+		{tmpPDFFile, destPDFFile},
+		{tmpSidecarFile, sidecarFile},
+	})
+}
+
+// renameAll renames all files or none of them. Replaced destination files are
+// backed up under a unique name, so no other file is touched, and restored if
+// a later rename fails.
+func renameAll(files [][2]string) (err error) {
+	type done struct {
+		dst, backup string
+	}
+	var renamed []done
+
+	defer func() {
+		if err == nil {
+			for _, d := range renamed {
+				if d.backup != "" {
+					os.Remove(d.backup)
+				}
+			}
+			return
+		}
+
+		// Roll back in reverse order.
+		for i := len(renamed) - 1; i >= 0; i-- {
+			d := renamed[i]
+			os.Remove(d.dst)
+			if d.backup != "" {
+				os.Rename(d.backup, d.dst)
+			}
+		}
+	}()
+
+	for _, f := range files {
+		d := done{dst: f[1]}
+
+		e, err := exists(f[1])
+		if err != nil {
+			return err
+		} else if e {
+			id, err := GetID("bak")
+			if err != nil {
+				return err
+			}
+			d.backup = f[1] + "." + id
+			if err := os.Rename(f[1], d.backup); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Rename(f[0], f[1]); err != nil {
+			if d.backup != "" {
+				os.Rename(d.backup, f[1])
+			}
+			return err
+		}
+		renamed = append(renamed, d)
+	}
+
+	return nil
+}
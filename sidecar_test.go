@@ -0,0 +1,90 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameAllKeepsExistingBackups(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	dest := write("out.pdf", "old pdf")
+	backup := write("out.pdf.bak", "user backup")
+	tmp := write("out.pdf.tmp", "new pdf")
+
+	if err := renameAll([][2]string{{tmp, dest}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(dest); got != "new pdf" {
+		t.Errorf("destination = %q, want %q", got, "new pdf")
+	}
+	if got := read(backup); got != "user backup" {
+		t.Errorf("existing backup = %q, want %q", got, "user backup")
+	}
+}
+
+func TestRenameAllRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "out.pdf")
+	tmp := filepath.Join(dir, "out.pdf.tmp")
+	if err := ioutil.WriteFile(dest, []byte("old pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tmp, []byte("new pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second source is missing, so the first rename is rolled back.
+	err := renameAll([][2]string{
+		{tmp, dest},
+		{filepath.Join(dir, "missing.json.tmp"), filepath.Join(dir, "out.json")},
+	})
+	if err == nil {
+		t.Fatal("renameAll succeeded with a missing source")
+	}
+	data, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old pdf" {
+		t.Errorf("destination = %q, want the original", data)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Errorf("files after rollback = %v, want only out.pdf", files)
+	}
+}
@@ -0,0 +1,341 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Burst splits the PDF into single pages and returns a reader per page in
+// page order. The number of pages is the number of readers.
+func Burst(pdfFile string) ([]io.Reader, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the pdftk command line arguments. pdftk writes a doc_data.txt
+	// file into the working directory too.
+	args := []string{
+		pdfFile,
+		"burst",
+		"output", filepath.Join(tmpDir, "page_%04d.pdf"),
+	}
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	// The page numbers are zero padded, so sorting the names sorts the pages.
+	files, err := filepath.Glob(filepath.Join(tmpDir, "page_*.pdf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	pages := make([]io.Reader, len(files))
+	for i, f := range files {
+		fb, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		pages[i] = bytes.NewReader(fb)
+	}
+
+	return pages, nil
+}
+
+// SplitEvery splits the PDF into chunks of n pages and returns a reader per
+// chunk in page order. The last chunk may have fewer pages.
+func SplitEvery(pdfFile string, n int) ([]io.Reader, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid page count: %d", n)
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	pdfFile, err := getAbs(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	return splitChunks(pdfFile, tmpDir, n)
+}
+
+// splitChunks splits the PDF into chunks of at most maxPages pages. The
+// chunks are written to tmpDir.
+func splitChunks(pdfFile, tmpDir string, maxPages int) ([]io.Reader, error) {
+	numPages, err := NumPages(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []io.Reader
+	for first := 1; first <= numPages; first += maxPages {
+		last := first + maxPages - 1
+		if last > numPages {
+			last = numPages
+		}
+
+		// Create the pdftk command line arguments.
+		outputFile := filepath.Join(tmpDir, fmt.Sprintf("chunk_%d.pdf", len(chunks)))
+		args := []string{
+			pdfFile,
+			"cat", fmt.Sprintf("%d-%d", first, last),
+			"output", outputFile,
+		}
+
+		// Run the pdftk utility.
+		err = runCommandInPath(tmpDir, "pdftk", args...)
+		if err != nil {
+			return nil, fmt.Errorf("pdftk error: %w", err)
+		}
+
+		fb, err := ioutil.ReadFile(outputFile)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, bytes.NewReader(fb))
+	}
+
+	return chunks, nil
+}
+
+// burstPatternRegex matches a burst file name pattern with a single page
+// number verb, like "page_%04d.pdf".
+var burstPatternRegex = regexp.MustCompile(`^[^%/\\]*%0?[0-9]*d[^%/\\]*$`)
+
+// DocData is the document data of pdftk dump_data, which pdftk also writes
+// when bursting a PDF.
+type DocData struct {
+	Info     map[string]string
+	NumPages int
+	// PageSizes are the widths and heights of the pages in points.
+	PageSizes [][2]float64
+	Bookmarks []Bookmark
+}
+
+// GetDocData returns the document data of the PDF, like the info entries,
+// the page count and the page sizes.
+func GetDocData(pdfFile string) (*DocData, error) {
+	data, err := dumpData(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	return data.docData(), nil
+}
+
+// BurstToDir splits the PDF into single page files in outputDir and returns
+// their paths in page order together with the document data. The file names
+// are created from the pattern with the page number, starting at 1, like
+// "page_%04d.pdf", which is the default for an empty pattern. Existing files
+// are replaced.
+func BurstToDir(pdfFile, outputDir, pattern string) ([]string, *DocData, error) {
+	var err error
+
+	if pattern == "" {
+		pattern = "page_%04d.pdf"
+	} else if !burstPatternRegex.MatchString(pattern) {
+		return nil, nil, fmt.Errorf("invalid burst pattern: '%s'", pattern)
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, nil, err
+	}
+	if outputDir, err = filepath.Abs(outputDir); err != nil {
+		return nil, nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the pdftk command line arguments. pdftk writes the doc_data.txt
+	// file into the working directory, which is the temporary directory.
+	args := []string{
+		pdfFile,
+		"burst",
+		"output", filepath.Join(outputDir, pattern),
+	}
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(tmpDir, "doc_data.txt"))
+	if err != nil {
+		return nil, nil, err
+	}
+	// pdftk writes doc_data.txt like the plain dump_data operation.
+	data := parseDumpData(out, true)
+
+	files := make([]string, data.numPages)
+	for i := range files {
+		files[i] = filepath.Join(outputDir, fmt.Sprintf(pattern, i+1))
+	}
+
+	return files, data.docData(), nil
+}
+
+// SplitByBookmarks splits the input PDF into one file per bookmark of the
+// given outline level (1 for the top-level bookmarks) and returns the paths
+// of the files written to outputDir. A file contains the pages from its
+// bookmark to the next bookmark of the same or a higher level. Pages before
+// the first bookmark are not part of any file. The files are named by the
+// position and sanitized title of their bookmark, e.g. "02_Installation.pdf".
+func SplitByBookmarks(input, outputDir string, level int) ([]string, error) {
+	if level < 1 {
+		return nil, fmt.Errorf("invalid bookmark level: %d", level)
+	}
+
+	data, err := dumpData(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.bookmarks) == 0 {
+		return nil, fmt.Errorf("PDF has no bookmarks: '%s'", input)
+	}
+
+	// Collect the bookmarks starting a part. Deeper bookmarks don't end one.
+	var parts []Bookmark
+	for _, b := range data.bookmarks {
+		if b.Level <= level && b.Page > 0 {
+			parts = append(parts, b)
+		}
+	}
+
+	var chapters int
+	for _, b := range parts {
+		if b.Level == level {
+			chapters++
+		}
+	}
+	if chapters == 0 {
+		return nil, fmt.Errorf("PDF has no bookmarks at level %d: '%s'", level, input)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for i, b := range parts {
+		if b.Level != level {
+			continue
+		}
+
+		last := data.numPages
+		if i+1 < len(parts) {
+			last = parts[i+1].Page - 1
+		}
+		if last < b.Page {
+			last = b.Page
+		}
+
+		name := fmt.Sprintf("%0*d_%s.pdf", len(fmt.Sprint(chapters)), len(files)+1, sanitizeFileName(b.Title))
+		output := filepath.Join(outputDir, name)
+		if err := runPdftkFile(input, output, "cat", fmt.Sprintf("%d-%d", b.Page, last)); err != nil {
+			return nil, err
+		}
+		files = append(files, output)
+	}
+
+	return files, nil
+}
+
+// sanitizeFileName replaces all characters except letters, digits, '-' and
+// '_' of the title with underscores and shortens it to 64 characters.
+func sanitizeFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(title))
+
+	// Collapse the underscores of replaced character sequences.
+	for strings.Contains(name, "__") {
+		name = strings.Replace(name, "__", "_", -1)
+	}
+	if r := []rune(name); len(r) > 64 {
+		name = string(r[:64])
+	}
+	name = strings.Trim(name, "_")
+	if name == "" {
+		name = "untitled"
+	}
+	return name
+}
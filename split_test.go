@@ -0,0 +1,162 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Installation", "Installation"},
+		{"  Getting Started  ", "Getting_Started"},
+		{"1. Einführung: Überblick", "1_Einführung_Überblick"},
+		{"a/b\\c", "a_b_c"},
+		{"(!)", "untitled"},
+		{"", "untitled"},
+		{strings.Repeat("x", 70), strings.Repeat("x", 64)},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFileName(tt.title); got != tt.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+// bookmarkDump is the dump_data output of an 8 page document with three
+// chapters and two sections.
+const bookmarkDump = `BookmarkBegin
+BookmarkTitle: Intro
+BookmarkLevel: 1
+BookmarkPageNumber: 1
+BookmarkBegin
+BookmarkTitle: Details
+BookmarkLevel: 2
+BookmarkPageNumber: 2
+BookmarkBegin
+BookmarkTitle: Setup
+BookmarkLevel: 1
+BookmarkPageNumber: 3
+BookmarkBegin
+BookmarkTitle: Config
+BookmarkLevel: 2
+BookmarkPageNumber: 5
+BookmarkBegin
+BookmarkTitle: Appendix
+BookmarkLevel: 1
+BookmarkPageNumber: 7
+NumberOfPages: 8
+`
+
+func TestSplitByBookmarksRanges(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+
+	tests := []struct {
+		level  int
+		files  []string
+		ranges []string
+	}{
+		{1, []string{"1_Intro.pdf", "2_Setup.pdf", "3_Appendix.pdf"}, []string{"1-2", "3-6", "7-8"}},
+		{2, []string{"1_Details.pdf", "2_Config.pdf"}, []string{"2-2", "5-6"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.files, " "), func(t *testing.T) {
+			e := &recordExecutor{stdout: []byte(bookmarkDump), output: []byte("%PDF-1.4")}
+			useExecutor(t, e)
+			outputDir := t.TempDir()
+
+			files, err := SplitByBookmarks(input, outputDir, tt.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range files {
+				files[i] = filepath.Base(files[i])
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("files = %q, want %q", files, tt.files)
+			}
+
+			var ranges []string
+			for _, call := range e.calls {
+				if len(call) > 3 && call[2] == "cat" {
+					ranges = append(ranges, call[3])
+				}
+			}
+			if !reflect.DeepEqual(ranges, tt.ranges) {
+				t.Errorf("ranges = %q, want %q", ranges, tt.ranges)
+			}
+		})
+	}
+}
+
+func TestSplitByBookmarksInvalid(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+
+	tests := []struct {
+		name  string
+		dump  string
+		level int
+	}{
+		{"level 0", bookmarkDump, 0},
+		{"no bookmarks", "NumberOfPages: 8\n", 1},
+		{"no bookmarks at level", bookmarkDump, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExecutor(t, &recordExecutor{stdout: []byte(tt.dump)})
+			if _, err := SplitByBookmarks(input, t.TempDir(), tt.level); err == nil {
+				t.Error("SplitByBookmarks succeeded")
+			}
+		})
+	}
+}
+
+func TestSplitByBookmarks(t *testing.T) {
+	requirePDFTK(t)
+	input := writeTestPDF(t, "input.pdf", 6)
+	bookmarks := []Bookmark{
+		{Title: "Chapter 1", Level: 1, Page: 1},
+		{Title: "Chapter 2", Level: 1, Page: 3},
+		{Title: "Section 2.1", Level: 2, Page: 4},
+		{Title: "Chapter 3", Level: 1, Page: 6},
+	}
+	if err := SetBookmarks(input, input, bookmarks); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := SplitByBookmarks(input, t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("files = %q, want 3", files)
+	}
+	for i, want := range []int{2, 3, 1} {
+		if n, err := NumPages(files[i]); err != nil || n != want {
+			t.Errorf("%s pages = %d, %v, want %d", filepath.Base(files[i]), n, err, want)
+		}
+	}
+}
@@ -21,20 +21,277 @@ package fillpdf
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 )
 
 // Multistamp stamps one PDF ontop of another, returns a reader to bytes generated.
-func Multistamp(stampontoPDFFile, stampPDFFile string) (io.Reader, error) {
+// The options WithBackend, WithTempDir, WithInputPassword and
+// WithOutputOptions apply to all stamp and background functions.
+func Multistamp(stampontoPDFFile, stampPDFFile string, opts ...Option) (io.Reader, error) {
+	return MultistampContext(context.Background(), stampontoPDFFile, stampPDFFile, opts...)
+}
+
+// MultistampContext is like Multistamp, but kills pdftk once the context is done.
+func MultistampContext(ctx context.Context, stampontoPDFFile, stampPDFFile string, opts ...Option) (io.Reader, error) {
+	return stampContext(ctx, StampModeMultistamp, stampontoPDFFile, stampPDFFile, newOptions(opts))
+}
+
+// MultistampToWriter is like Multistamp, but reads the PDFs from the readers
+// and streams the stamped PDF to w instead of buffering it in memory.
+// WithInputPassword opens the PDF to stamp onto, WithOutputOptions,
+// WithTempDir, WithProgress and WithBackend apply like for a fill.
+func MultistampToWriter(stampontoPDF, stampPDF io.Reader, w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	if err := o.output.validate(); err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	stampontoPDFFile, err := spoolReader(stampontoPDF, tmpDir, "input.pdf")
+	if err != nil {
+		return err
+	}
+	stampPDFFile, err := spoolReader(stampPDF, tmpDir, "stamp.pdf")
+	if err != nil {
+		return err
+	}
+
+	if sb := o.stampBackend(); sb != nil {
+		r, err := stampWithBackend(context.Background(), sb, StampModeMultistamp, stampontoPDFFile, stampPDFFile, o)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(o.progressWriter(w), r)
+		return err
+	}
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return err
+	}
+
+	// Run the pdftk utility.
+	args := append(pdftkInput(stampontoPDFFile, o.password),
+		"multistamp", stampPDFFile,
+		"output", "-",
+	)
+	args = append(args, o.output.args()...)
+	if err := runCommandToWriter(tmpDir, o.progressWriter(w), "pdftk", args...); err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+	return nil
+}
+
+// MultistampWithPassword is like Multistamp for a password protected PDF to
+// stamp onto. The password is never part of returned errors.
+func MultistampWithPassword(stampontoPDFFile, stampPDFFile, password string) (io.Reader, error) {
+	return Multistamp(stampontoPDFFile, stampPDFFile, WithInputPassword(password))
+}
+
+// Multibackground puts one PDF behind another page by page like Multistamp,
+// e.g. a letterhead behind the text. The background is only visible where
+// the pages are transparent.
+func Multibackground(backgroundontoPDFFile, backgroundPDFFile string, opts ...Option) (io.Reader, error) {
+	return MultibackgroundContext(context.Background(), backgroundontoPDFFile, backgroundPDFFile, opts...)
+}
+
+// MultibackgroundContext is like Multibackground, but kills pdftk once the
+// context is done.
+func MultibackgroundContext(ctx context.Context, backgroundontoPDFFile, backgroundPDFFile string, opts ...Option) (io.Reader, error) {
+	return stampContext(ctx, StampModeMultibackground, backgroundontoPDFFile, backgroundPDFFile, newOptions(opts))
+}
+
+// Stamp stamps the first page of the stamp PDF on top of every page of the
+// other PDF, while Multistamp maps the stamp pages to the pages one by one.
+func Stamp(stampontoPDFFile, stampPDFFile string, opts ...Option) (io.Reader, error) {
+	return StampContext(context.Background(), stampontoPDFFile, stampPDFFile, opts...)
+}
+
+// StampContext is like Stamp, but kills pdftk once the context is done.
+func StampContext(ctx context.Context, stampontoPDFFile, stampPDFFile string, opts ...Option) (io.Reader, error) {
+	return stampContext(ctx, StampModeStamp, stampontoPDFFile, stampPDFFile, newOptions(opts))
+}
+
+// StampPages is like Stamp, but only stamps the pages selected by the pdftk
+// page ranges like "1", "2-end" or "1-endodd", separated by spaces or commas.
+// The other pages are left unchanged. An empty string selects all pages.
+// Selecting pages always requires pdftk. The options apply like for Stamp.
+func StampPages(stampontoPDFFile, stampPDFFile, pages string, opts ...Option) (io.Reader, error) {
+	var err error
+	if pages == "" {
+		return Stamp(stampontoPDFFile, stampPDFFile, opts...)
+	}
+
+	o := newOptions(opts)
+	if err := o.output.validate(); err != nil {
+		return nil, err
+	}
+
+	if stampontoPDFFile, err = getAbs(stampontoPDFFile); err != nil {
+		return nil, err
+	}
+
+	data, err := dumpDataWithPassword(stampontoPDFFile, o.password)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectPages(pages, data.numPages)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stamped pages are encrypted with the output options at the end.
+	so := *o
+	so.output = OutputOptions{}
+	stamped, err := stampContext(context.Background(), StampModeStamp, stampontoPDFFile, stampPDFFile, &so)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	stampedFile, err := spoolReader(stamped, tmpDir, "stamped.pdf")
+	if err != nil {
+		return nil, err
+	}
+
+	// Take the selected pages from the stamped file B and the others from
+	// the original A, joining consecutive pages into ranges.
+	args := []string{"A=" + stampontoPDFFile, "B=" + stampedFile}
+	if o.password != "" {
+		args = append(args, "input_pw", "A="+o.password)
+	}
+	args = append(args, "cat")
+	for first := 0; first < len(selected); {
+		last := first
+		for last+1 < len(selected) && selected[last+1] == selected[first] {
+			last++
+		}
+		handle := "A"
+		if selected[first] {
+			handle = "B"
+		}
+		args = append(args, fmt.Sprintf("%s%d-%d", handle, first+1, last+1))
+		first = last + 1
+	}
+
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	args = append(append(args, "output", outputFile), o.output.args()...)
+
+	// Run the pdftk utility.
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
+
+// Background puts the first page of the background PDF behind every page of
+// the other PDF, like Stamp.
+func Background(backgroundontoPDFFile, backgroundPDFFile string, opts ...Option) (io.Reader, error) {
+	return BackgroundContext(context.Background(), backgroundontoPDFFile, backgroundPDFFile, opts...)
+}
+
+// BackgroundContext is like Background, but kills pdftk once the context is
+// done.
+func BackgroundContext(ctx context.Context, backgroundontoPDFFile, backgroundPDFFile string, opts ...Option) (io.Reader, error) {
+	return stampContext(ctx, StampModeBackground, backgroundontoPDFFile, backgroundPDFFile, newOptions(opts))
+}
+
+// stampContext stamps with the Backend of the call, if it is a StampBackend
+// and neither an input password nor output options are given, or else with
+// pdftk.
+func stampContext(ctx context.Context, mode StampMode, stampontoPDFFile, stampPDFFile string, o *options) (io.Reader, error) {
+	if err := o.output.validate(); err != nil {
+		return nil, err
+	}
+	if sb := o.stampBackend(); sb != nil {
+		return stampWithBackend(ctx, sb, mode, stampontoPDFFile, stampPDFFile, o)
+	}
+	return pdftkStamp(ctx, string(mode), stampontoPDFFile, stampPDFFile, o)
+}
+
+// stampBackend returns the StampBackend of the call, or nil if it has none or
+// pdftk is required for the password or the output options.
+func (o *options) stampBackend() StampBackend {
+	sb, ok := o.getBackend().(StampBackend)
+	if !ok || o.password != "" || len(o.output.args()) > 0 {
+		return nil
+	}
+	return sb
+}
+
+// stampWithBackend stamps the PDF with the StampBackend.
+func stampWithBackend(ctx context.Context, sb StampBackend, mode StampMode, stampontoPDFFile, stampPDFFile string, o *options) (io.Reader, error) {
+	var err error
+	if stampontoPDFFile, err = getAbs(stampontoPDFFile); err != nil {
+		return nil, err
+	}
+	if stampPDFFile, err = getAbs(stampPDFFile); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	if err := sb.Stamp(ctx, stampontoPDFFile, stampPDFFile, outputFile, mode); err != nil {
+		return nil, err
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
+
+// pdftkStamp runs the pdftk stamp, multistamp, background or multibackground
+// operation.
+func pdftkStamp(ctx context.Context, operation, stampontoPDFFile, stampPDFFile string, o *options) (io.Reader, error) {
 	var err error
 
 	// Check if the pdftk utility exists.
-	if _, err := exec.LookPath("pdftk"); err != nil {
+	if err := lookPath("pdftk"); err != nil {
 		return nil, err
 	}
 
@@ -48,7 +305,7 @@ func Multistamp(stampontoPDFFile, stampPDFFile string) (io.Reader, error) {
 	}
 
 	// Create a temporary directory.
-	tmpDir, err := ioutil.TempDir("", "fillpdf-")
+	tmpDir, err := createTempDir(o.tempDir)
 	if err != nil {
 		return nil, err
 	}
@@ -62,16 +319,16 @@ func Multistamp(stampontoPDFFile, stampPDFFile string) (io.Reader, error) {
 	outputFile := filepath.Clean(tmpDir + "/output.pdf")
 
 	// Create the pdftk command line arguments.
-	args := []string{
-		stampontoPDFFile,
-		"multistamp", stampPDFFile,
+	args := append(pdftkInput(stampontoPDFFile, o.password),
+		operation, stampPDFFile,
 		"output", outputFile,
-	}
+	)
+	args = append(args, o.output.args()...)
 
 	// Run the pdftk utility.
-	err = runCommandInPath(tmpDir, "pdftk", args...)
+	err = runCommandInPathContext(ctx, tmpDir, "pdftk", args...)
 	if err != nil {
-		return nil, fmt.Errorf("pdftk error: %v", err)
+		return nil, fmt.Errorf("pdftk error: %w", err)
 	}
 
 	fb, err := ioutil.ReadFile(outputFile)
@@ -81,3 +338,66 @@ func Multistamp(stampontoPDFFile, stampPDFFile string) (io.Reader, error) {
 
 	return bytes.NewReader(fb), nil
 }
+
+// DiffOverlay stamps the overlay PDF semi-transparently on top of the base PDF,
+// page by page like Multistamp, so differences between both versions become
+// visible. The opacity of the overlay must be between 0 and 1.
+func DiffOverlay(basePDFFile, overlayPDFFile string, opacity float64) (io.Reader, error) {
+	if opacity < 0 || opacity > 1 {
+		return nil, fmt.Errorf("invalid opacity: %v", opacity)
+	}
+
+	doc, err := loadPDFFile(overlayPDFFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Write the transparent overlay.
+	overlayFile := filepath.Clean(tmpDir + "/overlay.pdf")
+	if err := transparentPages(doc, opacity).writeFile(overlayFile); err != nil {
+		return nil, err
+	}
+
+	return Multistamp(basePDFFile, overlayFile)
+}
+
+// transparentPages wraps the content of all pages into a graphics state with
+// the given fill and stroke opacity.
+func transparentPages(doc *pdfDocument, opacity float64) *pdfUpdate {
+	u := doc.update()
+
+	gs := u.add(pdfDict{
+		"Type": pdfName("ExtGState"),
+		"CA":   opacity,
+		"ca":   opacity,
+	})
+	begin := u.add(&pdfStream{dict: pdfDict{}, data: []byte("q /FillPDFOpacity gs\n")})
+	end := u.add(&pdfStream{dict: pdfDict{}, data: []byte("\nQ")})
+
+	for _, ref := range doc.pages() {
+		page := copyDict(doc.dict(ref))
+
+		resources := copyDict(doc.dict(doc.inherited(page, "Resources")))
+		extGState := copyDict(doc.dict(resources["ExtGState"]))
+		extGState["FillPDFOpacity"] = gs
+		resources["ExtGState"] = extGState
+		page["Resources"] = resources
+
+		contents := append(pdfArray{begin}, doc.pageContents(page)...)
+		page["Contents"] = append(contents, end)
+
+		u.set(ref, page)
+	}
+	return u
+}
@@ -0,0 +1,259 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMultistampToWriterOptions(t *testing.T) {
+	e := &recordExecutor{stdout: []byte("%PDF-stamped")}
+	useExecutor(t, e)
+
+	var out bytes.Buffer
+	err := MultistampToWriter(strings.NewReader("%PDF-a"), strings.NewReader("%PDF-b"), &out,
+		WithTempDir(t.TempDir()),
+		WithInputPassword("secret"),
+		WithOutputOptions(OutputOptions{UserPassword: "user", Encryption: EncryptAES128}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "%PDF-stamped" {
+		t.Errorf("output = %q, want the pdftk output", out.String())
+	}
+
+	args := e.lastCall()
+	for i, arg := range args {
+		if filepath.IsAbs(arg) {
+			args[i] = filepath.Base(arg)
+		}
+	}
+	if got, want := strings.Join(args, " "),
+		"pdftk input.pdf input_pw secret multistamp stamp.pdf output - user_pw user encrypt_aes128"; got != want {
+		t.Errorf("pdftk args = %q, want %q", got, want)
+	}
+}
+
+func TestStampOperations(t *testing.T) {
+	pages := writeTestPDF(t, "pages.pdf", 3)
+	stamp := writeStampPDF(t, "stamp.pdf", 1)
+
+	tests := []struct {
+		operation string
+		stamp     func(a, b string, opts ...Option) (io.Reader, error)
+	}{
+		{"stamp", Stamp},
+		{"multistamp", Multistamp},
+		{"background", Background},
+		{"multibackground", Multibackground},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			e := &recordExecutor{output: []byte("%PDF-stamped")}
+			useExecutor(t, e)
+
+			r, err := tt.stamp(pages, stamp, WithBackend(PDFTKBackend{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out, _ := ioutil.ReadAll(r); string(out) != "%PDF-stamped" {
+				t.Errorf("output = %q, want the pdftk output", out)
+			}
+
+			args := e.lastCall()
+			for i, arg := range args {
+				if filepath.IsAbs(arg) {
+					args[i] = filepath.Base(arg)
+				}
+			}
+			if got, want := strings.Join(args, " "), "pdftk pages.pdf "+tt.operation+" stamp.pdf output output.pdf"; got != want {
+				t.Errorf("pdftk args = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestStampEveryPage(t *testing.T) {
+	requirePDFTK(t)
+	pages := writeTestPDF(t, "pages.pdf", 3)
+	stamp := writeStampPDF(t, "stamp.pdf", 1)
+
+	for _, run := range []func(a, b string, opts ...Option) (io.Reader, error){Stamp, Background} {
+		r, err := run(pages, stamp, WithBackend(PDFTKBackend{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := filepath.Join(t.TempDir(), "stamped.pdf")
+		if err := ioutil.WriteFile(output, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err := loadPDFFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := doc.pages()
+		if len(refs) != 3 {
+			t.Fatalf("pages = %d, want 3", len(refs))
+		}
+		for i, ref := range refs {
+			if len(doc.dict(doc.dict(doc.inherited(doc.dict(ref), "Resources"))["XObject"])) == 0 {
+				t.Errorf("page %d has no stamp", i+1)
+			}
+		}
+	}
+}
+
+func TestStampPagesOptions(t *testing.T) {
+	e := &recordExecutor{stdout: []byte("NumberOfPages: 4\n"), output: []byte("%PDF-stamped")}
+	useExecutor(t, e)
+
+	dir := t.TempDir()
+	r, err := StampPages(writeTestPDF(t, "pages.pdf", 4), writeStampPDF(t, "stamp.pdf", 1), "2-3",
+		WithTempDir(dir),
+		WithInputPassword("secret"),
+		WithOutputOptions(OutputOptions{OwnerPassword: "owner"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := ioutil.ReadAll(r); string(out) != "%PDF-stamped" {
+		t.Errorf("output = %q, want the pdftk output", out)
+	}
+	checkOutputsIn(t, e.calls, dir)
+
+	var got []string
+	for _, call := range e.calls {
+		args := append([]string(nil), call...)
+		for i, arg := range args {
+			if filepath.IsAbs(arg) {
+				args[i] = filepath.Base(arg)
+			} else if j := strings.IndexByte(arg, '='); j > 0 && filepath.IsAbs(arg[j+1:]) {
+				args[i] = arg[:j+1] + filepath.Base(arg[j+1:])
+			}
+		}
+		if len(args) > 1 && args[1] != "--version" && args[1] != "--help" {
+			got = append(got, strings.Join(args, " "))
+		}
+	}
+	want := []string{
+		"pdftk pages.pdf input_pw secret dump_data_utf8",
+		"pdftk pages.pdf input_pw secret stamp stamp.pdf output output.pdf",
+		"pdftk A=pages.pdf B=stamped.pdf input_pw A=secret cat A1-1 B2-3 A4-4 output output.pdf owner_pw owner",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pdftk calls = %q, want %q", got, want)
+	}
+}
+
+func TestStampPagesInvalid(t *testing.T) {
+	useExecutor(t, &recordExecutor{stdout: []byte("NumberOfPages: 2\n")})
+
+	pages := writeTestPDF(t, "pages.pdf", 2)
+	stamp := writeStampPDF(t, "stamp.pdf", 1)
+	for _, r := range []string{"3", "1-x", "0"} {
+		if _, err := StampPages(pages, stamp, r); err == nil {
+			t.Errorf("StampPages(%q) succeeded", r)
+		}
+	}
+}
+
+func TestTransparentPages(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "overlay.pdf", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := transparentPages(doc, 0.25).bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = parseNativePDF(out); err != nil {
+		t.Fatal(err)
+	}
+	refs := doc.pages()
+	if len(refs) != 2 {
+		t.Fatalf("pages = %d, want 2", len(refs))
+	}
+	for i, ref := range refs {
+		page := doc.dict(ref)
+		gs := doc.dict(doc.dict(doc.dict(doc.inherited(page, "Resources"))["ExtGState"])["FillPDFOpacity"])
+		if gs["CA"] != 0.25 || gs["ca"] != 0.25 {
+			t.Errorf("page %d: graphics state = %v, want opacity 0.25", i+1, gs)
+		}
+		text := pageText(t, doc, ref)
+		if !strings.HasPrefix(text, "q /FillPDFOpacity gs\n") || !strings.HasSuffix(text, "\nQ") {
+			t.Errorf("page %d: content = %q, want it wrapped into the graphics state", i+1, text)
+		}
+		if want := fmt.Sprintf("(Page %d)", i+1); !strings.Contains(text, want) {
+			t.Errorf("page %d: content = %q, want the original content %s", i+1, text, want)
+		}
+	}
+}
+
+func TestDiffOverlay(t *testing.T) {
+	overlay := writeTestPDF(t, "overlay.pdf", 2)
+	data, err := ioutil.ReadFile(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &recordExecutor{stdout: data, output: []byte("%PDF-diff")}
+	useExecutor(t, e)
+
+	r, err := DiffOverlay(writeTestPDF(t, "base.pdf", 2), overlay, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := ioutil.ReadAll(r); string(out) != "%PDF-diff" {
+		t.Errorf("output = %q, want the pdftk output", out)
+	}
+
+	args := e.lastCall()
+	for i, arg := range args {
+		if filepath.IsAbs(arg) {
+			args[i] = filepath.Base(arg)
+		}
+	}
+	if got, want := strings.Join(args, " "), "pdftk base.pdf multistamp overlay.pdf output output.pdf"; got != want {
+		t.Errorf("pdftk args = %q, want %q", got, want)
+	}
+
+	for _, opacity := range []float64{-0.1, 1.5} {
+		if _, err := DiffOverlay("base.pdf", overlay, opacity); err == nil {
+			t.Errorf("DiffOverlay with opacity %v succeeded", opacity)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// defaultTimeFormat is the layout of time.Time values without format tag.
+const defaultTimeFormat = "2006-01-02"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FillStruct fills the PDF form like FillFile with the form created by
+// FormFromStruct from v.
+func FillStruct(v interface{}, formPDFFile, destPDFFile string, opts ...Option) error {
+	form, err := FormFromStruct(v)
+	if err != nil {
+		return err
+	}
+	return FillFile(form, formPDFFile, destPDFFile, opts...)
+}
+
+// FormFromStruct creates a form from the exported fields of the struct v or
+// a pointer to it. The field name is taken from the pdf tag, like
+// `pdf:"Field_Name"`, or else the Go field name. Fields tagged `pdf:"-"` are
+// skipped and `pdf:"Name,omitempty"` skips zero values.
+//
+// Bools and Checkbox values fill checkboxes. time.Time values are formatted
+// with the layout of the format tag, like `format:"02.01.2006"`, and
+// "2006-01-02" by default. Numbers are formatted with the fmt verb of the
+// format tag, like `format:"%.2f"`, or else kept as they are, so
+// WithNumberFormat applies. Nested structs become nested forms and nil
+// pointers are skipped.
+func FormFromStruct(v interface{}) (Form, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("invalid struct: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid struct: %T", v)
+	}
+	return structForm(rv)
+}
+
+// structForm creates the form of the struct value.
+func structForm(rv reflect.Value) (Form, error) {
+	form := Form{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name, omitEmpty := sf.Name, false
+		if tag, ok := sf.Tag.Lookup("pdf"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			} else if parts[0] != "" {
+				name = parts[0]
+			}
+			omitEmpty = containsString(parts[1:], "omitempty")
+		}
+
+		fv := rv.Field(i)
+		for isNilable(fv) && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if (isNilable(fv) && fv.IsNil()) || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		value, err := structValue(fv, sf.Tag.Get("format"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid struct field '%s': %v", sf.Name, err)
+		}
+		if _, ok := form[name]; ok {
+			return nil, fmt.Errorf("duplicate form field: '%s'", name)
+		}
+		form[name] = value
+	}
+	return form, nil
+}
+
+// isNilable returns whenever the value is a pointer or interface.
+func isNilable(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface
+}
+
+// structValue returns the form value of a struct field.
+func structValue(fv reflect.Value, format string) (interface{}, error) {
+	switch {
+	case fv.Type() == timeType:
+		t := fv.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		} else if format == "" {
+			format = defaultTimeFormat
+		}
+		return t.Format(format), nil
+	case fv.Type() == reflect.TypeOf(Checkbox{}):
+		return fv.Interface(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Bool, reflect.String:
+		return fv.Interface(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if format != "" {
+			return fmt.Sprintf(format, fv.Interface()), nil
+		}
+		return fv.Interface(), nil
+	case reflect.Struct:
+		return structForm(fv)
+	}
+
+	if s, ok := fv.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", fv.Type())
+}
@@ -0,0 +1,138 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `pdf:"city"`
+	Zip  int    `pdf:"zip,omitempty"`
+}
+
+type testPerson struct {
+	Name     string       `pdf:"name"`
+	Agree    bool         `pdf:"agree"`
+	Color    *string      `pdf:"color"`
+	Born     time.Time    `pdf:"born" format:"02.01.2006"`
+	Signed   time.Time    `pdf:"signed"`
+	Empty    time.Time    `pdf:"empty,omitempty"`
+	Amount   float64      `pdf:"amount" format:"%.2f"`
+	Count    int          `pdf:"count"`
+	Address  *testAddress `pdf:"address"`
+	Missing  *testAddress `pdf:"missing"`
+	Consent  Checkbox     `pdf:"consent"`
+	Duration time.Duration
+	Internal string `pdf:"-"`
+	hidden   string
+}
+
+func TestFormFromStruct(t *testing.T) {
+	color := "g"
+	p := testPerson{
+		Name:     "Ann",
+		Agree:    true,
+		Color:    &color,
+		Born:     time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		Signed:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Amount:   12.5,
+		Count:    3,
+		Address:  &testAddress{City: "Berlin"},
+		Consent:  Checkbox{Checked: true, OnValue: "On"},
+		Duration: time.Minute,
+		Internal: "x",
+		hidden:   "y",
+	}
+	want := Form{
+		"name":     "Ann",
+		"agree":    true,
+		"color":    "g",
+		"born":     "17.05.1990",
+		"signed":   "2020-01-02",
+		"amount":   "12.50",
+		"count":    3,
+		"address":  Form{"city": "Berlin"},
+		"consent":  Checkbox{Checked: true, OnValue: "On"},
+		"Duration": time.Minute,
+	}
+
+	for _, v := range []interface{}{p, &p} {
+		form, err := FormFromStruct(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(form, want) {
+			t.Errorf("FormFromStruct(%T) = %v, want %v", v, form, want)
+		}
+	}
+}
+
+func TestFormFromStructInvalid(t *testing.T) {
+	var nilPerson *testPerson
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil pointer", nilPerson, "nil pointer"},
+		{"no struct", "name", "invalid struct"},
+		{"unsupported", struct{ Tags []string }{[]string{"a"}}, "unsupported type"},
+		{"duplicate", struct {
+			A string `pdf:"name"`
+			B string `pdf:"name"`
+		}{}, "duplicate form field"},
+	}
+	for _, tt := range tests {
+		if _, err := FormFromStruct(tt.v); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("FormFromStruct(%s) error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestFillStruct(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	dest := filepath.Join(t.TempDir(), "filled.pdf")
+
+	v := struct {
+		Name    string      `pdf:"name"`
+		Address testAddress `pdf:"address"`
+	}{"Bob", testAddress{City: "Hamburg"}}
+	if err := FillStruct(v, template, dest, WithBackend(NativeBackend{}), WithFlatten(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := doc.fieldValues(); values["name"] != "Bob" || values["address.city"] != "Hamburg" {
+		t.Errorf("field values = %v, want the struct values", values)
+	}
+}
@@ -0,0 +1,159 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// TabOrder defines the order in which the fields of a page are visited with
+// the tab key. The values are the /Tabs entries of the PDF specification.
+type TabOrder string
+
+const (
+	// TabOrderRow visits the fields row by row, from top to bottom.
+	TabOrderRow TabOrder = "R"
+	// TabOrderColumn visits the fields column by column, from left to right.
+	TabOrderColumn TabOrder = "C"
+	// TabOrderStructure follows the logical structure tree of tagged PDFs.
+	TabOrderStructure TabOrder = "S"
+)
+
+// SetTabOrder sets the tab order of all pages of the input PDF and writes the
+// result to output. For row and column order the annotations are sorted
+// accordingly too, for viewers ignoring the /Tabs entry. WithTempDir applies
+// like for a fill.
+func SetTabOrder(input, output string, order TabOrder, opts ...Option) error {
+	if order != TabOrderRow && order != TabOrderColumn && order != TabOrderStructure {
+		return fmt.Errorf("invalid tab order: '%s'", order)
+	}
+
+	doc, err := loadPDFFile(input)
+	if err != nil {
+		return err
+	}
+
+	return tabOrderPages(doc, order).save(output, newOptions(opts).tempDir)
+}
+
+// tabOrderPages sets the tab order of all pages like SetTabOrder.
+func tabOrderPages(doc *pdfDocument, order TabOrder) *pdfUpdate {
+	u := doc.update()
+	for _, ref := range doc.pages() {
+		page := copyDict(doc.dict(ref))
+		page["Tabs"] = pdfName(order)
+
+		annots := doc.array(page["Annots"])
+		if order != TabOrderStructure && len(annots) > 1 {
+			annots = append(pdfArray{}, annots...)
+			sort.SliceStable(annots, func(i, j int) bool {
+				li, ti := doc.annotPosition(annots[i])
+				lj, tj := doc.annotPosition(annots[j])
+				if order == TabOrderRow {
+					return ti > tj || (ti == tj && li < lj)
+				}
+				return li < lj || (li == lj && ti > tj)
+			})
+			page["Annots"] = annots
+		}
+
+		u.set(ref, page)
+	}
+	return u
+}
+
+// SetFieldOrder sets an explicit tab order by the given fully qualified field
+// names and writes the result to output. The widgets of the listed fields are
+// moved to the front of the annotations of their page in the given order,
+// all other annotations keep their relative order behind them. The /Tabs
+// entry is removed, so viewers follow the annotation order. WithTempDir
+// applies like for a fill.
+func SetFieldOrder(input, output string, fields []string, opts ...Option) error {
+	doc, err := loadPDFFile(input)
+	if err != nil {
+		return err
+	}
+
+	u, err := fieldOrderPages(doc, fields)
+	if err != nil {
+		return err
+	}
+	return u.save(output, newOptions(opts).tempDir)
+}
+
+// fieldOrderPages sets the order of the fields like SetFieldOrder.
+func fieldOrderPages(doc *pdfDocument, fields []string) (*pdfUpdate, error) {
+	index := make(map[string]int, len(fields))
+	for i, name := range fields {
+		index[name] = i
+	}
+
+	// Check all fields exist to catch typos.
+	found := make(map[string]bool)
+	for _, w := range doc.widgets() {
+		found[w.name] = true
+	}
+	for _, name := range fields {
+		if !found[name] {
+			return nil, fmt.Errorf("form field does not exist: '%s'", name)
+		}
+	}
+
+	u := doc.update()
+	for _, ref := range doc.pages() {
+		page := copyDict(doc.dict(ref))
+		delete(page, "Tabs")
+
+		annots := append(pdfArray{}, doc.array(page["Annots"])...)
+		position := func(annot interface{}) int {
+			dict := doc.dict(annot)
+			if doc.name(dict["Subtype"]) == "Widget" {
+				if i, ok := index[doc.fieldName(dict)]; ok {
+					return i
+				}
+			}
+			return len(fields)
+		}
+		sort.SliceStable(annots, func(i, j int) bool {
+			return position(annots[i]) < position(annots[j])
+		})
+		if len(annots) > 0 {
+			page["Annots"] = annots
+		}
+
+		u.set(ref, page)
+	}
+	return u, nil
+}
+
+// annotPosition returns the left and top coordinate of an annotation.
+func (d *pdfDocument) annotPosition(annot interface{}) (left, top float64) {
+	rect := d.array(d.dict(annot)["Rect"])
+	if len(rect) != 4 {
+		return 0, 0
+	}
+	x1, _ := d.number(rect[0])
+	y1, _ := d.number(rect[1])
+	x2, _ := d.number(rect[2])
+	y2, _ := d.number(rect[3])
+	return math.Min(x1, x2), math.Max(y1, y2)
+}
@@ -0,0 +1,201 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGridForm writes a form with the text fields "a" (top right), "b" (top
+// left), "c" (bottom left) and "d" (bottom right) on each of the pages, whose
+// annotations are listed in the order d, a, c, b.
+func writeGridForm(t testing.TB, name string, pages int) string {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+
+	var kids, fields pdfArray
+	for i := 0; i < pages; i++ {
+		page := w.add(nil)
+		widget := func(name string, x, y float64) pdfRef {
+			ref := w.add(pdfDict{
+				"Type": pdfName("Annot"), "Subtype": pdfName("Widget"), "P": page,
+				"FT": pdfName("Tx"), "T": pdfString(name), "Rect": pdfArray{x, y, x + 100, y + 20},
+			})
+			fields = append(fields, ref)
+			return ref
+		}
+		suffix := ""
+		if i > 0 {
+			suffix = strings.Repeat("'", i)
+		}
+		d := widget("d"+suffix, 300, 600)
+		a := widget("a"+suffix, 300, 700)
+		c := widget("c"+suffix, 72, 600)
+		b := widget("b"+suffix, 72, 700)
+		w.set(page, pdfDict{
+			"Type":     pdfName("Page"),
+			"Parent":   parent,
+			"MediaBox": pdfArray{0, 0, 595, 842},
+			"Annots":   pdfArray{d, a, c, b},
+		})
+		kids = append(kids, page)
+	}
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": kids, "Count": len(kids)})
+	root := w.add(pdfDict{"Type": pdfName("Catalog"), "Pages": parent, "AcroForm": pdfDict{"Fields": fields}})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readGridForm parses the form of writeGridForm.
+func readGridForm(t testing.TB, pages int) *pdfDocument {
+	t.Helper()
+	data, err := ioutil.ReadFile(writeGridForm(t, "form.pdf", pages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// tabOrders returns the /Tabs entry and the field names of the annotations of
+// each page of the updated document.
+func tabOrders(t testing.TB, u *pdfUpdate) (tabs []string, annots []string) {
+	t.Helper()
+	data, err := u.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range doc.pages() {
+		page := doc.dict(ref)
+		tabs = append(tabs, string(doc.name(page["Tabs"])))
+		var names []string
+		for _, annot := range doc.array(page["Annots"]) {
+			names = append(names, doc.fieldName(doc.dict(annot)))
+		}
+		annots = append(annots, strings.Join(names, " "))
+	}
+	return tabs, annots
+}
+
+func TestTabOrderPages(t *testing.T) {
+	tests := []struct {
+		order  TabOrder
+		annots []string
+	}{
+		{TabOrderRow, []string{"b a c d", "b' a' c' d'"}},
+		{TabOrderColumn, []string{"b c a d", "b' c' a' d'"}},
+		{TabOrderStructure, []string{"d a c b", "d' a' c' b'"}},
+	}
+	for _, tt := range tests {
+		tabs, annots := tabOrders(t, tabOrderPages(readGridForm(t, 2), tt.order))
+		if want := []string{string(tt.order), string(tt.order)}; strings.Join(tabs, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: /Tabs = %q, want %q", tt.order, tabs, want)
+		}
+		if strings.Join(annots, ", ") != strings.Join(tt.annots, ", ") {
+			t.Errorf("%s: /Annots = %q, want %q", tt.order, annots, tt.annots)
+		}
+	}
+}
+
+func TestFieldOrderPages(t *testing.T) {
+	tests := []struct {
+		fields []string
+		annots []string
+	}{
+		{[]string{"d", "b"}, []string{"d b a c", "d' a' c' b'"}},
+		{[]string{"c", "b", "a", "d", "a'"}, []string{"c b a d", "a' d' c' b'"}},
+		{nil, []string{"d a c b", "d' a' c' b'"}},
+	}
+	for _, tt := range tests {
+		// An existing /Tabs entry is removed.
+		data, err := tabOrderPages(readGridForm(t, 2), TabOrderStructure).bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := parseNativePDF(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := fieldOrderPages(doc, tt.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tabs, annots := tabOrders(t, u)
+		if strings.Join(tabs, "") != "" {
+			t.Errorf("%q: /Tabs = %q, want none", tt.fields, tabs)
+		}
+		if strings.Join(annots, ", ") != strings.Join(tt.annots, ", ") {
+			t.Errorf("%q: /Annots = %q, want %q", tt.fields, annots, tt.annots)
+		}
+	}
+
+	if _, err := fieldOrderPages(readGridForm(t, 1), []string{"a", "x"}); err == nil {
+		t.Error("fieldOrderPages with a missing field succeeded")
+	}
+}
+
+func TestSetTabOrder(t *testing.T) {
+	requirePDFTK(t)
+	input := writeGridForm(t, "form.pdf", 1)
+	output := filepath.Join(t.TempDir(), "tabs.pdf")
+
+	if err := SetTabOrder(input, output, TabOrderColumn); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loadPDFFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := doc.dict(doc.pages()[0])
+	if tabs := doc.name(page["Tabs"]); tabs != "C" {
+		t.Errorf("/Tabs = %q, want C", tabs)
+	}
+	var names []string
+	for _, annot := range doc.array(page["Annots"]) {
+		names = append(names, doc.fieldName(doc.dict(annot)))
+	}
+	if got := strings.Join(names, " "); got != "b c a d" {
+		t.Errorf("/Annots = %q, want %q", got, "b c a d")
+	}
+
+	if err := SetTabOrder(input, output, TabOrder("X")); err == nil {
+		t.Error("SetTabOrder with an invalid order succeeded")
+	}
+}
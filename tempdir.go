@@ -0,0 +1,56 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io/ioutil"
+	"sync"
+)
+
+var (
+	tempDirMutex  sync.Mutex
+	tempDirParent string
+)
+
+// SetTempDir sets the directory the package creates its temporary working
+// directories in, e.g. a large scratch volume instead of a small tmpfs. The
+// working directories are still created and removed per call. An empty dir
+// restores the default of the system temporary directory.
+func SetTempDir(dir string) {
+	tempDirMutex.Lock()
+	defer tempDirMutex.Unlock()
+	tempDirParent = dir
+}
+
+// TempDir returns the directory set with SetTempDir, empty for the default.
+func TempDir() string {
+	tempDirMutex.Lock()
+	defer tempDirMutex.Unlock()
+	return tempDirParent
+}
+
+// createTempDir creates a temporary working directory in parent, or in the
+// directory set with SetTempDir if parent is empty.
+func createTempDir(parent string) (string, error) {
+	if parent == "" {
+		parent = TempDir()
+	}
+	return ioutil.TempDir(parent, "fillpdf-")
+}
@@ -0,0 +1,118 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// requirePDFTK skips the test if the pdftk utility is not in the PATH.
+func requirePDFTK(t testing.TB) {
+	t.Helper()
+	if err := lookPath("pdftk"); err != nil {
+		t.Skip("pdftk not found in PATH")
+	}
+}
+
+// writeTestPDF writes a PDF with n A4 pages, each showing its page number,
+// into the temporary directory of the test and returns its path.
+func writeTestPDF(t testing.TB, name string, n int) string {
+	t.Helper()
+	pages := make([]pdfPage, n)
+	for i := range pages {
+		pages[i] = pdfPage{
+			width:     595,
+			height:    842,
+			content:   []byte(fmt.Sprintf("BT /F1 24 Tf 72 720 Td %s Tj ET", pdfLiteral(fmt.Sprintf("Page %d", i+1)))),
+			resources: helveticaResources(),
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := writePDF(path, pages); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// recordExecutor records the commands it is asked to run and returns the
+// canned stdout for each of them. If output is set, it is written to the
+// file following the "output" argument.
+type recordExecutor struct {
+	mutex  sync.Mutex
+	calls  [][]string
+	stdout []byte
+	output []byte
+}
+
+// Run implements Executor.
+func (e *recordExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.calls = append(e.calls, append([]string{name}, args...))
+
+	for i, arg := range args {
+		if arg == "output" && i+1 < len(args) && args[i+1] != "-" && e.output != nil {
+			if err := ioutil.WriteFile(args[i+1], e.output, 0600); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return e.stdout, nil, nil
+}
+
+// lastCall returns the last recorded command.
+func (e *recordExecutor) lastCall() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.calls) == 0 {
+		return nil
+	}
+	return e.calls[len(e.calls)-1]
+}
+
+// useExecutor sets the Executor of the package until the test ends. The
+// cached pdftk version and capabilities are reset before and after, so they
+// are read from the Executor.
+func useExecutor(t testing.TB, e Executor) {
+	t.Helper()
+	resetPDFTKCache()
+	SetExecutor(e)
+	t.Cleanup(func() {
+		SetExecutor(nil)
+		resetPDFTKCache()
+	})
+}
+
+// resetPDFTKCache forgets the cached pdftk version and capabilities.
+func resetPDFTKCache() {
+	versionMutex.Lock()
+	cachedVersion = ""
+	versionMutex.Unlock()
+
+	capabilitiesMutex.Lock()
+	cachedCapabilities = nil
+	capabilitiesMutex.Unlock()
+}
@@ -0,0 +1,157 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Layout of the table of contents pages in points.
+const (
+	tocMargin     = 72.0
+	tocTitleSize  = 18.0
+	tocFontSize   = 12.0
+	tocLineHeight = 18.0
+)
+
+// tocEntry is a line of the table of contents.
+type tocEntry struct {
+	title string
+	page  int
+}
+
+// MergeWithTOC concatenates all input <files> like Merge and prepends a table
+// of contents listing the title of each file and the page it starts on.
+// The title is read from the document information, the file name is used if
+// it has none. If countTOC is true, the page numbers include the pages of the
+// table of contents, otherwise the first page of the first file is page 1.
+// The table of contents uses the page size of the first input page.
+// WithTempDir, WithBackend and WithOutputOptions apply like for
+// MergeWithOptions.
+func MergeWithTOC(countTOC bool, files []string, opts ...Option) (io.Reader, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no input files")
+	}
+
+	var entries []tocEntry
+	width, height := 595.0, 842.0
+	page := 1
+	for i, f := range files {
+		data, err := dumpData(f)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && len(data.pageSizes) > 0 && data.pageSizes[0][0] > 0 {
+			width, height = data.pageSizes[0][0], data.pageSizes[0][1]
+		}
+
+		title := strings.TrimSpace(data.info["Title"])
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		}
+		entries = append(entries, tocEntry{title: title, page: page})
+		page += data.numPages
+	}
+
+	pages := tocPages(entries, width, height, countTOC)
+
+	o := newOptions(opts)
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir(o.tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	tocFile := filepath.Join(tmpDir, "toc.pdf")
+	if err := writePDF(tocFile, pages); err != nil {
+		return nil, err
+	}
+
+	return mergeFiles(append([]string{tocFile}, files...), o)
+}
+
+// tocPages lays out the entries on as many pages as required.
+func tocPages(entries []tocEntry, width, height float64, countTOC bool) []pdfPage {
+	linesPerPage := int((height - 2*tocMargin - 2*tocLineHeight) / tocLineHeight)
+	if linesPerPage < 1 {
+		linesPerPage = 1
+	}
+	numPages := (len(entries) + linesPerPage - 1) / linesPerPage
+
+	offset := 0
+	if countTOC {
+		offset = numPages
+	}
+
+	var pages []pdfPage
+	for start := 0; start < len(entries); start += linesPerPage {
+		var b bytes.Buffer
+		y := height - tocMargin - tocTitleSize
+		if start == 0 {
+			fmt.Fprintf(&b, "BT /F1 %g Tf %g %g Td %s Tj ET\n", tocTitleSize, tocMargin, y, pdfLiteral("Contents"))
+		}
+		y -= 2 * tocLineHeight
+
+		end := start + linesPerPage
+		if end > len(entries) {
+			end = len(entries)
+		}
+		for _, e := range entries[start:end] {
+			num := strconv.Itoa(e.page + offset)
+			numWidth := measureText(num, "Helvetica", tocFontSize, StandardFontMetrics)
+			numX := width - tocMargin - numWidth
+
+			// Shorten titles running into the page number.
+			title := e.title
+			maxWidth := numX - tocMargin - tocFontSize
+			for title != "" && measureText(title+"...", "Helvetica", tocFontSize, StandardFontMetrics) > maxWidth {
+				r := []rune(title)
+				title = string(r[:len(r)-1])
+			}
+			if title != e.title {
+				title += "..."
+			}
+
+			fmt.Fprintf(&b, "BT /F1 %g Tf %g %g Td %s Tj ET\n", tocFontSize, tocMargin, y, pdfLiteral(title))
+			fmt.Fprintf(&b, "BT /F1 %g Tf %g %g Td %s Tj ET\n", tocFontSize, numX, y, pdfLiteral(num))
+			y -= tocLineHeight
+		}
+
+		pages = append(pages, pdfPage{
+			width:     width,
+			height:    height,
+			content:   b.Bytes(),
+			resources: helveticaResources(),
+		})
+	}
+	return pages
+}
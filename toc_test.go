@@ -0,0 +1,163 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTOCPages(t *testing.T) {
+	var entries []tocEntry
+	for i := 0; i < 40; i++ {
+		entries = append(entries, tocEntry{title: fmt.Sprintf("Chapter %d", i+1), page: 1 + 3*i})
+	}
+	entries[1].title = strings.Repeat("A very long title ", 10)
+
+	for _, countTOC := range []bool{false, true} {
+		pages := tocPages(entries, 595, 842, countTOC)
+		if len(pages) != 2 {
+			t.Fatalf("countTOC %v: pages = %d, want 2", countTOC, len(pages))
+		}
+		offset := 0
+		if countTOC {
+			offset = 2
+		}
+
+		first, second := string(pages[0].content), string(pages[1].content)
+		if !strings.Contains(first, "(Contents)") || strings.Contains(second, "(Contents)") {
+			t.Errorf("countTOC %v: the heading is not on the first page only", countTOC)
+		}
+		for i, e := range entries {
+			content := first
+			if i >= 36 {
+				content = second
+			}
+			if num := fmt.Sprintf("(%d)", e.page+offset); !strings.Contains(content, num) {
+				t.Errorf("countTOC %v: entry %d has no page number %s", countTOC, i+1, num)
+			}
+		}
+		if strings.Contains(first, pdfLiteral(entries[1].title)) || !strings.Contains(first, "...)") {
+			t.Errorf("countTOC %v: the long title is not shortened", countTOC)
+		}
+		if pages[0].width != 595 || pages[0].height != 842 {
+			t.Errorf("countTOC %v: page size = %vx%v, want 595x842", countTOC, pages[0].width, pages[0].height)
+		}
+	}
+}
+
+// tocExecutor returns the dump_data output of the input files by their base
+// names and keeps the first input of the cat operation, the table of
+// contents.
+type tocExecutor struct {
+	mutex    sync.Mutex
+	dumpData map[string]string
+	toc      []byte
+	inputs   []string
+}
+
+// Run implements Executor.
+func (e *tocExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(args) == 2 && strings.HasPrefix(args[1], "dump_data") {
+		return []byte(e.dumpData[filepath.Base(args[0])]), nil, nil
+	}
+	for i, arg := range args {
+		if arg != "cat" {
+			continue
+		}
+		var err error
+		if e.toc, err = ioutil.ReadFile(args[0]); err != nil {
+			return nil, nil, err
+		}
+		for _, input := range args[:i] {
+			e.inputs = append(e.inputs, filepath.Base(input))
+		}
+		return nil, nil, ioutil.WriteFile(args[len(args)-1], []byte("%PDF-merged"), 0600)
+	}
+	return nil, nil, nil
+}
+
+func TestMergeWithTOC(t *testing.T) {
+	a := writeTestPDF(t, "a.pdf", 3)
+	b := writeTestPDF(t, "b.pdf", 2)
+
+	tests := []struct {
+		countTOC bool
+		want     []string
+	}{
+		{false, []string{"(Annual Report)", "(1)", "(b)", "(4)"}},
+		{true, []string{"(Annual Report)", "(2)", "(b)", "(5)"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.countTOC), func(t *testing.T) {
+			e := &tocExecutor{dumpData: map[string]string{
+				"a.pdf": "InfoBegin\nInfoKey: Title\nInfoValue: Annual Report\nNumberOfPages: 3\nPageMediaDimensions: 612 792\n",
+				"b.pdf": "NumberOfPages: 2\nPageMediaDimensions: 595 842\n",
+			}}
+			useExecutor(t, e)
+
+			r, err := MergeWithTOC(tt.countTOC, []string{a, b})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out, _ := ioutil.ReadAll(r); string(out) != "%PDF-merged" {
+				t.Errorf("output = %q, want the pdftk output", out)
+			}
+			if got := strings.Join(e.inputs, " "); got != "toc.pdf a.pdf b.pdf" {
+				t.Errorf("merged files = %q, want the table of contents first", got)
+			}
+
+			doc, err := parseNativePDF(e.toc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			refs := doc.pages()
+			if len(refs) != 1 {
+				t.Fatalf("table of contents pages = %d, want 1", len(refs))
+			}
+			if box := doc.array(doc.dict(refs[0])["MediaBox"]); len(box) != 4 || box[2] != 612.0 || box[3] != 792.0 {
+				t.Errorf("table of contents media box = %v, want the size of the first input page", box)
+			}
+			text := pageText(t, doc, refs[0])
+			pos := 0
+			for _, want := range tt.want {
+				i := strings.Index(text[pos:], want)
+				if i < 0 {
+					t.Fatalf("table of contents = %q, want %s after position %d", text, want, pos)
+				}
+				pos += i + len(want)
+			}
+		})
+	}
+}
+
+func TestMergeWithTOCNoFiles(t *testing.T) {
+	if _, err := MergeWithTOC(false, nil); err == nil {
+		t.Error("MergeWithTOC without files succeeded")
+	}
+}
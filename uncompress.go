@@ -0,0 +1,132 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Uncompress returns a reader to the PDF with uncompressed page streams,
+// e.g. to inspect or diff the document. Rewriting the document with pdftk
+// also repairs some broken cross-reference tables.
+func Uncompress(pdfFile string) (io.Reader, error) {
+	return rewritePDF(pdfFile, "uncompress")
+}
+
+// Compress returns a reader to the PDF with compressed page streams, e.g. to
+// undo Uncompress.
+func Compress(pdfFile string) (io.Reader, error) {
+	return rewritePDF(pdfFile, "compress")
+}
+
+// Repair reads a damaged PDF, e.g. with a broken cross-reference table, and
+// returns a reader to the PDF written again by pdftk. pdftk rebuilds the
+// cross-reference table while reading, so the output is a clean document.
+// Documents pdftk can't read at all stay broken.
+func Repair(r io.Reader) (io.Reader, error) {
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	pdfFile, err := spoolReader(r, tmpDir, "input.pdf")
+	if err != nil {
+		return nil, err
+	}
+	return rewritePDF(pdfFile)
+}
+
+// repairFile writes the PDF file again with pdftk to output, see Repair.
+func repairFile(pdfFile, output, password string) error {
+	args := append(pdftkInput(pdfFile, password), "output", output)
+	if err := runCommandInPath(filepath.Dir(output), "pdftk", args...); err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+	return nil
+}
+
+// isParseError returns whenever pdftk failed to parse an input PDF.
+func isParseError(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, msg := range []string{"open_reader", "InvalidPdfException", "Rebuild failed", "PdfException"} {
+		if strings.Contains(cmdErr.Stderr, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewritePDF runs pdftk with the output options on the PDF file.
+func rewritePDF(pdfFile string, options ...string) (io.Reader, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the temporary output file path.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+
+	// Run the pdftk utility.
+	args := append([]string{pdfFile, "output", outputFile}, options...)
+	err = runCommandInPath(tmpDir, "pdftk", args...)
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
@@ -0,0 +1,212 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewritePDF(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+
+	tests := []struct {
+		name    string
+		rewrite func() (io.Reader, error)
+		options []string
+	}{
+		{"uncompress", func() (io.Reader, error) { return Uncompress(input) }, []string{"uncompress"}},
+		{"compress", func() (io.Reader, error) { return Compress(input) }, []string{"compress"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &recordExecutor{output: []byte("%PDF-rewritten")}
+			useExecutor(t, e)
+
+			r, err := tt.rewrite()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out, err := ioutil.ReadAll(r); err != nil || string(out) != "%PDF-rewritten" {
+				t.Errorf("output = %q, %v, want the pdftk output", out, err)
+			}
+
+			call := e.lastCall()
+			if len(call) != 4+len(tt.options) || call[0] != "pdftk" || call[2] != "output" {
+				t.Fatalf("pdftk call = %v", call)
+			}
+			if got := strings.Join(call[4:], " "); got != strings.Join(tt.options, " ") {
+				t.Errorf("pdftk options = %q, want %q", got, strings.Join(tt.options, " "))
+			}
+			if call[1] != input {
+				t.Errorf("pdftk input = %s, want %s", call[1], input)
+			}
+		})
+	}
+}
+
+func TestUncompressCompress(t *testing.T) {
+	requirePDFTK(t)
+	input := writeTestPDF(t, "input.pdf", 2)
+
+	r, err := Compress(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(compressed, []byte("(Page 1) Tj")) {
+		t.Error("compressed PDF contains the plain page content")
+	}
+
+	output := filepath.Join(t.TempDir(), "compressed.pdf")
+	if err := ioutil.WriteFile(output, compressed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = Uncompress(output); err != nil {
+		t.Fatal(err)
+	}
+	doc := parseTestPDF(t, r)
+	if n := len(doc.pages()); n != 2 {
+		t.Errorf("uncompressed PDF has %d pages, want 2", n)
+	}
+	if text := pageText(t, doc, doc.pages()[0]); !strings.Contains(text, "(Page 1) Tj") {
+		t.Errorf("uncompressed page content = %q, want the plain text", text)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "input.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &recordExecutor{output: []byte("%PDF-repaired")}
+	useExecutor(t, e)
+
+	r, err := Repair(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := ioutil.ReadAll(r); err != nil || string(out) != "%PDF-repaired" {
+		t.Errorf("output = %q, %v, want the pdftk output", out, err)
+	}
+	call := e.lastCall()
+	if got, want := baseNames(call), "pdftk input.pdf output output.pdf"; got != want {
+		t.Errorf("pdftk call = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(call[1]); !os.IsNotExist(err) {
+		t.Errorf("spooled input %s was not removed", call[1])
+	}
+}
+
+func TestIsParseError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&CommandError{Name: "pdftk", Stderr: "Error: Unexpected Exception in open_reader()"}, true},
+		{fmt.Errorf("pdftk error: %w", &CommandError{Name: "pdftk", Stderr: "java.io.IOException: Rebuild failed"}), true},
+		{&CommandError{Name: "pdftk", Stderr: "Error: Failed to open output file"}, false},
+		{errors.New("open_reader"), false},
+	}
+	for _, tt := range tests {
+		if got := isParseError(tt.err); got != tt.want {
+			t.Errorf("isParseError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// brokenExecutor fails the fills of all templates but repaired ones with
+// the stderr and runs the other pdftk commands like recordExecutor.
+type brokenExecutor struct {
+	recordExecutor
+	stderr string
+}
+
+// Run implements Executor.
+func (e *brokenExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	stdout, _, err := e.recordExecutor.Run(dir, name, args, stdin)
+	if containsString(args, "fill_form") && filepath.Base(args[0]) != "repaired.pdf" {
+		return nil, []byte(e.stderr), errors.New("exit status 1")
+	}
+	return stdout, nil, err
+}
+
+func TestFillRepair(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+
+	tests := []struct {
+		name    string
+		stderr  string
+		repair  bool
+		wantErr bool
+	}{
+		{"repaired", "Error: Unexpected Exception in open_reader()", true, false},
+		{"not enabled", "Error: Unexpected Exception in open_reader()", false, true},
+		{"no parse error", "Error: Failed to open output file", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &brokenExecutor{stderr: tt.stderr}
+			e.stdout = []byte(pdftkHelp)
+			e.output = []byte("%PDF-filled")
+			useExecutor(t, e)
+
+			opts := []Option{WithBackend(PDFTKBackend{})}
+			if tt.repair {
+				opts = append(opts, WithRepair())
+			}
+			out, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FillPDFToBytes() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.stderr) {
+					t.Errorf("error = %v, want the error of the first fill", err)
+				}
+				return
+			}
+			if string(out) != "%PDF-filled" {
+				t.Errorf("output = %q, want the output of the repaired fill", out)
+			}
+
+			var repairs, fills int
+			for _, call := range e.calls {
+				if containsString(call, "fill_form") {
+					fills++
+				} else if len(call) == 4 && call[2] == "output" && filepath.Base(call[3]) == "repaired.pdf" {
+					repairs++
+				}
+			}
+			if repairs != 1 || fills != 2 {
+				t.Errorf("pdftk ran %d repairs and %d fills, want 1 and 2: %v", repairs, fills, e.calls)
+			}
+		})
+	}
+}
@@ -20,13 +20,15 @@ package fillpdf
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func getAbs(path string) (string, error) {
@@ -41,7 +43,7 @@ func getAbs(path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to check if file exists: %v", err)
 	} else if !e {
-		return "", fmt.Errorf("file does not exists: '%s'", path)
+		return "", fmt.Errorf("%w: '%s'", ErrFileNotFound, path)
 	}
 
 	return absPath, nil
@@ -86,40 +88,149 @@ func copyFile(src, dst string) (err error) {
 	return
 }
 
+// spoolReader writes the reader to a new file in dir and returns its path.
+func spoolReader(r io.Reader, dir, name string) (path string, err error) {
+	path = filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		cerr := f.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	_, err = io.Copy(f, r)
+	return path, err
+}
+
+// runPdftkFile runs pdftk with the input file and the operation arguments and
+// writes the result to the output file. The output may be the input file.
+func runPdftkFile(input, output string, operation ...string) error {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return err
+	}
+
+	// Get the absolute paths.
+	if input, err = getAbs(input); err != nil {
+		return err
+	}
+
+	if output, err = filepath.Abs(output); err != nil {
+		return err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Create the temporary output file path.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+
+	// Create the pdftk command line arguments.
+	args := append([]string{input}, operation...)
+	args = append(args, "output", outputFile)
+
+	// Run the pdftk utility.
+	if err := runCommandInPath(tmpDir, "pdftk", args...); err != nil {
+		return fmt.Errorf("pdftk error: %w", err)
+	}
+
+	// On success, copy the output file to the final destination.
+	return copyFile(outputFile, output)
+}
+
 // runCommandInPath runs a command and waits for it to exit.
 // The working directory is also set.
 // The stderr error message is returned on error.
 func runCommandInPath(dir, name string, args ...string) error {
-	// Create the command.
-	var stderr bytes.Buffer
-	cmd := exec.Command(name, args...)
-	cmd.Stderr = &stderr
-	cmd.Dir = dir
+	return runCommandInPathContext(context.Background(), dir, name, args...)
+}
 
-	// Start the command and wait for it to exit.
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf(strings.TrimSpace(stderr.String()))
+// runCommandInPathContext is like runCommandInPath, but kills the command
+// once the context is done.
+func runCommandInPathContext(ctx context.Context, dir, name string, args ...string) error {
+	return runCommandToWriterContext(ctx, dir, ioutil.Discard, name, args...)
+}
+
+func runCommandWithOutput(dir, name string, args ...string) ([]byte, error) {
+	return runCommandWithOutputContext(context.Background(), dir, name, args...)
+}
+
+// runCommandWithOutputContext is like runCommandWithOutput, but kills the
+// command once the context is done.
+func runCommandWithOutputContext(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	if err := runCommandToWriterContext(ctx, dir, &stdout, name, args...); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return stdout.Bytes(), nil
 }
 
-func runCommandWithOutput(dir, name string, args ...string) ([]byte, error) {
-	// Create the command.
+// runCommandToWriter runs a command and streams its stdout to w.
+// The stderr error message is returned on error.
+func runCommandToWriter(dir string, w io.Writer, name string, args ...string) error {
+	return runCommandToWriterContext(context.Background(), dir, w, name, args...)
+}
+
+// runCommandToWriterContext is like runCommandToWriter, but kills the command
+// once the context is done. The context error is returned wrapped with the
+// stage that was running then.
+func runCommandToWriterContext(ctx context.Context, dir string, w io.Writer, name string, args ...string) error {
 	var stderr bytes.Buffer
-	var stdout bytes.Buffer
-	cmd := exec.Command(name, args...)
-	cmd.Stderr = &stderr
-	cmd.Stdout = &stdout
-	cmd.Dir = dir
+
 	// Start the command and wait for it to exit.
-	err := cmd.Run()
+	release, err := acquireProcessContext(ctx, name)
 	if err != nil {
-		return nil, fmt.Errorf(strings.TrimSpace(stderr.String()))
+		return fmt.Errorf("%s: waiting for a process slot: %w", commandStage(name, args), err)
 	}
+	start := time.Now()
+	err = execute(ctx, dir, nil, w, &stderr, name, args...)
+	release()
+	err = commandError(ctx, name, args, stderr.String(), err)
+	traceCommand(name, args, start, err)
+	return err
+}
 
-	return stdout.Bytes(), nil
+// commandError returns the error of a finished command, nil on success.
+func commandError(ctx context.Context, name string, args []string, stderr string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", commandStage(name, args), ctxErr)
+	} else if err != nil {
+		return &CommandError{
+			Name:     name,
+			Args:     redactArgs(args),
+			ExitCode: exitCode(err),
+			Stderr:   redactPasswords(strings.TrimSpace(stderr), args),
+			Err:      err,
+		}
+	}
+	return nil
+}
+
+// commandStage describes the command for error messages, e.g. "pdftk cat".
+func commandStage(name string, args []string) string {
+	if name == "pdftk" {
+		for _, arg := range args {
+			if containsString(knownOperations, arg) {
+				return name + " " + arg
+			}
+		}
+	}
+	return name
 }
 
 //create Random ID
@@ -0,0 +1,128 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// TextStampOptions configure StampText. The zero value draws black
+// Helvetica text of 48pt at the center of each page.
+type TextStampOptions struct {
+	// FontSize of the text in points, 48 if zero.
+	FontSize float64
+	// Rotation of the text in degrees counterclockwise, e.g. 45 for a
+	// diagonal watermark.
+	Rotation float64
+	// Opacity of the text between 0 and 1. Zero draws the text opaque.
+	Opacity float64
+	// Color of the text as RGB components between 0 and 1.
+	Color [3]float64
+	// X and Y are the center of the text in points from the lower left
+	// corner of the page. Zero for both centers the text on the page.
+	X, Y float64
+	// Background puts the text behind the page content instead of on top.
+	Background bool
+}
+
+// StampText draws the text onto every page of the PDF file, e.g. a
+// "CONFIDENTIAL" watermark, and returns a reader to the stamped PDF. The
+// text is set in Helvetica, characters outside of Latin-1 are replaced by '?'.
+func StampText(pdfFile string, text string, opts TextStampOptions) (io.Reader, error) {
+	if opts.Opacity < 0 || opts.Opacity > 1 {
+		return nil, fmt.Errorf("invalid opacity: %v", opts.Opacity)
+	}
+	if opts.FontSize < 0 {
+		return nil, fmt.Errorf("invalid font size: %v", opts.FontSize)
+	}
+	if opts.FontSize == 0 {
+		opts.FontSize = 48
+	}
+	if opts.Opacity == 0 {
+		opts.Opacity = 1
+	}
+
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := helveticaResources()
+	resources["ExtGState"] = pdfDict{
+		"GS1": pdfDict{
+			"Type": pdfName("ExtGState"),
+			"CA":   opts.Opacity,
+			"ca":   opts.Opacity,
+		},
+	}
+
+	// Create one stamp page per page, matching its size.
+	width := measureText(text, "Helvetica", opts.FontSize, StandardFontMetrics)
+	sin, cos := math.Sincos(opts.Rotation * math.Pi / 180)
+	refs := doc.pages()
+	pages := make([]pdfPage, len(refs))
+	for i, ref := range refs {
+		box := doc.array(doc.inherited(doc.dict(ref), "MediaBox"))
+		var r [4]float64
+		for j := 0; j < 4 && j < len(box); j++ {
+			r[j], _ = doc.number(box[j])
+		}
+		pages[i] = pdfPage{width: r[2] - r[0], height: r[3] - r[1], resources: resources}
+
+		x, y := opts.X, opts.Y
+		if x == 0 && y == 0 {
+			x, y = pages[i].width/2, pages[i].height/2
+		}
+
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "q /GS1 gs %s %s %s rg\n",
+			pdfNumber(opts.Color[0]), pdfNumber(opts.Color[1]), pdfNumber(opts.Color[2]))
+		fmt.Fprintf(&b, "%.4f %.4f %.4f %.4f %s %s cm\n", cos, sin, -sin, cos, pdfNumber(x), pdfNumber(y))
+		fmt.Fprintf(&b, "BT /F1 %s Tf %s %s Td %s Tj ET\nQ\n",
+			pdfNumber(opts.FontSize), pdfNumber(-width/2), pdfNumber(-0.35*opts.FontSize), pdfLiteral(text))
+		pages[i].content = b.Bytes()
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	stampFile := filepath.Join(tmpDir, "stamp.pdf")
+	if err := writePDF(stampFile, pages); err != nil {
+		return nil, err
+	}
+
+	if opts.Background {
+		return Multibackground(pdfFile, stampFile)
+	}
+	return Multistamp(pdfFile, stampFile)
+}
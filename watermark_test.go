@@ -0,0 +1,129 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stampTextExecutor returns the input PDF to load like recordExecutor and
+// keeps a copy of the stamp PDF pdftk is asked to apply.
+type stampTextExecutor struct {
+	recordExecutor
+	stamp []byte
+}
+
+// Run implements Executor.
+func (e *stampTextExecutor) Run(dir, name string, args []string, stdin io.Reader) ([]byte, []byte, error) {
+	for i, arg := range args {
+		if (arg == "multistamp" || arg == "multibackground") && i+1 < len(args) {
+			data, err := ioutil.ReadFile(args[i+1])
+			if err != nil {
+				return nil, nil, err
+			}
+			e.stamp = data
+		}
+	}
+	return e.recordExecutor.Run(dir, name, args, stdin)
+}
+
+func TestStampText(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.pdf")
+	err := writePDF(input, []pdfPage{{width: 595, height: 842}, {width: 842, height: 595}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      TextStampOptions
+		operation string
+		want      []string
+	}{
+		{
+			name:      "default",
+			operation: "multistamp",
+			want:      []string{"/F1 48 Tf", "1.0000 0.0000 -0.0000 1.0000 297.5 421 cm", "(CONFIDENTIAL) Tj", "0 0 0 rg"},
+		},
+		{
+			name:      "diagonal background",
+			opts:      TextStampOptions{FontSize: 20, Rotation: 45, Color: [3]float64{1, 0, 0}, X: 100, Y: 200, Background: true},
+			operation: "multibackground",
+			want:      []string{"/F1 20 Tf", "0.7071 0.7071 -0.7071 0.7071 100 200 cm", "1 0 0 rg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &stampTextExecutor{}
+			e.stdout = data
+			e.output = []byte("%PDF-stamped")
+			useExecutor(t, e)
+
+			r, err := StampText(input, "CONFIDENTIAL", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out, err := ioutil.ReadAll(r); err != nil || string(out) != "%PDF-stamped" {
+				t.Errorf("StampText() = %q, %v, want the pdftk output", out, err)
+			}
+			if call := e.lastCall(); !containsString(call, tt.operation) {
+				t.Errorf("pdftk call = %v, want %s", call, tt.operation)
+			}
+
+			stamp, err := parseNativePDF(e.stamp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages := stamp.pages()
+			if len(pages) != 2 {
+				t.Fatalf("stamp has %d pages, want 2", len(pages))
+			}
+			box := stamp.array(stamp.dict(pages[1])["MediaBox"])
+			if len(box) != 4 {
+				t.Fatalf("MediaBox of the second stamp page = %v", box)
+			}
+			if w, _ := stamp.number(box[2]); w != 842 {
+				t.Errorf("MediaBox of the second stamp page = %v, want the landscape page size", box)
+			}
+			for _, want := range tt.want {
+				if text := pageText(t, stamp, pages[0]); !strings.Contains(text, want) {
+					t.Errorf("stamp content %q does not contain %q", text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStampTextInvalid(t *testing.T) {
+	input := writeTestPDF(t, "input.pdf", 1)
+	for _, opts := range []TextStampOptions{{Opacity: -0.1}, {Opacity: 1.5}, {FontSize: -1}} {
+		if _, err := StampText(input, "DRAFT", opts); err == nil {
+			t.Errorf("StampText(%+v) succeeded", opts)
+		}
+	}
+}
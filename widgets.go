@@ -0,0 +1,203 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Field flags from the PDF specification (table 221, 226, 228 and 230).
+const (
+	fieldFlagRequired   = 1 << 1
+	fieldFlagMultiline  = 1 << 12
+	fieldFlagRadio      = 1 << 15
+	fieldFlagPushButton = 1 << 16
+	fieldFlagCombo      = 1 << 17
+	fieldFlagEdit       = 1 << 18
+	fieldFlagComb       = 1 << 24
+)
+
+// pdfWidget is a single widget annotation of a form field.
+type pdfWidget struct {
+	ref       pdfRef
+	dict      pdfDict
+	name      string
+	page      int
+	rect      [4]float64
+	fieldType pdfName
+	flags     int
+	maxLen    int
+	da        string
+}
+
+// width returns the widget width, taking a /MK /R rotation into account.
+func (w pdfWidget) width() float64 {
+	if w.rotated() {
+		return abs(w.rect[3] - w.rect[1])
+	}
+	return abs(w.rect[2] - w.rect[0])
+}
+
+// height returns the widget height, taking a /MK /R rotation into account.
+func (w pdfWidget) height() float64 {
+	if w.rotated() {
+		return abs(w.rect[2] - w.rect[0])
+	}
+	return abs(w.rect[3] - w.rect[1])
+}
+
+func (w pdfWidget) rotated() bool {
+	mk, _ := w.dict["MK"].(pdfDict)
+	r, _ := mk["R"].(float64)
+	return int(r)%180 != 0
+}
+
+// widgets returns all form field widgets in page order.
+func (d *pdfDocument) widgets() []pdfWidget {
+	var acroDA string
+	if acro := d.dict(d.catalog()["AcroForm"]); acro != nil {
+		acroDA = d.text(acro["DA"])
+	}
+
+	var list []pdfWidget
+	for i, pageRef := range d.pages() {
+		page := d.dict(pageRef)
+		for _, annot := range d.array(page["Annots"]) {
+			dict := d.dict(annot)
+			if dict == nil || d.name(dict["Subtype"]) != "Widget" {
+				continue
+			}
+
+			w := pdfWidget{
+				dict:      dict,
+				name:      d.fieldName(dict),
+				page:      i + 1,
+				fieldType: d.name(d.inherited(dict, "FT")),
+				da:        acroDA,
+			}
+			if ref, ok := annot.(pdfRef); ok {
+				w.ref = ref
+			}
+			for j, v := range d.array(dict["Rect"]) {
+				if j < 4 {
+					w.rect[j], _ = d.number(v)
+				}
+			}
+			if f, ok := d.number(d.inherited(dict, "Ff")); ok {
+				w.flags = int(f)
+			}
+			if f, ok := d.number(d.inherited(dict, "MaxLen")); ok {
+				w.maxLen = int(f)
+			}
+			if da := d.text(d.inherited(dict, "DA")); da != "" {
+				w.da = da
+			}
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
+// fieldName returns the fully qualified name of the field the widget belongs to.
+func (d *pdfDocument) fieldName(dict pdfDict) string {
+	var parts []string
+	for i := 0; dict != nil && i < 64; i++ {
+		if t, ok := dict["T"]; ok {
+			parts = append([]string{d.text(t)}, parts...)
+		}
+		dict = d.dict(dict["Parent"])
+	}
+	return strings.Join(parts, ".")
+}
+
+// parseDA extracts the font resource name and size of a default appearance
+// string like "/Helv 10 Tf 0 g".
+func parseDA(da string) (font string, size float64) {
+	tokens := strings.Fields(da)
+	for i, t := range tokens {
+		if t == "Tf" && i >= 2 {
+			font = strings.TrimPrefix(tokens[i-2], "/")
+			size, _ = strconv.ParseFloat(tokens[i-1], 64)
+		}
+	}
+	return
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// fieldValues returns the /V values of all terminal fields keyed by their
+// fully qualified names. Checkbox and radio values are returned as the name
+// of the selected state.
+func (d *pdfDocument) fieldValues() map[string]string {
+	values := make(map[string]string)
+	var walk func(v interface{}, prefix string, depth int)
+	walk = func(v interface{}, prefix string, depth int) {
+		field := d.dict(v)
+		if field == nil || depth > 64 {
+			return
+		}
+		name := prefix
+		if _, ok := field["T"]; ok {
+			if name != "" {
+				name += "."
+			}
+			name += d.text(field["T"])
+		}
+
+		// Kids with a /T are child fields, others are plain widgets.
+		isTerminal := true
+		for _, kid := range d.array(field["Kids"]) {
+			if _, ok := d.dict(kid)["T"]; ok {
+				isTerminal = false
+				walk(kid, name, depth+1)
+			}
+		}
+		if !isTerminal {
+			return
+		}
+
+		switch val := d.resolve(field["V"]).(type) {
+		case pdfString:
+			values[name] = decodeTextString(val)
+		case pdfName:
+			values[name] = string(val)
+		case pdfArray:
+			var parts []string
+			for _, e := range val {
+				parts = append(parts, d.text(e))
+			}
+			values[name] = strings.Join(parts, ", ")
+		default:
+			values[name] = ""
+		}
+	}
+
+	acro := d.dict(d.catalog()["AcroForm"])
+	for _, f := range d.array(acro["Fields"]) {
+		walk(f, "", 0)
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// HasXFA returns whenever the PDF form contains XFA data. pdftk only fills
+// the AcroForm fields of such forms, so viewers preferring the XFA data may
+// show the form blank. Use DropXFA or WithDropXFA to remove the XFA data.
+func HasXFA(pdfFile string) (bool, error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return false, err
+	}
+	return doc.hasXFA(), nil
+}
+
+// hasXFA returns whenever the AcroForm of the document has XFA data.
+func (d *pdfDocument) hasXFA() bool {
+	_, ok := d.dict(d.catalog()["AcroForm"])["XFA"]
+	return ok
+}
+
+// DropXFA removes the XFA data of the PDF form and returns a reader to the
+// resulting PDF, which viewers show with its AcroForm fields.
+func DropXFA(pdfFile string) (io.Reader, error) {
+	var err error
+
+	// Check if the pdftk utility exists.
+	if err := lookPath("pdftk"); err != nil {
+		return nil, err
+	}
+
+	if pdfFile, err = getAbs(pdfFile); err != nil {
+		return nil, err
+	}
+
+	// Create a temporary directory.
+	tmpDir, err := createTempDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the temporary directory on defer again.
+	defer func() {
+		os.RemoveAll(tmpDir)
+	}()
+
+	// Run the pdftk utility.
+	outputFile := filepath.Clean(tmpDir + "/output.pdf")
+	err = runCommandInPath(tmpDir, "pdftk", pdfFile, "output", outputFile, "drop_xfa")
+	if err != nil {
+		return nil, fmt.Errorf("pdftk error: %w", err)
+	}
+
+	fb, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(fb), nil
+}
@@ -0,0 +1,132 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeXFAForm writes a one page PDF form with XFA data and no AcroForm
+// fields and returns its path.
+func writeXFAForm(t testing.TB) string {
+	t.Helper()
+	w := &pdfWriter{}
+	parent := w.add(nil)
+	page := w.add(pdfDict{"Type": pdfName("Page"), "Parent": parent, "MediaBox": pdfArray{0, 0, 595, 842}})
+	w.set(parent, pdfDict{"Type": pdfName("Pages"), "Kids": pdfArray{page}, "Count": 1})
+	xfa := w.add(&pdfStream{dict: pdfDict{}, data: []byte("<xdp:xdp xmlns:xdp=\"http://ns.adobe.com/xdp/\"/>")})
+	root := w.add(pdfDict{
+		"Type":     pdfName("Catalog"),
+		"Pages":    parent,
+		"AcroForm": pdfDict{"Fields": pdfArray{}, "XFA": xfa},
+	})
+
+	data, err := w.bytes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "xfa.pdf")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHasXFA(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		file string
+		want bool
+	}{
+		{"xfa", writeXFAForm(t), true},
+		{"acroform", writeTestForm(t, "form.pdf"), false},
+		{"no form", writeTestPDF(t, "doc.pdf", 1), false},
+	} {
+		data, err := ioutil.ReadFile(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		useExecutor(t, &recordExecutor{stdout: data})
+
+		if got, err := HasXFA(tt.file); err != nil || got != tt.want {
+			t.Errorf("HasXFA(%s) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestDropXFACommandLine(t *testing.T) {
+	input := writeXFAForm(t)
+	e := &recordExecutor{output: []byte("%PDF-dropped")}
+	useExecutor(t, e)
+
+	r, err := DropXFA(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != "%PDF-dropped" {
+		t.Errorf("output = %q, %v, want the pdftk output", data, err)
+	}
+	if got, want := baseNames(e.lastCall()), "pdftk xfa.pdf output output.pdf drop_xfa"; got != want {
+		t.Errorf("pdftk call = %q, want %q", got, want)
+	}
+}
+
+func TestFillDropXFA(t *testing.T) {
+	template := writeTestForm(t, "form.pdf")
+	for _, drop := range []bool{false, true} {
+		e := &recordExecutor{stdout: []byte(pdftkHelp), output: []byte("%PDF-filled")}
+		useExecutor(t, e)
+
+		opts := []Option{WithBackend(PDFTKBackend{})}
+		if drop {
+			opts = append(opts, WithDropXFA())
+		}
+		if _, err := FillPDFToBytes(Form{"name": "Ann"}, template, t.TempDir(), "Yes", "Off", opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got := containsString(e.lastCall(), "drop_xfa"); got != drop {
+			t.Errorf("WithDropXFA %v: pdftk call %v has drop_xfa %v", drop, e.lastCall(), got)
+		}
+	}
+}
+
+func TestXFAFormErrors(t *testing.T) {
+	template := writeXFAForm(t)
+	data, err := ioutil.ReadFile(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	o := newOptions([]Option{WithBackend(NativeBackend{}), WithFieldValidation()})
+	_, _, err = o.prepareForm(Form{"name": "Ann"}, template, filepath.Join(t.TempDir(), "template.pdf"))
+	if !errors.Is(err, ErrXFAForm) {
+		t.Errorf("field validation error = %v, want ErrXFAForm", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "filled.pdf")
+	err = FillFile(Form{"name": "Ann"}, template, dest, WithBackend(NativeBackend{}))
+	if !errors.Is(err, ErrXFAForm) {
+		t.Errorf("native fill error = %v, want ErrXFAForm", err)
+	}
+}
@@ -0,0 +1,107 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// GetXMP returns the XMP metadata stream of the PDF document.
+// The result is nil if the document has no XMP metadata.
+func GetXMP(pdfFile string) ([]byte, error) {
+	doc, err := loadPDFFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	return doc.xmp(), nil
+}
+
+// xmp returns the XMP metadata stream like GetXMP.
+func (d *pdfDocument) xmp() []byte {
+	s, ok := d.resolve(d.catalog()["Metadata"]).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	return append([]byte(nil), s.data...)
+}
+
+// SetXMP replaces the XMP metadata stream of the input PDF and writes the
+// result to output. The XMP packet has to be well-formed XML. WithTempDir
+// applies like for a fill.
+func SetXMP(input, output string, xmp []byte, opts ...Option) error {
+	if err := checkXML(xmp); err != nil {
+		return fmt.Errorf("invalid XMP metadata: %v", err)
+	}
+
+	doc, err := loadPDFFile(input)
+	if err != nil {
+		return err
+	}
+
+	u, err := xmpUpdate(doc, xmp)
+	if err != nil {
+		return err
+	}
+	return u.save(output, newOptions(opts).tempDir)
+}
+
+// xmpUpdate replaces the XMP metadata stream like SetXMP.
+func xmpUpdate(doc *pdfDocument, xmp []byte) (*pdfUpdate, error) {
+	root, ok := doc.trailer["Root"].(pdfRef)
+	if !ok {
+		return nil, fmt.Errorf("invalid PDF: missing document catalog")
+	}
+
+	u := doc.update()
+	catalog := copyDict(doc.catalog())
+	catalog["Metadata"] = u.add(&pdfStream{
+		dict: pdfDict{
+			"Type":    pdfName("Metadata"),
+			"Subtype": pdfName("XML"),
+		},
+		data: xmp,
+	})
+	u.set(root, catalog)
+	return u, nil
+}
+
+// checkXML returns an error if data is not well-formed XML.
+func checkXML(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	elements := 0
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if _, ok := t.(xml.StartElement); ok {
+			elements++
+		}
+	}
+	if elements == 0 {
+		return fmt.Errorf("no XML element found")
+	}
+	return nil
+}
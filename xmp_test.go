@@ -0,0 +1,121 @@
+package fillpdf
+
+/*
+ *  FillPDF - Fill PDF forms, with extentions
+ *  Copyright
+ *  PKG Author: Roland Singer
+ *  File Author: Alexander Félix
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+  <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+      <dc:identifier>DMS-4711</dc:identifier>
+    </rdf:Description>
+  </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestXMPUpdate(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xmp := doc.xmp(); xmp != nil {
+		t.Fatalf("XMP of the test PDF = %q, want none", xmp)
+	}
+
+	// Replace the metadata twice, the second stream wins.
+	for _, xmp := range []string{"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"/>", testXMP} {
+		u, err := xmpUpdate(doc, []byte(xmp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := u.bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc, err = parseNativePDF(out); err != nil {
+			t.Fatal(err)
+		}
+		if got := doc.xmp(); string(got) != xmp {
+			t.Errorf("XMP after update = %q, want %q", got, xmp)
+		}
+	}
+
+	s, ok := doc.resolve(doc.catalog()["Metadata"]).(*pdfStream)
+	if !ok || doc.name(s.dict["Type"]) != "Metadata" || doc.name(s.dict["Subtype"]) != "XML" {
+		t.Errorf("metadata stream = %v, want /Type /Metadata /Subtype /XML", s)
+	}
+}
+
+func TestGetXMP(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestPDF(t, "pages.pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseNativePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := xmpUpdate(doc, []byte(testXMP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = u.bytes(); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(t.TempDir(), "xmp.pdf")
+	if err := ioutil.WriteFile(input, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	useExecutor(t, &recordExecutor{stdout: data})
+
+	xmp, err := GetXMP(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(xmp, []byte(testXMP)) {
+		t.Errorf("GetXMP() = %q, want %q", xmp, testXMP)
+	}
+}
+
+func TestSetXMPInvalid(t *testing.T) {
+	e := &recordExecutor{}
+	useExecutor(t, e)
+
+	input := writeTestPDF(t, "pages.pdf", 1)
+	output := filepath.Join(t.TempDir(), "xmp.pdf")
+	for _, xmp := range []string{"", "no xml", "<x:xmpmeta>", "<a></b>"} {
+		if err := SetXMP(input, output, []byte(xmp)); err == nil {
+			t.Errorf("SetXMP(%q) succeeded", xmp)
+		}
+	}
+	if len(e.calls) != 0 {
+		t.Errorf("pdftk calls = %q, want none for invalid XMP", e.calls)
+	}
+}